@@ -1,119 +1,336 @@
 package wayland
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net"
 	"os"
 	"path"
+	"reflect"
 	"strconv"
-	"sync/atomic"
+	"time"
 )
 
+var ErrDisconnected = errors.New("disconnected from the Wayland compositor")
+
+// DisconnectedEvent is the last event reported, once the connection to the
+// compositor is lost. Objects are gone with the connection, and every
+// later call fails with ErrDisconnected.
+type DisconnectedEvent struct {
+	// Cause is the error that broke the connection, such as io.EOF when
+	// the compositor exits, or a protocol error reported by it.
+	Cause error
+}
+
 type Backend struct {
-	Conn         net.Conn
+	Conn         *Conn
 	File         *os.File
 	PrevObjectId uint32
 
+	// Debug, if set, receives a line for every request and event, as
+	// libwayland writes with WAYLAND_DEBUG=1. It is set to os.Stderr
+	// when the WAYLAND_DEBUG environment variable is 1 or client.
+	Debug io.Writer
+
 	RegistryId   ObjectId
 	CompositorId ObjectId
-	SurfaceId    ObjectId
+	ShmId        ObjectId
+	WmBaseId     ObjectId
+
+	// objects are the proxies of the objects of the connection, and free
+	// the ids of deleted objects, to be reused.
+	objects  map[ObjectId]*Proxy
+	free     []ObjectId
+	display  *Proxy
+	registry *Proxy
+
+	// globals are the globals announced by the compositor, by name.
+	// Those announced once connected are reported with GlobalEvent.
+	globals   map[uint32]Global
+	connected bool
+
+	shmFormats []ShmFormat
+	pool       *shmPool
+
+	// pending are the events read while waiting for another message, to
+	// be returned by NextEvent.
+	pending []interface{}
+
+	err error
+
+	// disconnected is the cause of the loss of the connection, which is
+	// reported once with DisconnectedEvent.
+	disconnected error
+	reported     bool
 }
 
-func NewBackend() (b Backend, err error) {
-	b.Conn, b.File, err = connect()
+// NewBackend connects to the compositor named by the WAYLAND_SOCKET or
+// WAYLAND_DISPLAY environment variables.
+func NewBackend() (b *Backend, err error) {
+	return NewBackendContext(context.Background())
+}
+
+// NewBackendContext is like NewBackend, but gives up connecting when ctx is
+// done, for example if the compositor never answers the registry sync.
+func NewBackendContext(ctx context.Context) (b *Backend, err error) {
+	conn, f, err := connect(ctx)
 	if err != nil {
-		return b, fmt.Errorf("initializing wayland socket connection: %w", err)
+		return nil, fmt.Errorf("initializing wayland socket connection: %w", err)
 	}
+	b = newBackend(NewConn(conn))
+	b.File = f
+	defer func() {
+		if err != nil {
+			b.Close()
+		}
+	}()
 
-	b.PrevObjectId = 1
-	b.RegistryId = b.NewObjectId()
-	syncCallbackId := b.NewObjectId()
+	stop := watchContext(ctx, b.Conn)
+	defer stop()
 
-	var msg Message
-	msg = NewMessage(DisplayId, OpDisplayGetRegistry, DisplayGetRegistry{
-		Registry: b.RegistryId,
-	})
+	err = b.getRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("writing get_registry message: %w", contextError(ctx, err))
+	}
 
-	err = msg.Write(b.Conn)
+	// The globals are announced before the answer to the first roundtrip,
+	// and the formats supported by wl_shm once bound, before the second.
+	err = b.Roundtrip()
 	if err != nil {
-		return b, fmt.Errorf("writing get_registry message: %w", err)
+		return nil, fmt.Errorf("reading registry globals: %w", contextError(ctx, err))
+	}
+	err = b.Roundtrip()
+	if err != nil {
+		return nil, fmt.Errorf("reading shm formats: %w", contextError(ctx, err))
 	}
 
-	msg = NewMessage(DisplayId, OpDisplaySync, DisplaySync{
-		Callback: syncCallbackId,
-	})
-	err = msg.Write(b.Conn)
+	b.connected = true
+	return b, nil
+}
+
+// newBackend returns a backend using conn, with the wl_display object.
+func newBackend(conn *Conn) *Backend {
+	b := &Backend{
+		Conn:         conn,
+		PrevObjectId: uint32(DisplayId),
+		objects:      make(map[ObjectId]*Proxy),
+		globals:      make(map[uint32]Global),
+	}
+	b.display = &Proxy{
+		Id:        DisplayId,
+		Interface: DisplayInterface,
+		Version:   1,
+		b:         b,
+		handlers:  make(map[Opcode]reflect.Value),
+	}
+	b.objects[DisplayId] = b.display
+	if debugEnv() {
+		b.Debug = os.Stderr
+	}
+	b.display.Handle(OpDisplayErrorEvent, b.displayError)
+	b.display.Handle(OpDisplayDeleteId, b.deleteId)
+	return b
+}
+
+func (b *Backend) Close() {
+	if b.err == nil {
+		// Destroy requests are sent, as the compositor may otherwise
+		// keep showing windows until it notices.
+		b.Conn.Flush()
+		b.err = fmt.Errorf("backend closed: %w", ErrDisconnected)
+	}
+	if b.pool != nil {
+		b.pool.release()
+		b.pool = nil
+	}
+	b.Conn.Close()
+	if b.File != nil {
+		b.File.Close()
+	}
+}
+
+// NextEvent returns the next event without handler, such as those of the
+// windows or the decoded events of proxies, or the Message of an event of
+// an object or interface the backend does not know about.
+// The fds of returned events belong to the caller.
+// Errors reported with wl_display.error are fatal, and close the
+// connection. When the connection is lost, DisconnectedEvent is returned
+// once, and ErrDisconnected afterwards.
+func (b *Backend) NextEvent() (ev interface{}, err error) {
+	for {
+		if b.disconnected != nil && !b.reported {
+			b.reported = true
+			return DisconnectedEvent{Cause: b.disconnected}, nil
+		}
+		if len(b.pending) > 0 {
+			ev := b.pending[0]
+			b.pending = b.pending[1:]
+			return ev, nil
+		}
+		if b.err != nil {
+			return nil, b.err
+		}
+
+		// The lost connection is reported by the next iteration.
+		b.Dispatch()
+	}
+}
+
+// Dispatch waits for events and calls the handlers of their objects: the
+// first event read, and the others that arrived with it. The requests sent
+// so far are flushed first. Events without handler are kept for NextEvent.
+func (b *Backend) Dispatch() error {
+	err := b.Flush()
 	if err != nil {
-		return b, fmt.Errorf("writing sync message: %w", err)
+		return err
+	}
+
+	for {
+		msg, err := b.receive()
+		if err != nil {
+			return err
+		}
+		err = b.dispatchMessage(msg)
+		if err != nil {
+			return err
+		}
+		// Handlers report the errors they cause by disconnecting.
+		if b.err != nil {
+			return b.err
+		}
+		if !b.Conn.Buffered() {
+			return nil
+		}
 	}
+}
 
+// Roundtrip waits until the compositor processed the requests sent so far,
+// dispatching the events they caused.
+func (b *Backend) Roundtrip() error {
 	done := false
+	callback := b.NewProxy(CallbackInterface, 1)
+	callback.Handle(OpCallbackDone, func(CallbackDone) {
+		done = true
+	})
+	err := b.display.Request(OpDisplaySync, DisplaySync{
+		Callback: callback.Id,
+	})
+	if err != nil {
+		return err
+	}
+
 	for !done {
-		msg, err = ReadMessage(b.Conn)
+		err = b.Dispatch()
 		if err != nil {
-			return b, fmt.Errorf("reading registry global message: %w", err)
-		}
-		log.Printf("received message: %v", msg)
-
-		switch {
-		case msg.ObjectId == syncCallbackId:
-			log.Printf("received callback %d", msg.ObjectId)
-			done = true
-
-		case msg.ObjectId == b.RegistryId && msg.Opcode == OpRegistryGlobal:
-			var ev RegistryGlobal
-			msg.Unmarshall(&ev)
-			log.Printf("global: %v", ev)
-
-			if ev.Interface == "wl_compositor" {
-				b.CompositorId = b.NewObjectId()
-				msg = NewMessage(b.RegistryId, OpRegistryBind, RegistryBind{
-					Name:      ev.Name,
-					Interface: ev.Interface,
-					Version:   ev.Version,
-					Id:        b.CompositorId,
-				})
-				log.Printf("bind message: %v", msg)
-				err = msg.Write(b.Conn)
-				if err != nil {
-					return b, fmt.Errorf("binding to compositor: %w", err)
-				}
-				log.Printf("bound to compositor %d", b.CompositorId)
-			}
-		case msg.ObjectId == DisplayId && msg.Opcode == OpDisplayError:
-			var ev DisplayError
-			msg.Unmarshall(&ev)
-			log.Fatalf("error: %v", ev)
+			return err
 		}
+	}
+	return nil
+}
 
+// Flush writes the requests sent so far. They are otherwise written when
+// waiting for events, by Dispatch, Roundtrip or NextEvent.
+func (b *Backend) Flush() error {
+	if b.err != nil {
+		return b.err
 	}
 
-	b.SurfaceId = b.NewObjectId()
-	msg = NewMessage(b.CompositorId, OpCompositorCreateSurface, CompositorCreateSurface{
-		Id: b.SurfaceId,
-	})
-	err = msg.Write(b.Conn)
+	err := b.Conn.Flush()
+	if err != nil {
+		return b.disconnect(err)
+	}
+	return nil
+}
+
+// request encodes and writes a request to an object.
+func (b *Backend) request(id ObjectId, opcode Opcode, data interface{}) error {
+	msg, err := NewMessage(id, opcode, data)
+	if err != nil {
+		return err
+	}
+	err = b.send(msg)
 	if err != nil {
-		return b, fmt.Errorf("creating suface: %w", err)
+		return err
 	}
-	log.Printf("created surface %d", b.SurfaceId)
+	if b.Debug != nil {
+		b.traceRequest(id, opcode, data)
+	}
+	return nil
+}
 
-	return
+// send queues a request to the compositor.
+func (b *Backend) send(msg Message) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	err := b.Conn.WriteMessage(msg)
+	if err != nil {
+		return b.disconnect(err)
+	}
+	return nil
 }
 
-func (b *Backend) Close() {
+// receive reads a message from the compositor.
+func (b *Backend) receive() (msg Message, err error) {
+	if b.err != nil {
+		return msg, b.err
+	}
+
+	msg, err = b.Conn.ReadMessage()
+	if err != nil {
+		return msg, b.disconnect(err)
+	}
+	return msg, nil
+}
+
+// displayError disconnects because of a wl_display.error event, which
+// leaves the connection unusable. Calls then fail with an error wrapping the
+// ProtocolError.
+func (b *Backend) displayError(ev DisplayErrorEvent) {
+	err := &ProtocolError{
+		ObjectId: ev.ObjectId,
+		Code:     ev.Code,
+		Message:  ev.Message,
+	}
+	if p := b.objects[ev.ObjectId]; p != nil {
+		err.Interface = p.Interface
+	}
+	b.disconnect(err)
+}
+
+// disconnect records that the connection was lost because of cause and
+// closes it. It returns the error returned by later calls.
+func (b *Backend) disconnect(cause error) error {
+	if b.disconnected != nil || b.err != nil {
+		return b.err
+	}
+
+	b.disconnected = cause
+	b.err = &disconnectedError{cause: cause}
 	b.Conn.Close()
 	if b.File != nil {
 		b.File.Close()
 	}
-}
+	if b.pool != nil {
+		b.pool.release()
+		b.pool = nil
+	}
+	b.pending = nil
 
-func (b *Backend) NewObjectId() ObjectId {
-	return ObjectId(atomic.AddUint32(&b.PrevObjectId, 1))
+	b.RegistryId = 0
+	b.CompositorId = 0
+	b.ShmId = 0
+	b.WmBaseId = 0
+	b.objects = make(map[ObjectId]*Proxy)
+	b.free = nil
+	b.globals = make(map[uint32]Global)
+	return b.err
 }
 
-func connect() (conn net.Conn, f *os.File, err error) {
+func connect(ctx context.Context) (conn *net.UnixConn, f *os.File, err error) {
 	socketFd := os.Getenv("WAYLAND_SOCKET")
 	if socketFd != "" {
 		socketFdI, err := strconv.Atoi(socketFd)
@@ -122,7 +339,7 @@ func connect() (conn net.Conn, f *os.File, err error) {
 		}
 
 		f = os.NewFile(uintptr(socketFdI), "wayland-0")
-		if f != nil {
+		if f == nil {
 			return conn, f, fmt.Errorf("interpreting 'WAYLAND_SOCKET as a file")
 		}
 		defer func() {
@@ -131,15 +348,16 @@ func connect() (conn net.Conn, f *os.File, err error) {
 			}
 		}()
 
-		conn, err = net.FileConn(f)
+		fc, err := net.FileConn(f)
 		if err != nil {
 			return conn, f, fmt.Errorf("interpreting 'WAYLAND_SOCKET' as a socket: %w", err)
 		}
-		defer func() {
-			if err != nil {
-				conn.Close()
-			}
-		}()
+		conn, ok := fc.(*net.UnixConn)
+		if !ok {
+			fc.Close()
+			err = fmt.Errorf("'WAYLAND_SOCKET' is not a unix socket")
+			return nil, f, err
+		}
 
 		return conn, f, nil
 	}
@@ -157,11 +375,42 @@ func connect() (conn net.Conn, f *os.File, err error) {
 		wlDisplay = path.Join(xdgRtd, wlDisplay)
 	}
 
-	conn, err = net.Dial("unix", wlDisplay)
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "unix", wlDisplay)
 	if err != nil {
 		return conn, f, fmt.Errorf("connecting to wayland display socket '%s': %w", wlDisplay, err)
 	}
 
-	return conn, nil, nil
+	return c.(*net.UnixConn), nil, nil
+
+}
+
+// watchContext makes blocking I/O on conn fail once ctx is done, until the
+// returned function is called.
+func watchContext(ctx context.Context, conn interface{ SetDeadline(time.Time) error }) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		conn.SetDeadline(time.Time{})
+	}
+}
 
+// contextError returns the error of ctx if it is done, as I/O errors are
+// then caused by watchContext.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
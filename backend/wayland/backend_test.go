@@ -0,0 +1,133 @@
+package wayland
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestNewBackend(t *testing.T) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[1]), "compositor")
+	fc, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &fakeCompositor{t: t, conn: NewConn(fc.(*net.UnixConn))}
+	defer c.conn.Close()
+	t.Setenv("WAYLAND_SOCKET", strconv.Itoa(fds[0]))
+
+	// The ids of the registry, the first sync callback, the bound globals
+	// and the sync callback waiting for the shm formats.
+	registry, callback := ObjectId(2), ObjectId(3)
+	compositor, shm, wmBase, formatsCallback := ObjectId(4), ObjectId(5), ObjectId(6), ObjectId(7)
+
+	c.send(registry, OpRegistryGlobal, RegistryGlobal{Name: 1, Interface: CompositorInterface, Version: 6})
+	c.send(registry, OpRegistryGlobal, RegistryGlobal{Name: 2, Interface: "wl_seat", Version: 7})
+	c.send(registry, OpRegistryGlobal, RegistryGlobal{Name: 3, Interface: ShmInterface, Version: 1})
+	c.send(registry, OpRegistryGlobal, RegistryGlobal{Name: 4, Interface: XdgWmBaseInterface, Version: 5})
+	c.send(callback, OpCallbackDone, CallbackDone{})
+
+	type result struct {
+		b   *Backend
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		b, err := NewBackendContext(context.Background())
+		done <- result{b, err}
+	}()
+
+	c.expect(DisplayId, OpDisplayGetRegistry, nil)
+	c.expect(DisplayId, OpDisplaySync, nil)
+	for _, want := range []RegistryBind{
+		{Name: 1, Id: NewId{Interface: CompositorInterface, Version: 4, Id: compositor}},
+		{Name: 3, Id: NewId{Interface: ShmInterface, Version: 1, Id: shm}},
+		{Name: 4, Id: NewId{Interface: XdgWmBaseInterface, Version: 2, Id: wmBase}},
+	} {
+		var bind RegistryBind
+		c.expect(registry, OpRegistryBind, &bind)
+		if bind != want {
+			t.Errorf("bound %+v, want %+v", bind, want)
+		}
+	}
+	var sync DisplaySync
+	c.expect(DisplayId, OpDisplaySync, &sync)
+	if sync.Callback != formatsCallback {
+		t.Fatalf("sync with callback %d, want %d", sync.Callback, formatsCallback)
+	}
+	c.send(shm, OpShmFormatEvent, ShmFormatEvent{Format: ShmFormatArgb8888})
+	c.send(shm, OpShmFormatEvent, ShmFormatEvent{Format: ShmFormatXrgb8888})
+	c.send(formatsCallback, OpCallbackDone, CallbackDone{})
+
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	b := r.b
+	defer b.Close()
+
+	if b.CompositorId != compositor || b.ShmId != shm || b.WmBaseId != wmBase {
+		t.Errorf("bound compositor %d, shm %d and xdg_wm_base %d", b.CompositorId, b.ShmId, b.WmBaseId)
+	}
+	if !b.HasGlobal("wl_seat", 7) || b.HasGlobal("wl_seat", 8) || b.HasGlobal("wl_output", 1) {
+		t.Errorf("globals %v", b.Globals())
+	}
+	// Globals announced while connecting are not reported.
+	if len(b.pending) != 0 {
+		t.Errorf("pending events %v", b.pending)
+	}
+	formats := b.ShmFormats()
+	if len(formats) != 2 || formats[0] != ShmFormatArgb8888 || formats[1] != ShmFormatXrgb8888 {
+		t.Errorf("shm formats %v", formats)
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+
+	shm := b.ShmId
+	c.send(DisplayId, OpDisplayErrorEvent, DisplayErrorEvent{
+		ObjectId: shm,
+		Code:     1,
+		Message:  "bad stride",
+	})
+	err := b.Dispatch()
+
+	var perr *ProtocolError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("dispatch failed with %v", err)
+	}
+	want := ProtocolError{ObjectId: shm, Interface: ShmInterface, Code: 1, Message: "bad stride"}
+	if *perr != want || perr.CodeName() != "invalid_stride" {
+		t.Errorf("got %+v (%s)", *perr, perr.CodeName())
+	}
+
+	// The connection is dead.
+	ev, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := ev.(DisconnectedEvent); !ok || d.Cause != error(perr) {
+		t.Errorf("got event %+v", ev)
+	}
+	err = b.Roundtrip()
+	if !errors.As(err, &perr) {
+		t.Errorf("roundtrip after the error failed with %v", err)
+	}
+
+	// Objects without error enum get the global errors.
+	perr = &ProtocolError{ObjectId: 3, Interface: CallbackInterface, Code: 1}
+	if perr.CodeName() != "invalid_method" {
+		t.Errorf("code name %q, want invalid_method", perr.CodeName())
+	}
+}
@@ -0,0 +1,316 @@
+package wayland
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxFds is the largest number of file descriptors passed in a single
+// sendmsg call by libwayland, which compositors size their buffers for.
+const maxFds = 28
+
+const (
+	// inSize is the size of the input ring buffer, which holds the largest
+	// message the 16-bit size of the header allows.
+	inSize = 1 << 16
+	// outSize is the size the output buffer is flushed at.
+	outSize = 1 << 16
+)
+
+// Conn is a connection to the compositor that passes file descriptors
+// along with messages, as SCM_RIGHTS control messages.
+//
+// The wire format does not tell which message a descriptor belongs to, so
+// received ones are queued, and taken in order as the fd arguments of
+// messages are unmarshalled. Every message with fd arguments must then be
+// unmarshalled, in the order they are read.
+//
+// Input is read in a ring buffer, as much as has arrived, so that a burst
+// of events is read with few system calls. Output is buffered until Flush,
+// which ReadMessage calls before waiting for input.
+type Conn struct {
+	c *net.UnixConn
+
+	// in is the ring buffer of received bytes, read at r and written at
+	// w. Both only grow, and are taken modulo inSize.
+	in   []byte
+	r, w uint
+	oob  []byte
+	fdq  fdQueue
+
+	// out are the messages written since the last flush, and outFds the
+	// duplicates of their descriptors, closed once passed.
+	out    []byte
+	outFds []int
+
+	// reads and writes count the system calls made, for benchmarks.
+	reads  int
+	writes int
+}
+
+func NewConn(c *net.UnixConn) *Conn {
+	return &Conn{
+		c:   c,
+		in:  make([]byte, inSize),
+		oob: make([]byte, syscall.CmsgSpace(maxFds*4)),
+	}
+}
+
+// ReadMessage returns the next message, reading more input if it has not
+// fully arrived. The file descriptors received so far are available to its
+// Unmarshall method.
+func (c *Conn) ReadMessage() (msg Message, err error) {
+	err = c.fill(8)
+	if err != nil {
+		return msg, fmt.Errorf("reading message header: %w", err)
+	}
+
+	var header [8]byte
+	c.peek(header[:], 0)
+	sizeAndOpcode := binary.LittleEndian.Uint32(header[4:8])
+	msg.ObjectId = ObjectId(binary.LittleEndian.Uint32(header[0:4]))
+	msg.Size = uint16(sizeAndOpcode >> 16)
+	msg.Opcode = Opcode(sizeAndOpcode)
+	if msg.Size < 8 {
+		return msg, fmt.Errorf("reading message header: invalid size %d", msg.Size)
+	}
+
+	err = c.fill(int(msg.Size))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return msg, fmt.Errorf("reading message payload: %w", err)
+	}
+	msg.Payload = newPayload(int(msg.Size) - 8)
+	c.peek(msg.Payload, 8)
+	c.r += uint(msg.Size)
+	msg.fdq = &c.fdq
+
+	return msg, nil
+}
+
+// Buffered returns whether a whole message is buffered, which ReadMessage
+// returns without reading.
+func (c *Conn) Buffered() bool {
+	if c.w-c.r < 8 {
+		return false
+	}
+	var header [8]byte
+	c.peek(header[:], 0)
+	size := binary.LittleEndian.Uint32(header[4:8]) >> 16
+	return uint(size) <= c.w-c.r
+}
+
+// payloadSize is the capacity of pooled payloads, the largest message of
+// libwayland, which compositors keep to.
+const payloadSize = 4096
+
+var payloadPool = sync.Pool{
+	New: func() interface{} { return new([payloadSize]byte) },
+}
+
+// newPayload returns a payload of n bytes, from the pool if it fits.
+func newPayload(n int) []byte {
+	if n == 0 {
+		return nil
+	}
+	if n > payloadSize {
+		return make([]byte, n)
+	}
+	return payloadPool.Get().(*[payloadSize]byte)[:n]
+}
+
+// release returns the payload of a read message to the pool. The message,
+// and the copies of it, must not be used afterwards.
+func (msg *Message) release() {
+	if cap(msg.Payload) == payloadSize {
+		payloadPool.Put((*[payloadSize]byte)(msg.Payload[:payloadSize]))
+	}
+	msg.Payload = nil
+}
+
+// fill reads until at least n bytes are buffered, flushing the output
+// first. Like io.ReadFull, it fails with io.EOF if the connection is closed
+// with nothing buffered, and with io.ErrUnexpectedEOF otherwise.
+func (c *Conn) fill(n int) error {
+	for int(c.w-c.r) < n {
+		err := c.Flush()
+		if err != nil {
+			return err
+		}
+
+		// Read into the free space, up to the end of the buffer.
+		start := int(c.w % inSize)
+		end := start + inSize - int(c.w-c.r)
+		if end > inSize {
+			end = inSize
+		}
+		nr, noob, _, _, err := c.c.ReadMsgUnix(c.in[start:end], c.oob)
+		c.reads++
+		c.w += uint(nr)
+		if noob > 0 {
+			// The fds read are lost, so those of later messages can no
+			// longer be matched with them.
+			perr := c.fdq.parse(c.oob[:noob])
+			if perr != nil {
+				return fmt.Errorf("reading %d bytes: fds out of sync with messages: %w", nr, perr)
+			}
+		}
+
+		if err == nil && nr == 0 && noob == 0 {
+			err = io.EOF
+		}
+		if err == io.EOF && c.w != c.r {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peek copies buffered bytes from offset off into dst.
+func (c *Conn) peek(dst []byte, off int) {
+	i := (c.r + uint(off)) % inSize
+	n := copy(dst, c.in[i:])
+	copy(dst[n:], c.in)
+}
+
+// WriteMessage queues a message, written with its file descriptors by the
+// next Flush. The descriptors are duplicated, so that the caller can close
+// them right away.
+func (c *Conn) WriteMessage(msg Message) error {
+	if len(msg.Fds) > maxFds {
+		return fmt.Errorf("writing message: %d file descriptors, at most %d can be passed", len(msg.Fds), maxFds)
+	}
+	if len(c.out)+8+len(msg.Payload) > outSize || len(c.outFds)+len(msg.Fds) > maxFds {
+		err := c.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, fd := range msg.Fds {
+		dup, err := dupCloseOnExec(fd)
+		if err != nil {
+			for _, fd := range c.outFds[len(c.outFds)-i:] {
+				syscall.Close(fd)
+			}
+			c.outFds = c.outFds[:len(c.outFds)-i]
+			return fmt.Errorf("writing message: duplicating file descriptor: %w", err)
+		}
+		c.outFds = append(c.outFds, dup)
+	}
+
+	var header [8]byte
+	sizeAndOpcode := (uint32(msg.Size) << 16) | uint32(msg.Opcode)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(msg.ObjectId))
+	binary.LittleEndian.PutUint32(header[4:8], sizeAndOpcode)
+	c.out = append(c.out, header[:]...)
+	c.out = append(c.out, msg.Payload...)
+	return nil
+}
+
+// Flush writes the queued messages, passing all their file descriptors
+// with the first byte.
+func (c *Conn) Flush() error {
+	if len(c.out) == 0 {
+		return nil
+	}
+	defer func() {
+		c.out = c.out[:0]
+		c.closeOutFds()
+	}()
+
+	var oob []byte
+	if len(c.outFds) > 0 {
+		oob = syscall.UnixRights(c.outFds...)
+	}
+	n, _, err := c.c.WriteMsgUnix(c.out, oob, nil)
+	c.writes++
+	if err != nil {
+		return fmt.Errorf("writing messages: %w", err)
+	}
+	if n < len(c.out) {
+		// The descriptors went with the first part.
+		_, err = c.c.Write(c.out[n:])
+		c.writes++
+		if err != nil {
+			return fmt.Errorf("writing messages: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Conn) closeOutFds() {
+	for _, fd := range c.outFds {
+		syscall.Close(fd)
+	}
+	c.outFds = c.outFds[:0]
+}
+
+// dupCloseOnExec duplicates a file descriptor, which is not inherited by
+// child processes.
+func dupCloseOnExec(fd int) (int, error) {
+	syscall.ForkLock.RLock()
+	defer syscall.ForkLock.RUnlock()
+	dup, err := syscall.Dup(fd)
+	if err != nil {
+		return -1, err
+	}
+	syscall.CloseOnExec(dup)
+	return dup, nil
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.c.SetDeadline(t)
+}
+
+// Close closes the connection without flushing it, and the file
+// descriptors not passed or not taken by a message.
+func (c *Conn) Close() error {
+	c.fdq.close()
+	c.closeOutFds()
+	return c.c.Close()
+}
+
+// fdQueue holds received file descriptors until they are attached to a
+// message.
+type fdQueue struct {
+	fds []int
+}
+
+func (q *fdQueue) parse(oob []byte) error {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return fmt.Errorf("parsing control message: %w", err)
+	}
+	for _, m := range msgs {
+		fds, err := syscall.ParseUnixRights(&m)
+		if err != nil {
+			continue
+		}
+		q.fds = append(q.fds, fds...)
+	}
+	return nil
+}
+
+func (q *fdQueue) pop() int {
+	fd := q.fds[0]
+	q.fds = q.fds[1:]
+	return fd
+}
+
+func (q *fdQueue) close() {
+	for _, fd := range q.fds {
+		syscall.Close(fd)
+	}
+	q.fds = nil
+}
@@ -0,0 +1,460 @@
+package wayland
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// newMessage encodes a message, failing the test on errors.
+func newMessage(t *testing.T, id ObjectId, opcode Opcode, data interface{}) Message {
+	t.Helper()
+	msg, err := NewMessage(id, opcode, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// mustUnmarshall decodes a message, failing the test on errors.
+func mustUnmarshall(t *testing.T, msg *Message, data interface{}) {
+	t.Helper()
+	err := msg.Unmarshall(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// socketPair returns the two ends of a connected unix socket.
+func socketPair(t testing.TB) (*Conn, *Conn) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns := make([]*Conn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		c, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn := NewConn(c.(*net.UnixConn))
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	return conns[0], conns[1]
+}
+
+type testFdEvent struct {
+	Serial uint32
+	Fd     Fd
+	Size   uint32
+}
+
+type testTwoFdsEvent struct {
+	Name  string
+	Read  Fd
+	Write Fd
+}
+
+func TestConnPassesFds(t *testing.T) {
+	client, server := socketPair(t)
+
+	r1, w1, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Close()
+	defer w1.Close()
+	r2, w2, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	defer w2.Close()
+
+	msgs := []Message{
+		newMessage(t, 3, 0, testFdEvent{Serial: 1, Fd: Fd(w1.Fd()), Size: 4096}),
+		newMessage(t, 4, 1, DisplaySync{Callback: 5}),
+		newMessage(t, 3, 2, testTwoFdsEvent{Name: "pipe", Read: Fd(r2.Fd()), Write: Fd(w2.Fd())}),
+	}
+	if len(msgs[0].Fds) != 1 || len(msgs[2].Fds) != 2 {
+		t.Fatalf("marshalled fds %v and %v", msgs[0].Fds, msgs[2].Fds)
+	}
+	if int(msgs[0].Size) != 16 {
+		t.Errorf("message with an fd has size %d, want 16", msgs[0].Size)
+	}
+	for _, msg := range msgs {
+		err = client.WriteMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = client.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read every message before unmarshalling, so that all the fds are
+	// queued together.
+	var got []Message
+	for range msgs {
+		msg, err := server.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, msg)
+	}
+
+	var ev1 testFdEvent
+	mustUnmarshall(t, &got[0], &ev1)
+	if ev1.Serial != 1 || ev1.Size != 4096 {
+		t.Errorf("unmarshalled %+v", ev1)
+	}
+	var sync DisplaySync
+	mustUnmarshall(t, &got[1], &sync)
+	if len(got[1].Fds) != 0 {
+		t.Errorf("message without fd arguments got fds %v", got[1].Fds)
+	}
+	var ev2 testTwoFdsEvent
+	mustUnmarshall(t, &got[2], &ev2)
+	if ev2.Name != "pipe" {
+		t.Errorf("unmarshalled %+v", ev2)
+	}
+
+	// The received descriptors are new ones for the same pipes, in the
+	// order they were sent.
+	checkPipe(t, int(ev1.Fd), r1)
+	checkPipe(t, int(ev2.Write), r2)
+	syscall.Close(int(ev1.Fd))
+	syscall.Close(int(ev2.Write))
+
+	rf := os.NewFile(uintptr(ev2.Read), "received")
+	defer rf.Close()
+	_, err = w2.Write([]byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b [1]byte
+	_, err = rf.Read(b[:])
+	if err != nil || b[0] != 'x' {
+		t.Errorf("reading received pipe: %q, %v", b, err)
+	}
+
+	// Unmarshalling again returns the attached descriptors.
+	var again testTwoFdsEvent
+	mustUnmarshall(t, &got[2], &again)
+	if again != ev2 {
+		t.Errorf("unmarshalled again %+v, want %+v", again, ev2)
+	}
+}
+
+// checkPipe checks that fd is the write end of the pipe read by r.
+func checkPipe(t *testing.T, fd int, r *os.File) {
+	t.Helper()
+
+	_, err := syscall.Write(fd, []byte("ok"))
+	if err != nil {
+		t.Fatalf("writing to received fd %d: %v", fd, err)
+	}
+	var b [2]byte
+	_, err = r.Read(b[:])
+	if err != nil || string(b[:]) != "ok" {
+		t.Errorf("reading pipe: %q, %v", b, err)
+	}
+}
+
+func TestConnMissingFd(t *testing.T) {
+	client, server := socketPair(t)
+
+	// A message with an fd argument whose descriptor was not passed.
+	msg := newMessage(t, 3, 0, testFdEvent{Serial: 1, Size: 1})
+	msg.Fds = nil
+	err := client.WriteMessage(msg)
+	if err == nil {
+		err = client.Flush()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = unmarshall(bytes.NewBuffer(got.Payload), got.nextFd(), &testFdEvent{})
+	if err == nil {
+		t.Error("unmarshalling without a received fd succeeded")
+	}
+}
+
+func TestConnSplitMessages(t *testing.T) {
+	client, server := socketPair(t)
+
+	// Messages larger than a single read are reassembled.
+	payload := make([]byte, 6000)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	msg := NewMessageBytes(7, 3, payload)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		client.WriteMessage(msg)
+		client.Flush()
+	}()
+	defer func() { <-written }()
+
+	got, err := server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ObjectId != 7 || got.Opcode != 3 || len(got.Payload) != len(payload) {
+		t.Fatalf("read message %d.%d of %d bytes", got.ObjectId, got.Opcode, len(got.Payload))
+	}
+	for i := range payload {
+		if got.Payload[i] != payload[i] {
+			t.Fatalf("payload differs at byte %d", i)
+		}
+	}
+}
+
+func TestConnRingWraps(t *testing.T) {
+	client, server := socketPair(t)
+
+	// The messages span the ring buffer several times, and are read as
+	// they arrive, in pieces that do not match them.
+	const count = 300
+	written := make(chan struct{})
+	defer func() { <-written }()
+	go func() {
+		defer close(written)
+		for i := 0; i < count; i++ {
+			payload := make([]byte, 4*(i%300))
+			for j := range payload {
+				payload[j] = byte(i + j)
+			}
+			client.WriteMessage(NewMessageBytes(ObjectId(i), 1, payload))
+			if i%7 == 0 {
+				client.Flush()
+			}
+		}
+		client.Flush()
+	}()
+
+	for i := 0; i < count; i++ {
+		msg, err := server.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.ObjectId != ObjectId(i) || len(msg.Payload) != 4*(i%300) {
+			t.Fatalf("read message %d of %d bytes, want %d", msg.ObjectId, len(msg.Payload), i)
+		}
+		for j, c := range msg.Payload {
+			if c != byte(i+j) {
+				t.Fatalf("message %d differs at byte %d", i, j)
+			}
+		}
+		msg.release()
+	}
+	if server.w-server.r != 0 || server.w < 2*inSize {
+		t.Errorf("read %d bytes, %d left", server.w, server.w-server.r)
+	}
+}
+
+func TestConnEOF(t *testing.T) {
+	client, server := socketPair(t)
+
+	// A message cut by the end of the connection.
+	var buf bytes.Buffer
+	msg := NewMessageBytes(3, 0, make([]byte, 8))
+	msg.Write(&buf)
+	msg.Write(&buf)
+	_, err := client.c.Write(buf.Bytes()[:buf.Len()-4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.c.CloseWrite()
+	_, err = server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.ReadMessage()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("read cut message with %v", err)
+	}
+
+	// The end of the connection between messages.
+	client, server = socketPair(t)
+	client.WriteMessage(msg)
+	client.Flush()
+	client.c.CloseWrite()
+	_, err = server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.ReadMessage()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("read past the end with %v", err)
+	}
+}
+
+func TestConnBatchesWrites(t *testing.T) {
+	client, server := socketPair(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// The messages are written at once, and the descriptors can be
+	// closed before.
+	for i := 0; i < 100; i++ {
+		err = client.WriteMessage(NewMessageBytes(3, 1, make([]byte, 12)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = client.WriteMessage(newMessage(t, 3, 0, testFdEvent{Serial: 1, Fd: Fd(w.Fd()), Size: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if client.writes != 0 {
+		t.Errorf("wrote before flushing")
+	}
+	err = client.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.writes != 1 {
+		t.Errorf("flushed with %d writes", client.writes)
+	}
+
+	for i := 0; i < 100; i++ {
+		_, err = server.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg, err := server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev testFdEvent
+	mustUnmarshall(t, &msg, &ev)
+	checkPipe(t, int(ev.Fd), r)
+	syscall.Close(int(ev.Fd))
+	if server.reads > 2 {
+		t.Errorf("read 101 messages with %d reads", server.reads)
+	}
+}
+
+// burst is the number of events of the benchmarks, such as the motion
+// events of a fast pointer.
+const burst = 1000
+
+// countingConn counts the system calls of an unbuffered connection.
+type countingConn struct {
+	*net.UnixConn
+	calls int
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	c.calls++
+	return c.UnixConn.Read(p)
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	c.calls++
+	return c.UnixConn.Write(p)
+}
+
+func BenchmarkReadBurst(b *testing.B) {
+	ev := NewMessageBytes(3, 1, make([]byte, 12))
+
+	b.Run("ring", func(b *testing.B) {
+		client, server := socketPair(b)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < burst; j++ {
+				client.WriteMessage(ev)
+			}
+			client.Flush()
+			for j := 0; j < burst; j++ {
+				msg, err := server.ReadMessage()
+				if err != nil {
+					b.Fatal(err)
+				}
+				msg.release()
+			}
+		}
+		b.ReportMetric(float64(server.reads)/float64(b.N*burst), "syscalls/event")
+	})
+
+	b.Run("unbuffered", func(b *testing.B) {
+		client, server := socketPair(b)
+		r := &countingConn{UnixConn: server.c}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < burst; j++ {
+				client.WriteMessage(ev)
+			}
+			client.Flush()
+			for j := 0; j < burst; j++ {
+				_, err := ReadMessage(r)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(r.calls)/float64(b.N*burst), "syscalls/event")
+	})
+}
+
+func BenchmarkWriteBurst(b *testing.B) {
+	req := NewMessageBytes(3, 1, make([]byte, 12))
+
+	b.Run("batched", func(b *testing.B) {
+		client, server := socketPair(b)
+		go discard(server.c)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < burst; j++ {
+				client.WriteMessage(req)
+			}
+			client.Flush()
+		}
+		b.ReportMetric(float64(client.writes)/float64(b.N*burst), "syscalls/request")
+	})
+
+	b.Run("unbuffered", func(b *testing.B) {
+		client, server := socketPair(b)
+		go discard(server.c)
+		w := &countingConn{UnixConn: client.c}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < burst; j++ {
+				req.Write(w)
+			}
+		}
+		b.ReportMetric(float64(w.calls)/float64(b.N*burst), "syscalls/request")
+	})
+}
+
+func discard(c *net.UnixConn) {
+	var buf [1 << 16]byte
+	for {
+		_, err := c.Read(buf[:])
+		if err != nil {
+			return
+		}
+	}
+}
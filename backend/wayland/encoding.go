@@ -7,6 +7,8 @@ import (
 	"io"
 	"math"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 type Message struct {
@@ -14,15 +16,86 @@ type Message struct {
 	Size     uint16
 	Opcode   Opcode
 	Payload  []byte
+
+	// Fds are the file descriptors passed with the message, in the order
+	// of its fd arguments. Received messages get them as they are
+	// unmarshalled, from the descriptors queued by their Conn.
+	Fds []int
+	fdq *fdQueue
+}
+
+// Fd is a file descriptor argument, passed out of band. Marshalling one
+// does not transfer ownership: the sender still has to close it.
+type Fd int
+
+var fdType = reflect.TypeOf(Fd(0))
+
+// Fixed is a signed 24.8 fixed point number, such as the coordinates of
+// pointer events.
+type Fixed int32
+
+// FixedFromFloat returns the fixed point number closest to f.
+func FixedFromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * 256))
+}
+
+// FixedFromInt returns the fixed point number of i.
+func FixedFromInt(i int) Fixed {
+	return Fixed(i * 256)
+}
+
+// Float returns the number as a float64, which represents it exactly.
+func (f Fixed) Float() float64 {
+	return float64(f) / 256
+}
+
+// Int returns the integer part of the number, rounded toward zero.
+func (f Fixed) Int() int {
+	return int(f / 256)
+}
+
+func (f Fixed) String() string {
+	return strconv.FormatFloat(f.Float(), 'f', -1, 64)
+}
+
+// NullString is a string argument that may be null, which is sent
+// differently from the empty string. A null object is the ObjectId 0.
+type NullString struct {
+	String string
+	Valid  bool // Valid is false for null.
+}
+
+var nullStringType = reflect.TypeOf(NullString{})
+
+// NewId is a new object of an interface the message does not tell, such as
+// the one bound by wl_registry.bind. The interface and version are sent
+// before the id.
+type NewId struct {
+	Interface string
+	Version   uint32
+	Id        ObjectId
 }
 
-func NewMessage(objectId ObjectId, opcode Opcode, data interface{}) (msg Message) {
+var newIdType = reflect.TypeOf(NewId{})
+
+// NewMessage encodes a message with the arguments in the fields of data, a
+// struct such as SurfaceAttach.
+func NewMessage(objectId ObjectId, opcode Opcode, data interface{}) (msg Message, err error) {
 	var b bytes.Buffer
-	err := marshall(&b, data)
+	var fds []int
+	err = marshall(&b, &fds, data)
 	if err != nil {
-		panic(err)
+		return msg, fmt.Errorf("encoding message %d of object %d: %w", opcode, objectId, err)
+	}
+	if 8+b.Len() > math.MaxUint16 {
+		return msg, fmt.Errorf("encoding message %d of object %d: %d bytes is too large", opcode, objectId, 8+b.Len())
+	}
+	if len(fds) > maxFds {
+		return msg, fmt.Errorf("encoding message %d of object %d: %d file descriptors is too many", opcode, objectId, len(fds))
 	}
-	return NewMessageBytes(objectId, opcode, b.Bytes())
+	msg = NewMessageBytes(objectId, opcode, b.Bytes())
+	msg.Fds = fds
+	return msg, nil
 }
 
 func NewMessageBytes(objectId ObjectId, opcode Opcode, payload []byte) (msg Message) {
@@ -33,9 +106,12 @@ func NewMessageBytes(objectId ObjectId, opcode Opcode, payload []byte) (msg Mess
 	return
 }
 
+// ReadMessage reads a message from r, without file descriptors. Like
+// io.ReadFull, it fails with io.EOF if r ends before the message, and with
+// io.ErrUnexpectedEOF if it ends in the middle of it.
 func ReadMessage(r io.Reader) (msg Message, err error) {
 	var header [8]byte
-	_, err = r.Read(header[:])
+	_, err = io.ReadFull(r, header[:])
 	if err != nil {
 		return msg, fmt.Errorf("reading message header: %w", err)
 	}
@@ -44,9 +120,15 @@ func ReadMessage(r io.Reader) (msg Message, err error) {
 	msg.ObjectId = ObjectId(binary.LittleEndian.Uint32(header[0:4]))
 	msg.Size = uint16(sizeAndOpcode >> 16)
 	msg.Opcode = Opcode(sizeAndOpcode)
+	if msg.Size < 8 {
+		return msg, fmt.Errorf("reading message header: invalid size %d", msg.Size)
+	}
 
 	msg.Payload = make([]byte, msg.Size-8)
-	_, err = r.Read(msg.Payload)
+	_, err = io.ReadFull(r, msg.Payload)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
 	if err != nil {
 		return msg, fmt.Errorf("reading message payload: %w", err)
 	}
@@ -54,37 +136,57 @@ func ReadMessage(r io.Reader) (msg Message, err error) {
 	return
 }
 
+// Write writes the message to w with a single call, without its file
+// descriptors.
 func (msg *Message) Write(w io.Writer) (err error) {
-	var header [8]byte
+	buf := make([]byte, 8+len(msg.Payload))
 	sizeAndOpcode := (uint32(msg.Size) << 16) | uint32(msg.Opcode)
-	binary.LittleEndian.PutUint32(header[0:4], uint32(msg.ObjectId))
-	binary.LittleEndian.PutUint32(header[4:8], sizeAndOpcode)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(msg.ObjectId))
+	binary.LittleEndian.PutUint32(buf[4:8], sizeAndOpcode)
+	copy(buf[8:], msg.Payload)
 
-	_, err = w.Write(header[:])
+	_, err = w.Write(buf)
 	if err != nil {
-		return fmt.Errorf("writing message header: %w", err)
+		return fmt.Errorf("writing message: %w", err)
 	}
+	return nil
+}
 
-	_, err = w.Write(msg.Payload)
+// Unmarshall decodes the arguments of the message into the fields of data,
+// a pointer to a struct such as *SurfaceEnter.
+func (msg *Message) Unmarshall(data interface{}) error {
+	buf := bytes.NewBuffer(msg.Payload)
+	err := unmarshall(buf, msg.nextFd(), data)
 	if err != nil {
-		return fmt.Errorf("writing message payload: %w", err)
+		return fmt.Errorf("decoding message %d of object %d: %w", msg.Opcode, msg.ObjectId, err)
 	}
-
 	return nil
 }
 
-func (msg *Message) Unmarshall(data interface{}) {
-	buf := bytes.NewBuffer(msg.Payload)
-	err := unmarshall(buf, data)
-	if err != nil {
-		panic(err)
+// nextFd returns a function returning the fds of the message in order.
+// Fds already attached are returned first, so that unmarshalling a
+// message again yields the same descriptors.
+func (msg *Message) nextFd() func() (int, error) {
+	i := 0
+	return func() (int, error) {
+		if i < len(msg.Fds) {
+			i++
+			return msg.Fds[i-1], nil
+		}
+		if msg.fdq == nil || len(msg.fdq.fds) == 0 {
+			return -1, fmt.Errorf("no file descriptor received")
+		}
+		fd := msg.fdq.pop()
+		msg.Fds = append(msg.Fds, fd)
+		i++
+		return fd, nil
 	}
 }
 
-func unmarshall(r io.Reader, data interface{}) (err error) {
+func unmarshall(r *bytes.Buffer, nextFd func() (int, error), data interface{}) (err error) {
 	dataValue := reflect.ValueOf(data)
 	if dataValue.Kind() != reflect.Ptr || dataValue.Elem().Kind() != reflect.Struct {
-		panic("'data' must be a pointer to a struct value")
+		return fmt.Errorf("'data' must be a pointer to a struct value, not %T", data)
 	}
 
 	elemValue := dataValue.Elem()
@@ -93,32 +195,39 @@ func unmarshall(r io.Reader, data interface{}) (err error) {
 	for i := 0; i < numField; i++ {
 		field := elemValue.Field(i)
 
-		switch field.Kind() {
-		case reflect.Uint32:
+		switch {
+		case field.Type() == fdType:
+			var fd int
+			fd, err = nextFd()
+			field.SetInt(int64(fd))
+		case field.Kind() == reflect.Uint32:
 			var n uint32
 			n, err = unmarshallUint32(r)
 			field.SetUint(uint64(n))
-		case reflect.Int32:
+		case field.Kind() == reflect.Int32:
 			var n int32
 			n, err = unmarshallInt32(r)
 			field.SetInt(int64(n))
-		case reflect.Float32:
-			var n float32
-			n, err = unmarshallFloat32(r)
-			field.SetFloat(float64(n))
-		case reflect.String:
-			var s string
+		case field.Kind() == reflect.String:
+			var s NullString
 			s, err = unmarshallString(r)
-			field.SetString(s)
-		case reflect.Array:
+			field.SetString(s.String)
+		case field.Type() == nullStringType:
+			var s NullString
+			s, err = unmarshallString(r)
+			field.Set(reflect.ValueOf(s))
+		case field.Type() == newIdType:
+			var id NewId
+			id, err = unmarshallNewId(r)
+			field.Set(reflect.ValueOf(id))
+		case field.Kind() == reflect.Slice:
 			var a []byte
-			if field.Elem().Kind() != reflect.Uint8 {
-				unsuportedType(field)
-			}
 			a, err = unmarshallArray(r)
-			field.SetBytes(a)
+			if err == nil {
+				err = setArray(field, a)
+			}
 		default:
-			unsuportedType(field)
+			err = unsupportedType(field)
 		}
 		if err != nil {
 			return fmt.Errorf("unmarshalling field '%s': %w",
@@ -130,67 +239,101 @@ func unmarshall(r io.Reader, data interface{}) (err error) {
 	return nil
 }
 
-func unsuportedType(field reflect.Value) {
-	panic("Unsuported field type '" + field.Type().Name() + "'")
+func unsupportedType(field reflect.Value) error {
+	return fmt.Errorf("unsupported field type '%s'", field.Type())
 }
 
-func unmarshallUint32(r io.Reader) (v uint32, err error) {
+func unmarshallUint32(r *bytes.Buffer) (v uint32, err error) {
 	var buf [4]byte
-	_, err = r.Read(buf[:])
+	_, err = io.ReadFull(r, buf[:])
 	if err != nil {
 		return v, fmt.Errorf("unmarshalling uint32 value: %w", err)
 	}
 	return binary.LittleEndian.Uint32(buf[:]), nil
 }
 
-func unmarshallInt32(r io.Reader) (v int32, err error) {
+func unmarshallInt32(r *bytes.Buffer) (v int32, err error) {
 	var buf [4]byte
-	_, err = r.Read(buf[:])
+	_, err = io.ReadFull(r, buf[:])
 	if err != nil {
 		return v, fmt.Errorf("unmarshalling int32 value: %w", err)
 	}
 	return int32(binary.LittleEndian.Uint32(buf[:])), nil
 }
 
-func unmarshallFloat32(r io.Reader) (v float32, err error) {
-	var buf [4]byte
-	_, err = r.Read(buf[:])
+// unmarshallString decodes a string that may be null. Null strings have
+// no length, other strings end with a NUL.
+func unmarshallString(r *bytes.Buffer) (s NullString, err error) {
+	b, err := unmarshallArray(r)
 	if err != nil {
-		return v, fmt.Errorf("unmarshalling float32 value: %w", err)
+		return s, fmt.Errorf("unmarshalling string value: %w", err)
+	}
+
+	if len(b) == 0 {
+		return s, nil
 	}
-	b := binary.LittleEndian.Uint32(buf[:])
-	return math.Float32frombits(b), nil
+	if b[len(b)-1] != 0 {
+		return s, fmt.Errorf("unmarshalling string value: missing NUL terminator")
+	}
+	return NullString{String: string(b[:len(b)-1]), Valid: true}, nil
 }
 
-func unmarshallString(r io.Reader) (s string, err error) {
-	var buf [4]byte
-	_, err = r.Read(buf[:])
+func unmarshallNewId(r *bytes.Buffer) (id NewId, err error) {
+	iface, err := unmarshallString(r)
 	if err != nil {
-		return s, fmt.Errorf("unmarshalling string value: %w", err)
+		return id, err
 	}
-
-	l := int(binary.LittleEndian.Uint32(buf[:]))
-	p := (4 - l%4) % 4
-	b := make([]byte, l+p)
-	_, err = r.Read(b)
+	id.Interface = iface.String
+	id.Version, err = unmarshallUint32(r)
 	if err != nil {
-		return s, fmt.Errorf("unmarshalling string value: %w", err)
+		return id, err
 	}
+	n, err := unmarshallUint32(r)
+	id.Id = ObjectId(n)
+	return id, err
+}
 
-	return string(b[0 : l-1]), nil
+// setArray sets a slice of bytes, or of 32-bit numbers in the byte order
+// of the wire, to the contents of an array argument.
+func setArray(field reflect.Value, a []byte) error {
+	switch field.Type().Elem().Kind() {
+	case reflect.Uint8:
+		field.SetBytes(a)
+	case reflect.Uint32, reflect.Int32:
+		if len(a)%4 != 0 {
+			return fmt.Errorf("unmarshalling array value: %d bytes is not a multiple of 4", len(a))
+		}
+		v := reflect.MakeSlice(field.Type(), len(a)/4, len(a)/4)
+		for i := 0; i < v.Len(); i++ {
+			n := binary.LittleEndian.Uint32(a[4*i:])
+			if v.Index(i).Kind() == reflect.Uint32 {
+				v.Index(i).SetUint(uint64(n))
+			} else {
+				v.Index(i).SetInt(int64(int32(n)))
+			}
+		}
+		field.Set(v)
+	default:
+		return unsupportedType(field)
+	}
+	return nil
 }
 
-func unmarshallArray(r io.Reader) (b []byte, err error) {
+func unmarshallArray(r *bytes.Buffer) (b []byte, err error) {
 	var buf [4]byte
-	_, err = r.Read(buf[:])
+	_, err = io.ReadFull(r, buf[:])
 	if err != nil {
 		return b, fmt.Errorf("unmarshalling array value: %w", err)
 	}
 
+	// The length is checked against the payload before allocating.
 	l := int(binary.LittleEndian.Uint32(buf[:]))
 	p := (4 - l%4) % 4
+	if l < 0 || l+p > r.Len() {
+		return b, fmt.Errorf("unmarshalling array value: length %d exceeds the %d bytes left", l, r.Len())
+	}
 	b = make([]byte, l+p)
-	_, err = r.Read(b)
+	_, err = io.ReadFull(r, b)
 	if err != nil {
 		return b, fmt.Errorf("unmarshalling array value: %w", err)
 	}
@@ -198,10 +341,10 @@ func unmarshallArray(r io.Reader) (b []byte, err error) {
 	return b[0:l], nil
 }
 
-func marshall(w io.Writer, data interface{}) (err error) {
+func marshall(w io.Writer, fds *[]int, data interface{}) (err error) {
 	elemValue := reflect.ValueOf(data)
 	if elemValue.Kind() != reflect.Struct {
-		panic("'data' must be a struct value")
+		return fmt.Errorf("'data' must be a struct value, not %T", data)
 	}
 
 	numField := elemValue.NumField()
@@ -209,22 +352,27 @@ func marshall(w io.Writer, data interface{}) (err error) {
 	for i := 0; i < numField; i++ {
 		field := elemValue.Field(i)
 
-		switch field.Kind() {
-		case reflect.Uint32:
+		switch {
+		case field.Type() == fdType:
+			*fds = append(*fds, int(field.Int()))
+		case field.Kind() == reflect.Uint32:
 			err = marshallUint32(w, uint32(field.Uint()))
-		case reflect.Int32:
+		case field.Kind() == reflect.Int32:
 			err = marshallInt32(w, int32(field.Int()))
-		case reflect.Float32:
-			err = marshallFloat32(w, float32(field.Float()))
-		case reflect.String:
-			err = marshallString(w, field.String())
-		case reflect.Array:
-			if field.Elem().Kind() != reflect.Uint8 {
-				unsuportedType(field)
+		case field.Kind() == reflect.String:
+			err = marshallString(w, NullString{String: field.String(), Valid: true})
+		case field.Type() == nullStringType:
+			err = marshallString(w, field.Interface().(NullString))
+		case field.Type() == newIdType:
+			err = marshallNewId(w, field.Interface().(NewId))
+		case field.Kind() == reflect.Slice:
+			var a []byte
+			a, err = arrayBytes(field)
+			if err == nil {
+				err = marshallArray(w, a)
 			}
-			err = marshallArray(w, field.Bytes())
 		default:
-			unsuportedType(field)
+			err = unsupportedType(field)
 		}
 		if err != nil {
 			return fmt.Errorf("marshalling field '%s': %w",
@@ -256,18 +404,14 @@ func marshallInt32(w io.Writer, v int32) (err error) {
 	return nil
 }
 
-func marshallFloat32(w io.Writer, v float32) (err error) {
-	n := math.Float32bits(v)
-	var buf [4]byte
-	binary.LittleEndian.PutUint32(buf[:], n)
-	_, err = w.Write(buf[:])
-	if err != nil {
-		return fmt.Errorf("marshalling float32 value: %w", err)
+func marshallString(w io.Writer, ns NullString) (err error) {
+	if !ns.Valid {
+		return marshallUint32(w, 0)
+	}
+	s := ns.String
+	if strings.IndexByte(s, 0) >= 0 {
+		return fmt.Errorf("marshalling string value: contains a NUL byte")
 	}
-	return nil
-}
-
-func marshallString(w io.Writer, s string) (err error) {
 	l := len(s) + 1
 	p := (4 - l%4) % 4
 
@@ -287,6 +431,39 @@ func marshallString(w io.Writer, s string) (err error) {
 	return nil
 }
 
+func marshallNewId(w io.Writer, id NewId) (err error) {
+	err = marshallString(w, NullString{String: id.Interface, Valid: true})
+	if err != nil {
+		return err
+	}
+	err = marshallUint32(w, id.Version)
+	if err != nil {
+		return err
+	}
+	return marshallUint32(w, uint32(id.Id))
+}
+
+// arrayBytes returns the contents of an array argument held in a slice of
+// bytes or of 32-bit numbers.
+func arrayBytes(field reflect.Value) ([]byte, error) {
+	switch field.Type().Elem().Kind() {
+	case reflect.Uint8:
+		return field.Bytes(), nil
+	case reflect.Uint32, reflect.Int32:
+		a := make([]byte, 4*field.Len())
+		for i := 0; i < field.Len(); i++ {
+			e := field.Index(i)
+			if e.Kind() == reflect.Uint32 {
+				binary.LittleEndian.PutUint32(a[4*i:], uint32(e.Uint()))
+			} else {
+				binary.LittleEndian.PutUint32(a[4*i:], uint32(e.Int()))
+			}
+		}
+		return a, nil
+	}
+	return nil, unsupportedType(field)
+}
+
 func marshallArray(w io.Writer, a []byte) (err error) {
 	l := len(a)
 	p := (4 - l%4) % 4
@@ -0,0 +1,165 @@
+package wayland
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// allTypes has a field of every argument type.
+type allTypes struct {
+	Int       int32
+	Uint      uint32
+	Enum      XdgToplevelState
+	Fixed     Fixed
+	String    string
+	Null      NullString
+	Empty     NullString
+	Object    ObjectId
+	NullObj   ObjectId
+	NewId     NewId
+	Bytes     []byte
+	States    []XdgToplevelState
+	Ints      []int32
+	EmptyList []uint32
+	Fd        Fd
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := allTypes{
+		Int:       -7,
+		Uint:      0xfffffffe,
+		Enum:      XdgToplevelStateActivated,
+		Fixed:     FixedFromFloat(-12.75),
+		String:    "héllo",
+		Null:      NullString{},
+		Empty:     NullString{Valid: true},
+		Object:    12,
+		NewId:     NewId{Interface: "wl_seat", Version: 7, Id: 13},
+		Bytes:     []byte{1, 2, 3},
+		States:    []XdgToplevelState{XdgToplevelStateMaximized, XdgToplevelStateActivated},
+		Ints:      []int32{-1, 1 << 30},
+		EmptyList: []uint32{},
+		Fd:        9,
+	}
+
+	msg, err := NewMessage(3, 1, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Fds) != 1 || msg.Fds[0] != 9 {
+		t.Errorf("passed fds %v", msg.Fds)
+	}
+	if len(msg.Payload)%4 != 0 {
+		t.Errorf("payload of %d bytes is not padded", len(msg.Payload))
+	}
+
+	var got allTypes
+	mustUnmarshall(t, &msg, &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Null and empty strings differ on the wire, and decode to "".
+	var s struct{ A, B string }
+	msg = newMessage(t, 3, 0, struct{ A, B NullString }{NullString{}, NullString{Valid: true}})
+	if len(msg.Payload) != 4+8 {
+		t.Errorf("null and empty strings sent in %d bytes", len(msg.Payload))
+	}
+	mustUnmarshall(t, &msg, &s)
+	if s.A != "" || s.B != "" {
+		t.Errorf("decoded %+v", s)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	for _, tt := range []struct {
+		f     float64
+		fixed Fixed
+		i     int
+	}{
+		{0, 0, 0},
+		{1, 256, 1},
+		{-1, -256, -1},
+		{10.5, 2688, 10},
+		{-10.5, -2688, -10},
+		{1.0 / 256, 1, 0},
+		{-0.25, -64, 0},
+	} {
+		if got := FixedFromFloat(tt.f); got != tt.fixed {
+			t.Errorf("FixedFromFloat(%v) = %d, want %d", tt.f, got, tt.fixed)
+		}
+		if got := tt.fixed.Float(); got != tt.f {
+			t.Errorf("%d.Float() = %v, want %v", tt.fixed, got, tt.f)
+		}
+		if got := tt.fixed.Int(); got != tt.i {
+			t.Errorf("%d.Int() = %d, want %d", tt.fixed, got, tt.i)
+		}
+	}
+	if FixedFromInt(-3) != FixedFromFloat(-3) || FixedFromFloat(10.5).String() != "10.5" {
+		t.Errorf("FixedFromInt(-3) = %d, String() = %s", FixedFromInt(-3), FixedFromFloat(10.5))
+	}
+
+	// Sent as the bits of the number.
+	msg := newMessage(t, 3, 0, struct{ X Fixed }{FixedFromFloat(-1.5)})
+	if n := int32(binary.LittleEndian.Uint32(msg.Payload)); n != -384 {
+		t.Errorf("sent %d", n)
+	}
+}
+
+func TestNewMessageErrors(t *testing.T) {
+	for _, data := range []interface{}{
+		&DisplaySync{},
+		struct{ N int }{},
+		XdgToplevelSetTitle{Title: "a\x00b"},
+		XdgToplevelSetTitle{Title: strings.Repeat("x", 1<<16)},
+		struct{ A [4]byte }{},
+		struct{ A []uint16 }{},
+		struct{ S NullString }{NullString{String: "a\x00", Valid: true}},
+	} {
+		_, err := NewMessage(3, 0, data)
+		if err == nil {
+			t.Errorf("encoded %T", data)
+		}
+	}
+}
+
+func TestUnmarshallErrors(t *testing.T) {
+	length := func(n uint32, rest ...byte) []byte {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, n)
+		return append(b, rest...)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		payload []byte
+		data    interface{}
+	}{
+		{"short uint", []byte{1, 2}, &DisplaySync{}},
+		{"missing string", nil, &XdgToplevelSetTitle{}},
+		{"long string", length(1 << 30), &XdgToplevelSetTitle{}},
+		{"short string", length(8, 'a', 'b', 'c', 0), &XdgToplevelSetTitle{}},
+		{"unterminated string", length(4, 'a', 'b', 'c', 'd'), &XdgToplevelSetTitle{}},
+		{"not a pointer", length(0), XdgToplevelSetTitle{}},
+		{"unsupported field", length(0), &struct{ N int }{}},
+		{"unsupported array", length(0), &struct{ A [4]byte }{}},
+		{"partial number", length(2, 1, 2, 0, 0), &struct{ A []uint32 }{}},
+		{"short new_id", length(0, 1, 0, 0, 0), &struct{ Id NewId }{}},
+	} {
+		msg := NewMessageBytes(3, 0, tt.payload)
+		err := msg.Unmarshall(tt.data)
+		if err == nil {
+			t.Errorf("%s: decoded %+v", tt.name, tt.data)
+		}
+	}
+
+	// Null strings have no length.
+	var title XdgToplevelSetTitle
+	msg := NewMessageBytes(3, 0, length(0))
+	err := msg.Unmarshall(&title)
+	if err != nil || title.Title != "" {
+		t.Errorf("decoded null string as %q, %v", title.Title, err)
+	}
+}
@@ -0,0 +1,61 @@
+package wayland
+
+import (
+	"fmt"
+)
+
+// errorNames are the names of the codes of the error enum of each
+// interface, as registered by the generated code.
+var errorNames = make(map[string]map[uint32]string)
+
+// ProtocolError is a fatal error sent by the compositor with
+// wl_display.error, for a request it could not process. The connection is
+// unusable afterwards.
+type ProtocolError struct {
+	ObjectId ObjectId
+	// Interface is the interface of the object, if it is known.
+	Interface string
+	Code      uint32
+	Message   string
+}
+
+// CodeName returns the name of the code in the error enum of the interface,
+// such as "invalid_stride" for wl_shm, or "" if it is unknown. Interfaces
+// without error enum only get the global errors of wl_display.
+func (e *ProtocolError) CodeName() string {
+	names, ok := errorNames[e.Interface]
+	if !ok {
+		names = errorNames[DisplayInterface]
+	}
+	return names[e.Code]
+}
+
+func (e *ProtocolError) Error() string {
+	object := fmt.Sprintf("object %d", e.ObjectId)
+	if e.Interface != "" {
+		object = fmt.Sprintf("%s@%d", e.Interface, e.ObjectId)
+	}
+	code := fmt.Sprint(e.Code)
+	if name := e.CodeName(); name != "" {
+		code = fmt.Sprintf("%d (%s)", e.Code, name)
+	}
+	return fmt.Sprintf("Wayland protocol error %s on %s: %s", code, object, e.Message)
+}
+
+// disconnectedError is returned once disconnected. It is ErrDisconnected,
+// and wraps the cause of the disconnection, such as a *ProtocolError.
+type disconnectedError struct {
+	cause error
+}
+
+func (e *disconnectedError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrDisconnected, e.cause)
+}
+
+func (e *disconnectedError) Is(target error) bool {
+	return target == ErrDisconnected
+}
+
+func (e *disconnectedError) Unwrap() error {
+	return e.cause
+}
@@ -0,0 +1,231 @@
+package wayland
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// ARGB is an in-memory image in the ARGB8888 format of wl_shm: premultiplied
+// 32-bit little-endian pixels, stored as blue, green, red and alpha bytes.
+// It has the same methods as image.RGBA, and color.RGBA values are stored
+// without conversion but for the order of channels.
+type ARGB struct {
+	Pix    []uint8
+	Stride int
+	Rect   image.Rectangle
+}
+
+func (p *ARGB) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (p *ARGB) Bounds() image.Rectangle {
+	return p.Rect
+}
+
+func (p *ARGB) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+func (p *ARGB) RGBAAt(x, y int) color.RGBA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return color.RGBA{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	return color.RGBA{s[2], s[1], s[0], s[3]}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *ARGB) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+func (p *ARGB) Set(x, y int, c color.Color) {
+	p.SetRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+func (p *ARGB) SetRGBA(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	s[0], s[1], s[2], s[3] = c.B, c.G, c.R, c.A
+}
+
+// SubImage returns an image representing the portion of p visible through
+// r, sharing its pixels.
+func (p *ARGB) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	if r.Empty() {
+		return &ARGB{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &ARGB{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// CopyRGBA copies the pixels of src in r to the same position in p.
+func (p *ARGB) CopyRGBA(src *image.RGBA, r image.Rectangle) {
+	r = r.Intersect(p.Rect).Intersect(src.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		in := src.Pix[src.PixOffset(r.Min.X, y):]
+		out := p.Pix[p.PixOffset(r.Min.X, y):]
+
+		for x := 0; x < r.Dx(); x++ {
+			s := in[4*x : 4*x+4]
+			d := out[4*x : 4*x+4]
+			d[0], d[1], d[2], d[3] = s[2], s[1], s[0], s[3]
+		}
+	}
+}
+
+// Frame is a buffer to draw the next image of a window into, obtained with
+// NextFrame. Its pixels are shared with the compositor, and must not be
+// used once it is committed.
+type Frame struct {
+	*ARGB
+
+	b      *Backend
+	buffer *shmBuffer
+}
+
+// nextFrame returns a buffer of the given size to draw the next frame of a
+// surface into. Buffers are reused once the compositor released them, and
+// nextFrame waits for it when all of them are in use.
+//
+// The content of the buffer is undefined: it may be an older frame, so it
+// has to be drawn entirely.
+func (b *Backend) nextFrame(surface ObjectId, width, height int) (f *Frame, err error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("allocating %dx%d frame: invalid size", width, height)
+	}
+
+	buf, err := b.frameBuffer(surface, width, height)
+	if err != nil {
+		return nil, fmt.Errorf("allocating %dx%d frame: %w", width, height, err)
+	}
+
+	// The buffer is busy until committed and released, so that it is not
+	// handed out twice.
+	buf.busy = true
+	return &Frame{
+		ARGB: &ARGB{
+			Pix:    b.pool.bytes(buf),
+			Stride: buf.stride,
+			Rect:   image.Rect(0, 0, width, height),
+		},
+		b:      b,
+		buffer: buf,
+	}, nil
+}
+
+// frameBuffer returns a free buffer of the given size for a surface,
+// allocating one or waiting for the compositor to release one.
+func (b *Backend) frameBuffer(surface ObjectId, width, height int) (*shmBuffer, error) {
+	size := width * height * 4
+	if b.pool == nil {
+		pool, err := b.newShmPool(framesPerSize * size)
+		if err != nil {
+			return nil, err
+		}
+		b.pool = pool
+	}
+
+	for {
+		// Released buffers of other sizes are left by a resize.
+		count := 0
+		for _, buf := range b.pool.buffers {
+			if buf.surface != surface {
+				continue
+			}
+			if buf.width != width || buf.height != height {
+				if !buf.busy {
+					err := b.pool.destroyBuffer(buf)
+					if err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+			if !buf.busy {
+				return buf, nil
+			}
+			count++
+		}
+
+		if count < framesPerSize {
+			buf, err := b.pool.newBuffer(b, width, height)
+			if err != nil {
+				return nil, err
+			}
+			buf.surface = surface
+			return buf, nil
+		}
+
+		err := b.Dispatch()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// destroyFrames destroys the buffers of a surface. Those still in use are
+// kept by the compositor until it is done with them.
+func (b *Backend) destroyFrames(surface ObjectId) {
+	if b.pool == nil {
+		return
+	}
+	for _, buf := range b.pool.buffers {
+		if buf.surface == surface {
+			b.pool.destroyBuffer(buf)
+		}
+	}
+}
+
+// Commit shows the frame on the surface. Damage is the part of the frame
+// that changed since the previous one, or all of it if none is given.
+func (f *Frame) Commit(damage ...image.Rectangle) error {
+	if f.buffer == nil {
+		return fmt.Errorf("committing frame: already committed")
+	}
+	b := f.b
+	buf := f.buffer
+	f.buffer = nil
+
+	err := b.request(buf.surface, OpSurfaceAttach, SurfaceAttach{Buffer: buf.proxy.Id})
+	if err != nil {
+		return fmt.Errorf("committing frame: %w", err)
+	}
+
+	if len(damage) == 0 {
+		damage = []image.Rectangle{f.Rect}
+	}
+	for _, r := range damage {
+		r = r.Intersect(f.Rect)
+		if r.Empty() {
+			continue
+		}
+		err = b.request(buf.surface, OpSurfaceDamage, SurfaceDamage{
+			X:      int32(r.Min.X),
+			Y:      int32(r.Min.Y),
+			Width:  int32(r.Dx()),
+			Height: int32(r.Dy()),
+		})
+		if err != nil {
+			return fmt.Errorf("committing frame: %w", err)
+		}
+	}
+
+	err = b.request(buf.surface, OpSurfaceCommit, SurfaceCommit{})
+	if err != nil {
+		return fmt.Errorf("committing frame: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,492 @@
+// Command scanner generates Go bindings for Wayland protocols from their XML
+// descriptions, such as wayland.xml and the files of wayland-protocols.
+//
+// Usage:
+//
+//	scanner -o output.go [-package name] [-interfaces a,b] protocol.xml [other.xml...]
+//
+// Every file is parsed so that enums can refer to interfaces of the others,
+// but only the interfaces of the first one are written, or those of them
+// listed with -interfaces, so that a complete protocol file can be vendored
+// while only the interfaces used get bindings. For each interface
+// it writes the opcodes of its requests and events, enum types, and structs
+// for the arguments of each message, encoded by NewMessage and decoded by
+// Message.Unmarshall. The events, the names of the messages and those of
+// the error codes are registered by interface name, so that
+// Backend.Dispatch can decode events for the handlers of objects, requests
+// and events can be traced, and protocol errors name their codes.
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type protocol struct {
+	Name       string  `xml:"name,attr"`
+	Interfaces []iface `xml:"interface"`
+}
+
+type iface struct {
+	Name     string      `xml:"name,attr"`
+	Version  int         `xml:"version,attr"`
+	Desc     description `xml:"description"`
+	Requests []message   `xml:"request"`
+	Events   []message   `xml:"event"`
+	Enums    []enum      `xml:"enum"`
+}
+
+type message struct {
+	Name  string      `xml:"name,attr"`
+	Type  string      `xml:"type,attr"`
+	Since int         `xml:"since,attr"`
+	Desc  description `xml:"description"`
+	Args  []arg       `xml:"arg"`
+}
+
+type arg struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	Interface string `xml:"interface,attr"`
+	Enum      string `xml:"enum,attr"`
+	AllowNull bool   `xml:"allow-null,attr"`
+	Summary   string `xml:"summary,attr"`
+}
+
+type enum struct {
+	Name     string      `xml:"name,attr"`
+	Since    int         `xml:"since,attr"`
+	Bitfield bool        `xml:"bitfield,attr"`
+	Desc     description `xml:"description"`
+	Entries  []entry     `xml:"entry"`
+}
+
+type entry struct {
+	Name    string `xml:"name,attr"`
+	Value   string `xml:"value,attr"`
+	Since   int    `xml:"since,attr"`
+	Summary string `xml:"summary,attr"`
+}
+
+type description struct {
+	Summary string `xml:"summary,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("scanner: ")
+
+	output := flag.String("o", "", "output file")
+	pkg := flag.String("package", "wayland", "package of the generated code")
+	names := flag.String("interfaces", "", "comma separated interfaces to generate, instead of all")
+	flag.Parse()
+	if *output == "" || flag.NArg() == 0 {
+		log.Fatal("usage: scanner -o output.go [-package name] [-interfaces a,b] protocol.xml [other.xml...]")
+	}
+
+	var protocols []*protocol
+	for _, path := range flag.Args() {
+		p, err := parse(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		protocols = append(protocols, p)
+	}
+
+	if *names != "" {
+		err := selectInterfaces(protocols[0], strings.Split(*names, ","))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	g := newGenerator(protocols)
+	src, err := g.generate(*pkg, filepath.Base(flag.Arg(0)), protocols[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = os.WriteFile(*output, src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parse(path string) (*protocol, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p protocol
+	err = xml.Unmarshal(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// selectInterfaces keeps the named interfaces of p, in the order of the
+// protocol file.
+func selectInterfaces(p *protocol, names []string) error {
+	want := make(map[string]bool)
+	for _, name := range names {
+		want[name] = true
+	}
+
+	var kept []iface
+	for _, i := range p.Interfaces {
+		if want[i.Name] {
+			kept = append(kept, i)
+			delete(want, i.Name)
+		}
+	}
+	for _, name := range names {
+		if want[name] {
+			return fmt.Errorf("protocol %s has no interface %s", p.Name, name)
+		}
+	}
+	p.Interfaces = kept
+	return nil
+}
+
+type generator struct {
+	buf bytes.Buffer
+
+	// enums are the Go types of the enums of all protocols, by their
+	// qualified name such as "wl_shm.format".
+	enums map[string]string
+}
+
+func newGenerator(protocols []*protocol) *generator {
+	g := &generator{enums: make(map[string]string)}
+	for _, p := range protocols {
+		for _, i := range p.Interfaces {
+			for _, e := range i.Enums {
+				g.enums[i.Name+"."+e.Name] = typeName(i.Name, e.Name)
+			}
+		}
+	}
+	return g
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) generate(pkg, source string, p *protocol) ([]byte, error) {
+	g.printf("// Code generated by scanner from %s; DO NOT EDIT.\n\n", source)
+	g.printf("package %s\n\n", pkg)
+
+	g.printf("// Names of the interfaces of the %s protocol, and the versions described.\n", p.Name)
+	g.printf("const (\n")
+	for _, i := range p.Interfaces {
+		name := camel(trimPrefix(i.Name))
+		g.printf("%sInterface = %q\n", name, i.Name)
+		g.printf("%sVersion = %d\n", name, i.Version)
+	}
+	g.printf(")\n\n")
+
+	for _, i := range p.Interfaces {
+		g.iface(i)
+	}
+
+	// The events are registered for Backend.Dispatch to decode them, the
+	// names of the messages for tracing, and the names of the error codes
+	// for ProtocolError.
+	g.printf("func init() {\n")
+	for _, i := range p.Interfaces {
+		name := camel(trimPrefix(i.Name))
+		g.names("requestNames", name, i.Requests)
+		g.names("eventNames", name, i.Events)
+		if len(i.Events) > 0 {
+			_, events := messageNames(i)
+			g.printf("eventTypes[%sInterface] = []interface{}{\n", name)
+			for _, event := range events {
+				g.printf("%s{},\n", event)
+			}
+			g.printf("}\n")
+		}
+		for _, e := range i.Enums {
+			if e.Name != "error" {
+				continue
+			}
+			g.printf("errorNames[%sInterface] = map[uint32]string{\n", name)
+			for _, v := range e.Entries {
+				g.printf("%s: %q,\n", v.Value, v.Name)
+			}
+			g.printf("}\n")
+		}
+	}
+	g.printf("}\n")
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+// names registers the names of the messages of an interface in table.
+func (g *generator) names(table, name string, messages []message) {
+	if len(messages) == 0 {
+		return
+	}
+	g.printf("%s[%sInterface] = []string{", table, name)
+	for n, m := range messages {
+		if n > 0 {
+			g.printf(", ")
+		}
+		g.printf("%q", m.Name)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) iface(i iface) {
+	requests, events := messageNames(i)
+
+	if len(i.Requests) > 0 {
+		g.printf("// Opcodes of the requests of %s.\n", i.Name)
+		g.printf("const (\n")
+		for n := range i.Requests {
+			g.printf("Op%s Opcode = %d\n", requests[n], n)
+		}
+		g.printf(")\n\n")
+	}
+	if len(i.Events) > 0 {
+		g.printf("// Opcodes of the events of %s.\n", i.Name)
+		g.printf("const (\n")
+		for n := range i.Events {
+			g.printf("Op%s Opcode = %d\n", events[n], n)
+		}
+		g.printf(")\n\n")
+	}
+
+	for _, e := range i.Enums {
+		g.enum(i, e)
+	}
+	for n, m := range i.Requests {
+		g.message(i, m, requests[n], "request")
+	}
+	for n, m := range i.Events {
+		g.message(i, m, events[n], "event")
+	}
+}
+
+// messageNames returns the Go names of the requests and events of an
+// interface. Messages are named after the interface and the message. Those
+// that clash with an enum, such as the wl_display.error event, or with a
+// request of the same name, get a suffix.
+func messageNames(i iface) (requests, events []string) {
+	enums := make(map[string]bool)
+	for _, e := range i.Enums {
+		enums[typeName(i.Name, e.Name)] = true
+	}
+
+	requests = make([]string, len(i.Requests))
+	names := make(map[string]bool)
+	for n, m := range i.Requests {
+		requests[n] = typeName(i.Name, m.Name)
+		if enums[requests[n]] {
+			requests[n] += "Request"
+		}
+		names[requests[n]] = true
+	}
+	events = make([]string, len(i.Events))
+	for n, m := range i.Events {
+		events[n] = typeName(i.Name, m.Name)
+		if enums[events[n]] || names[events[n]] {
+			events[n] += "Event"
+		}
+	}
+	return requests, events
+}
+
+func (g *generator) enum(i iface, e enum) {
+	name := typeName(i.Name, e.Name)
+	g.doc(name, fmt.Sprintf("the %s.%s enum", i.Name, e.Name), e.Desc)
+	if e.Bitfield {
+		g.printf("//\n// It is a bitfield.\n")
+	}
+	g.since(e.Since)
+	g.printf("type %s uint32\n\n", name)
+
+	g.printf("const (\n")
+	for _, v := range e.Entries {
+		if v.Summary != "" {
+			g.printf("// %s\n", sentence(v.Summary))
+		}
+		if v.Since > 1 {
+			if v.Summary != "" {
+				g.printf("//\n")
+			}
+			g.printf("// Since version %d.\n", v.Since)
+		}
+		g.printf("%s%s %s = %s\n", name, camel(v.Name), name, v.Value)
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) message(i iface, m message, name, kind string) {
+	g.doc(name, fmt.Sprintf("the %s.%s %s", i.Name, m.Name, kind), m.Desc)
+	if m.Type == "destructor" {
+		g.printf("//\n// It destroys the object.\n")
+	}
+	g.since(m.Since)
+
+	if len(m.Args) == 0 {
+		g.printf("type %s struct{}\n\n", name)
+	} else {
+		g.printf("type %s struct {\n", name)
+		for _, a := range m.Args {
+			g.arg(i, a)
+		}
+		g.printf("}\n\n")
+	}
+
+	since := m.Since
+	if since == 0 {
+		since = 1
+	}
+	g.printf("// Since returns the first version of %s with the %s.\n", i.Name, kind)
+	g.printf("func (%s) Since() uint32 { return %d }\n\n", name, since)
+
+	if m.Type == "destructor" {
+		g.printf("func (%s) destroys() {}\n\n", name)
+	}
+}
+
+func (g *generator) arg(i iface, a arg) {
+	name := camel(a.Name)
+	comment := ""
+	if a.Summary != "" {
+		comment = " // " + strings.Join(strings.Fields(a.Summary), " ")
+	}
+
+	switch a.Type {
+	case "new_id":
+		if a.Interface == "" {
+			// An object of any interface, such as the one bound by
+			// wl_registry.bind, sent with its interface and version.
+			g.printf("%s NewId%s\n", name, comment)
+			break
+		}
+		// Tagged for tracing, to tell them from other objects.
+		g.printf("%s ObjectId `wayland:\"new_id\"`%s\n", name, comment)
+	case "object":
+		// Null objects are the id 0.
+		g.printf("%s ObjectId%s\n", name, comment)
+	case "int", "uint":
+		typ := map[string]string{"int": "int32", "uint": "uint32"}[a.Type]
+		if a.Enum != "" {
+			qualified := a.Enum
+			if !strings.Contains(qualified, ".") {
+				qualified = i.Name + "." + qualified
+			}
+			// Enums of protocols that were not given stay numbers.
+			if t, ok := g.enums[qualified]; ok {
+				typ = t
+			}
+		}
+		g.printf("%s %s%s\n", name, typ, comment)
+	case "fixed":
+		g.printf("%s Fixed%s\n", name, comment)
+	case "string":
+		if a.AllowNull {
+			g.printf("%s NullString%s\n", name, comment)
+			break
+		}
+		g.printf("%s string%s\n", name, comment)
+	case "array":
+		g.printf("%s []byte%s\n", name, comment)
+	case "fd":
+		g.printf("%s Fd%s\n", name, comment)
+	default:
+		log.Fatalf("%s: argument %s of unknown type %q", i.Name, a.Name, a.Type)
+	}
+}
+
+// doc writes the doc comment of a declaration from a description.
+func (g *generator) doc(name, what string, d description) {
+	if d.Summary != "" {
+		g.printf("// %s is %s: %s\n", name, what, sentence(d.Summary))
+	} else {
+		g.printf("// %s is %s.\n", name, what)
+	}
+
+	for _, paragraph := range paragraphs(d.Text) {
+		g.printf("//\n")
+		for _, line := range paragraph {
+			g.printf("// %s\n", line)
+		}
+	}
+}
+
+func (g *generator) since(version int) {
+	if version > 1 {
+		g.printf("//\n// Since version %d.\n", version)
+	}
+}
+
+// paragraphs splits a description in paragraphs of lines, without the
+// indentation of the XML file.
+func paragraphs(text string) (ps [][]string) {
+	var p []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if p != nil {
+				ps = append(ps, p)
+				p = nil
+			}
+			continue
+		}
+		p = append(p, line)
+	}
+	if p != nil {
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// sentence returns a summary as a sentence ending with a period.
+func sentence(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if !strings.HasSuffix(s, ".") {
+		s += "."
+	}
+	return s
+}
+
+// typeName returns the Go name of a message or enum of an interface.
+func typeName(iface, name string) string {
+	return camel(trimPrefix(iface)) + camel(name)
+}
+
+// trimPrefix removes the wl_ prefix of core interfaces. The prefixes of
+// other protocols, such as xdg_, are kept to avoid clashes.
+func trimPrefix(name string) string {
+	return strings.TrimPrefix(name, "wl_")
+}
+
+// camel converts a snake case name to camel case, such as get_registry to
+// GetRegistry.
+func camel(name string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]))
+		sb.WriteString(part[1:])
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerated checks that the bindings of the wayland package are up to
+// date with the protocol files and the scanner.
+func TestGenerated(t *testing.T) {
+	for _, tc := range []struct {
+		output     string
+		interfaces []string
+		protocols  []string
+	}{
+		{"protocol_wayland.go", []string{
+			"wl_display", "wl_registry", "wl_callback", "wl_compositor", "wl_shm_pool",
+			"wl_shm", "wl_buffer", "wl_surface", "wl_region",
+		}, []string{"wayland.xml"}},
+		{"protocol_xdg_shell.go", nil, []string{"xdg-shell.xml", "wayland.xml"}},
+	} {
+		var protocols []*protocol
+		for _, name := range tc.protocols {
+			p, err := parse(filepath.Join("..", "..", "protocol", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			protocols = append(protocols, p)
+		}
+		if tc.interfaces != nil {
+			err := selectInterfaces(protocols[0], tc.interfaces)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := newGenerator(protocols).generate("wayland", tc.protocols[0], protocols[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(filepath.Join("..", "..", tc.output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate", tc.output)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	p := &protocol{
+		Name: "test",
+		Interfaces: []iface{{
+			Name:    "wl_thing",
+			Version: 3,
+			Requests: []message{
+				{Name: "set_state", Args: []arg{{Name: "state", Type: "uint", Enum: "state"}}},
+				{Name: "bind", Args: []arg{{Name: "id", Type: "new_id"}}},
+				{Name: "set_title", Args: []arg{{Name: "title", Type: "string", AllowNull: true}}},
+				{Name: "move", Args: []arg{{Name: "x", Type: "fixed"}}},
+				{Name: "done", Since: 2},
+			},
+			Events: []message{
+				{Name: "state", Args: []arg{{Name: "state", Type: "uint", Enum: "wl_other.mode"}}},
+				{Name: "done"},
+			},
+			Enums: []enum{{Name: "state", Entries: []entry{{Name: "tiled_left", Value: "0x5"}}}},
+		}},
+	}
+
+	src, err := newGenerator([]*protocol{p}).generate("test", "test.xml", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ThingInterface = \"wl_thing\"",
+		"ThingVersion   = 3",
+		"OpThingSetState Opcode = 0",
+		"OpThingStateEvent Opcode = 0",
+		"OpThingDoneEvent  Opcode = 1",
+		"ThingStateTiledLeft ThingState = 0x5",
+		"State ThingState",
+		"Id NewId",
+		"Title NullString",
+		`requestNames[ThingInterface] = []string{"set_state", "bind", "set_title", "move", "done"}`,
+		`eventNames[ThingInterface] = []string{"state", "done"}`,
+		"X Fixed",
+		"func (ThingDone) Since() uint32 { return 2 }",
+		// Enums of other protocols are numbers.
+		"State uint32",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code lacks %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestSelectInterfaces(t *testing.T) {
+	p := &protocol{Name: "test", Interfaces: []iface{{Name: "wl_a"}, {Name: "wl_b"}, {Name: "wl_c"}}}
+
+	err := selectInterfaces(p, []string{"wl_c", "wl_a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Interfaces) != 2 || p.Interfaces[0].Name != "wl_a" || p.Interfaces[1].Name != "wl_c" {
+		t.Errorf("selected %v", p.Interfaces)
+	}
+
+	if selectInterfaces(p, []string{"wl_d"}) == nil {
+		t.Error("selected a missing interface")
+	}
+}
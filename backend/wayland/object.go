@@ -0,0 +1,170 @@
+package wayland
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+)
+
+// eventTypes are the events of each interface, by opcode, as registered by
+// the generated code.
+var eventTypes = make(map[string][]interface{})
+
+// destructor is implemented by the requests and events that destroy their
+// object.
+type destructor interface {
+	destroys()
+}
+
+// Proxy is the client side of an object: its interface, the version it was
+// created with, and the handlers of its events.
+type Proxy struct {
+	Id        ObjectId
+	Interface string
+	Version   uint32
+
+	b        *Backend
+	handlers map[Opcode]reflect.Value
+
+	// destroyed is set once the object is destroyed. Its id is reused once
+	// the compositor acknowledges it with wl_display.delete_id, and the
+	// events it sends until then are ignored.
+	destroyed bool
+}
+
+// NewProxy returns the proxy of a new object, which is created by passing
+// its id to a request such as wl_compositor.create_surface. Objects created
+// by other objects have their version.
+func (b *Backend) NewProxy(iface string, version uint32) *Proxy {
+	p := &Proxy{
+		Id:        b.NewObjectId(),
+		Interface: iface,
+		Version:   version,
+		b:         b,
+		handlers:  make(map[Opcode]reflect.Value),
+	}
+	b.objects[p.Id] = p
+	return p
+}
+
+// Object returns the proxy of an object, or nil if there is none.
+func (b *Backend) Object(id ObjectId) *Proxy {
+	return b.objects[id]
+}
+
+// NewObjectId returns an unused object id, reusing those of deleted objects
+// first.
+func (b *Backend) NewObjectId() ObjectId {
+	if n := len(b.free); n > 0 {
+		id := b.free[n-1]
+		b.free = b.free[:n-1]
+		return id
+	}
+	b.PrevObjectId++
+	return ObjectId(b.PrevObjectId)
+}
+
+// Handle sets the handler of an event of the object, a function taking the
+// event struct, such as func(XdgWmBasePing) for OpXdgWmBasePing. It panics if
+// the handler does not match the event. Events without handler are returned
+// by NextEvent.
+func (p *Proxy) Handle(opcode Opcode, handler interface{}) {
+	types := eventTypes[p.Interface]
+	if int(opcode) >= len(types) {
+		panic(fmt.Sprintf("%s has no event %d", p.Interface, opcode))
+	}
+
+	h := reflect.ValueOf(handler)
+	event := reflect.TypeOf(types[opcode])
+	t := h.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.In(0) != event || t.NumOut() != 0 {
+		panic(fmt.Sprintf("handler of %s event %d must be a func(%s)", p.Interface, opcode, event))
+	}
+	p.handlers[opcode] = h
+}
+
+// Request sends a request to the object. Destructors, such as
+// wl_surface.destroy, destroy the proxy with the object.
+func (p *Proxy) Request(opcode Opcode, data interface{}) error {
+	if p.destroyed {
+		return fmt.Errorf("%v: request %d on a destroyed object", p, opcode)
+	}
+
+	err := p.b.request(p.Id, opcode, data)
+	if err != nil {
+		return err
+	}
+	if _, ok := data.(destructor); ok {
+		p.destroyed = true
+	}
+	return nil
+}
+
+func (p *Proxy) String() string {
+	return fmt.Sprintf("%s@%d", p.Interface, p.Id)
+}
+
+// dispatchMessage calls the handler of an event. Events without handler
+// are decoded and kept for NextEvent. Those of unknown objects or of
+// interfaces without bindings are kept as they are: without their
+// signature, the fds passed with them cannot be told from those of later
+// events, so they must carry none, as is the case of the objects the
+// compositor creates with events, such as wl_data_offer.
+func (b *Backend) dispatchMessage(msg Message) error {
+	p := b.objects[msg.ObjectId]
+	var types []interface{}
+	if p != nil {
+		types = eventTypes[p.Interface]
+	}
+	if types == nil {
+		if b.Debug != nil {
+			b.traceEvent(msg.ObjectId, msg.Opcode, msg)
+		}
+		b.pending = append(b.pending, msg)
+		return nil
+	}
+	if int(msg.Opcode) >= len(types) {
+		return b.disconnect(fmt.Errorf("%v: unknown event %d", p, msg.Opcode))
+	}
+
+	// Every event is decoded, even if ignored, so that the fds passed with
+	// it are not taken for those of later events.
+	// Decoded events do not refer to the payload, which is reused.
+	ev := reflect.New(reflect.TypeOf(types[msg.Opcode])).Elem()
+	err := msg.Unmarshall(ev.Addr().Interface())
+	msg.release()
+	if err != nil {
+		return b.disconnect(fmt.Errorf("%v: %w", p, err))
+	}
+	if b.Debug != nil {
+		b.traceEvent(msg.ObjectId, msg.Opcode, ev.Interface())
+	}
+
+	destroyed := p.destroyed
+	if _, ok := ev.Interface().(destructor); ok {
+		p.destroyed = true
+	}
+	if destroyed {
+		for _, fd := range msg.Fds {
+			syscall.Close(fd)
+		}
+		return nil
+	}
+	h, ok := p.handlers[msg.Opcode]
+	if !ok {
+		b.pending = append(b.pending, ev.Interface())
+		return nil
+	}
+	h.Call([]reflect.Value{ev})
+	return nil
+}
+
+// deleteId frees the id of a destroyed object for reuse.
+func (b *Backend) deleteId(ev DisplayDeleteId) {
+	id := ObjectId(ev.Id)
+	if _, ok := b.objects[id]; !ok {
+		return
+	}
+	delete(b.objects, id)
+	b.free = append(b.free, id)
+}
@@ -0,0 +1,159 @@
+package wayland
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+
+	surface := b.NewProxy(SurfaceInterface, 4)
+	var entered ObjectId
+	surface.Handle(OpSurfaceEnter, func(ev SurfaceEnter) {
+		entered = ev.Output
+	})
+
+	c.send(surface.Id, OpSurfaceEnter, SurfaceEnter{Output: 7})
+	c.send(surface.Id, OpSurfaceLeave, SurfaceLeave{Output: 7})
+	// Both events arrived together, and are dispatched at once.
+	err := b.Dispatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entered != 7 {
+		t.Errorf("entered output %d, want 7", entered)
+	}
+	// Events without handler are decoded and kept for NextEvent.
+	if len(b.pending) != 1 || b.pending[0] != (SurfaceLeave{Output: 7}) {
+		t.Errorf("pending events %v", b.pending)
+	}
+
+	// Events sent before the compositor knows about the destruction are
+	// ignored, and the id is reused once it is deleted.
+	err = surface.Request(OpSurfaceDestroy, SurfaceDestroy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.expect(surface.Id, OpSurfaceDestroy, nil)
+	c.send(surface.Id, OpSurfaceEnter, SurfaceEnter{Output: 8})
+	c.send(DisplayId, OpDisplayDeleteId, DisplayDeleteId{Id: uint32(surface.Id)})
+	err = b.Dispatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entered != 7 || len(b.pending) != 1 {
+		t.Errorf("dispatched event of destroyed object")
+	}
+	if surface.Request(OpSurfaceCommit, SurfaceCommit{}) == nil {
+		t.Errorf("sent request to destroyed object")
+	}
+	if b.Object(surface.Id) != nil {
+		t.Errorf("deleted object still registered")
+	}
+	if id := b.NewObjectId(); id != surface.Id {
+		t.Errorf("allocated id %d, want deleted %d", id, surface.Id)
+	}
+}
+
+func TestDispatchFds(t *testing.T) {
+	eventTypes["test_fds"] = []interface{}{testFdEvent{}, testFdEvent{}}
+	defer delete(eventTypes, "test_fds")
+
+	for _, debug := range []bool{false, true} {
+		b, c := newFakeCompositor(t)
+		defer b.Close()
+		if debug {
+			b.Debug = io.Discard
+		}
+
+		f1, err := os.CreateTemp(t.TempDir(), "kept")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f1.Close()
+		f2, err := os.CreateTemp(t.TempDir(), "handled")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f2.Close()
+
+		// The fd of the event kept for NextEvent must not be taken for that
+		// of the handled one.
+		p := b.NewProxy("test_fds", 1)
+		var handled testFdEvent
+		p.Handle(1, func(ev testFdEvent) {
+			handled = ev
+		})
+		c.send(p.Id, 0, testFdEvent{Serial: 1, Fd: Fd(f1.Fd())})
+		c.send(p.Id, 1, testFdEvent{Serial: 2, Fd: Fd(f2.Fd())})
+		for handled.Serial == 0 {
+			err = b.Dispatch()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if len(b.pending) != 1 {
+			t.Fatalf("debug %v: pending events %v", debug, b.pending)
+		}
+		kept := b.pending[0].(testFdEvent)
+		if !sameFile(t, int(kept.Fd), f1) || !sameFile(t, int(handled.Fd), f2) {
+			t.Errorf("debug %v: fds of events swapped", debug)
+		}
+		syscall.Close(int(kept.Fd))
+		syscall.Close(int(handled.Fd))
+	}
+}
+
+// sameFile returns whether fd refers to f.
+func sameFile(t *testing.T, fd int, f *os.File) bool {
+	t.Helper()
+	var st1, st2 syscall.Stat_t
+	err := syscall.Fstat(fd, &st1)
+	if err == nil {
+		err = syscall.Fstat(int(f.Fd()), &st2)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st1.Dev == st2.Dev && st1.Ino == st2.Ino
+}
+
+func TestRoundtrip(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+
+	// The callback is the next object.
+	callback := ObjectId(11)
+	c.send(b.ShmId, OpShmFormatEvent, ShmFormatEvent{Format: ShmFormatXrgb8888})
+	c.send(callback, OpCallbackDone, CallbackDone{})
+
+	err := b.Roundtrip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sync DisplaySync
+	c.expect(DisplayId, OpDisplaySync, &sync)
+	if sync.Callback != callback {
+		t.Errorf("sync with callback %d, want %d", sync.Callback, callback)
+	}
+	if formats := b.ShmFormats(); len(formats) != 1 || formats[0] != ShmFormatXrgb8888 {
+		t.Errorf("shm formats %v", formats)
+	}
+}
+
+func TestHandleMismatch(t *testing.T) {
+	b, _ := newFakeCompositor(t)
+	defer b.Close()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("handler of the wrong event accepted")
+		}
+	}()
+	b.NewProxy(SurfaceInterface, 1).Handle(OpSurfaceEnter, func(SurfaceLeave) {})
+}
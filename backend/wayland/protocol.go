@@ -1,61 +1,9 @@
 package wayland
 
-const DisplayId ObjectId = 1
-
-const (
-	OpDisplaySync        Opcode = 0
-	OpDisplayGetRegistry        = 1
-
-	OpDisplayError = 0
-
-	OpRegistryBind         = 0
-	OpRegistryGlobal       = 0
-	OpRegistryGlobalRemove = 1
+//go:generate go run ./internal/scanner -o protocol_wayland.go -interfaces wl_display,wl_registry,wl_callback,wl_compositor,wl_shm_pool,wl_shm,wl_buffer,wl_surface,wl_region protocol/wayland.xml
+//go:generate go run ./internal/scanner -o protocol_xdg_shell.go protocol/xdg-shell.xml protocol/wayland.xml
 
-	OpCallbackDone = 0
-
-	OpCompositorCreateSurface = 0
-	OpCompositonCreateRegion  = 1
-)
+const DisplayId ObjectId = 1
 
 type ObjectId uint32
 type Opcode uint16
-
-type DisplaySync struct {
-	Callback ObjectId
-}
-
-type DisplayGetRegistry struct {
-	Registry ObjectId
-}
-
-type DisplayError struct {
-	ObjectId ObjectId
-	Code     uint32
-	Message  string
-}
-
-type RegistryBind struct {
-	Name      uint32
-	Interface string
-	Version   uint32
-	Id        ObjectId
-}
-
-type RegistryGlobal struct {
-	Name      uint32
-	Interface string
-	Version   uint32
-}
-
-type CallbackDone struct {
-	CallbackData uint32
-}
-
-type CompositorCreateSurface struct {
-	Id ObjectId
-}
-
-type CompositorCreateRegion struct {
-	Id ObjectId
-}
@@ -0,0 +1,801 @@
+// Code generated by scanner from wayland.xml; DO NOT EDIT.
+
+package wayland
+
+// Names of the interfaces of the wayland protocol, and the versions described.
+const (
+	DisplayInterface    = "wl_display"
+	DisplayVersion      = 1
+	RegistryInterface   = "wl_registry"
+	RegistryVersion     = 1
+	CallbackInterface   = "wl_callback"
+	CallbackVersion     = 1
+	CompositorInterface = "wl_compositor"
+	CompositorVersion   = 6
+	ShmPoolInterface    = "wl_shm_pool"
+	ShmPoolVersion      = 2
+	ShmInterface        = "wl_shm"
+	ShmVersion          = 2
+	BufferInterface     = "wl_buffer"
+	BufferVersion       = 1
+	SurfaceInterface    = "wl_surface"
+	SurfaceVersion      = 6
+	RegionInterface     = "wl_region"
+	RegionVersion       = 1
+)
+
+// Opcodes of the requests of wl_display.
+const (
+	OpDisplaySync        Opcode = 0
+	OpDisplayGetRegistry Opcode = 1
+)
+
+// Opcodes of the events of wl_display.
+const (
+	OpDisplayErrorEvent Opcode = 0
+	OpDisplayDeleteId   Opcode = 1
+)
+
+// DisplayError is the wl_display.error enum: global error values.
+//
+// These errors are global and can be emitted in response to any
+// server request.
+type DisplayError uint32
+
+const (
+	// server couldn't find object.
+	DisplayErrorInvalidObject DisplayError = 0
+	// method doesn't exist on the specified interface or malformed request.
+	DisplayErrorInvalidMethod DisplayError = 1
+	// server is out of memory.
+	DisplayErrorNoMemory DisplayError = 2
+	// implementation error in compositor.
+	DisplayErrorImplementation DisplayError = 3
+)
+
+// DisplaySync is the wl_display.sync request: asynchronous roundtrip.
+//
+// The sync request asks the server to emit the 'done' event
+// on the returned wl_callback object. Since requests are
+// handled in-order and events are delivered in-order, this can
+// be used as a barrier to ensure all previous requests and the
+// resulting events have been handled.
+type DisplaySync struct {
+	Callback ObjectId `wayland:"new_id"` // callback object for the sync request
+}
+
+// Since returns the first version of wl_display with the request.
+func (DisplaySync) Since() uint32 { return 1 }
+
+// DisplayGetRegistry is the wl_display.get_registry request: get global registry object.
+//
+// This request creates a registry object that allows the client
+// to list and bind the global objects available from the
+// compositor.
+type DisplayGetRegistry struct {
+	Registry ObjectId `wayland:"new_id"` // global registry object
+}
+
+// Since returns the first version of wl_display with the request.
+func (DisplayGetRegistry) Since() uint32 { return 1 }
+
+// DisplayErrorEvent is the wl_display.error event: fatal error event.
+//
+// The error event is sent out when a fatal (non-recoverable)
+// error has occurred. The object_id argument is the object
+// where the error occurred, most often in response to a request
+// to that object. The code identifies the error and is defined
+// by the object interface.
+type DisplayErrorEvent struct {
+	ObjectId ObjectId // object where the error occurred
+	Code     uint32   // error code
+	Message  string   // error description
+}
+
+// Since returns the first version of wl_display with the event.
+func (DisplayErrorEvent) Since() uint32 { return 1 }
+
+// DisplayDeleteId is the wl_display.delete_id event: acknowledge object ID deletion.
+//
+// This event is used internally by the object ID management
+// logic. When a client deletes an object that it had created,
+// the server will send this event to acknowledge that it has
+// seen the delete request. When the client receives this event,
+// it will know that it can safely reuse the object ID.
+type DisplayDeleteId struct {
+	Id uint32 // deleted object ID
+}
+
+// Since returns the first version of wl_display with the event.
+func (DisplayDeleteId) Since() uint32 { return 1 }
+
+// Opcodes of the requests of wl_registry.
+const (
+	OpRegistryBind Opcode = 0
+)
+
+// Opcodes of the events of wl_registry.
+const (
+	OpRegistryGlobal       Opcode = 0
+	OpRegistryGlobalRemove Opcode = 1
+)
+
+// RegistryBind is the wl_registry.bind request: bind an object to the display.
+//
+// Binds a new, client-created object to the server using the
+// specified name as the identifier.
+type RegistryBind struct {
+	Name uint32 // unique numeric name of the object
+	Id   NewId  // bounded object
+}
+
+// Since returns the first version of wl_registry with the request.
+func (RegistryBind) Since() uint32 { return 1 }
+
+// RegistryGlobal is the wl_registry.global event: announce global object.
+//
+// Notify the client of global objects.
+//
+// The event notifies the client that a global object with
+// the given name is now available, and it implements the
+// given version of the given interface.
+type RegistryGlobal struct {
+	Name      uint32 // numeric name of the global object
+	Interface string // interface implemented by the object
+	Version   uint32 // interface version
+}
+
+// Since returns the first version of wl_registry with the event.
+func (RegistryGlobal) Since() uint32 { return 1 }
+
+// RegistryGlobalRemove is the wl_registry.global_remove event: announce removal of global object.
+//
+// Notify the client of removed global objects.
+//
+// This event notifies the client that the global identified
+// by name is no longer available. If the client bound to
+// the global using the bind request, the client should now
+// destroy that object.
+type RegistryGlobalRemove struct {
+	Name uint32 // numeric name of the global object
+}
+
+// Since returns the first version of wl_registry with the event.
+func (RegistryGlobalRemove) Since() uint32 { return 1 }
+
+// Opcodes of the events of wl_callback.
+const (
+	OpCallbackDone Opcode = 0
+)
+
+// CallbackDone is the wl_callback.done event: done event.
+//
+// Notify the client when the related request is done.
+//
+// It destroys the object.
+type CallbackDone struct {
+	CallbackData uint32 // request-specific data for the callback
+}
+
+// Since returns the first version of wl_callback with the event.
+func (CallbackDone) Since() uint32 { return 1 }
+
+func (CallbackDone) destroys() {}
+
+// Opcodes of the requests of wl_compositor.
+const (
+	OpCompositorCreateSurface Opcode = 0
+	OpCompositorCreateRegion  Opcode = 1
+)
+
+// CompositorCreateSurface is the wl_compositor.create_surface request: create new surface.
+//
+// Ask the compositor to create a new surface.
+type CompositorCreateSurface struct {
+	Id ObjectId `wayland:"new_id"` // the new surface
+}
+
+// Since returns the first version of wl_compositor with the request.
+func (CompositorCreateSurface) Since() uint32 { return 1 }
+
+// CompositorCreateRegion is the wl_compositor.create_region request: create new region.
+//
+// Ask the compositor to create a new region.
+type CompositorCreateRegion struct {
+	Id ObjectId `wayland:"new_id"` // the new region
+}
+
+// Since returns the first version of wl_compositor with the request.
+func (CompositorCreateRegion) Since() uint32 { return 1 }
+
+// Opcodes of the requests of wl_shm_pool.
+const (
+	OpShmPoolCreateBuffer Opcode = 0
+	OpShmPoolDestroy      Opcode = 1
+	OpShmPoolResize       Opcode = 2
+)
+
+// ShmPoolCreateBuffer is the wl_shm_pool.create_buffer request: create a buffer from the pool.
+//
+// Create a wl_buffer object from the pool.
+//
+// The buffer is created offset bytes into the pool and has
+// width and height as specified. The stride argument specifies
+// the number of bytes from the beginning of one row to the beginning
+// of the next. The format is the pixel format of the buffer and
+// must be one of those advertised through the wl_shm.format event.
+type ShmPoolCreateBuffer struct {
+	Id     ObjectId  `wayland:"new_id"` // buffer to create
+	Offset int32     // buffer byte offset within the pool
+	Width  int32     // buffer width, in pixels
+	Height int32     // buffer height, in pixels
+	Stride int32     // number of bytes from the beginning of one row to the beginning of the next row
+	Format ShmFormat // buffer pixel format
+}
+
+// Since returns the first version of wl_shm_pool with the request.
+func (ShmPoolCreateBuffer) Since() uint32 { return 1 }
+
+// ShmPoolDestroy is the wl_shm_pool.destroy request: destroy the pool.
+//
+// Destroy the shared memory pool.
+//
+// The mmapped memory will be released when all
+// buffers that have been created from this pool
+// are gone.
+//
+// It destroys the object.
+type ShmPoolDestroy struct{}
+
+// Since returns the first version of wl_shm_pool with the request.
+func (ShmPoolDestroy) Since() uint32 { return 1 }
+
+func (ShmPoolDestroy) destroys() {}
+
+// ShmPoolResize is the wl_shm_pool.resize request: change the size of the pool mapping.
+//
+// This request will cause the server to remap the backing memory
+// for the pool from the file descriptor passed when the pool was
+// created, but using the new size. This request can only be
+// used to make the pool bigger.
+type ShmPoolResize struct {
+	Size int32 // new size of the pool, in bytes
+}
+
+// Since returns the first version of wl_shm_pool with the request.
+func (ShmPoolResize) Since() uint32 { return 1 }
+
+// Opcodes of the requests of wl_shm.
+const (
+	OpShmCreatePool Opcode = 0
+	OpShmRelease    Opcode = 1
+)
+
+// Opcodes of the events of wl_shm.
+const (
+	OpShmFormatEvent Opcode = 0
+)
+
+// ShmError is the wl_shm.error enum: wl_shm error values.
+//
+// These errors can be emitted in response to wl_shm requests.
+type ShmError uint32
+
+const (
+	// buffer format is not known.
+	ShmErrorInvalidFormat ShmError = 0
+	// invalid size or stride during pool or buffer creation.
+	ShmErrorInvalidStride ShmError = 1
+	// mmapping the file descriptor failed.
+	ShmErrorInvalidFd ShmError = 2
+)
+
+// ShmFormat is the wl_shm.format enum: pixel formats.
+//
+// This describes the memory layout of an individual pixel.
+//
+// All renderers should support argb8888 and xrgb8888 but any other
+// formats are optional and may not be supported by the particular
+// renderer in use.
+//
+// The drm format codes match the macros defined in drm_fourcc.h, except
+// argb8888 and xrgb8888. The formats actually supported by the compositor
+// will be reported by the format event.
+type ShmFormat uint32
+
+const (
+	// 32-bit ARGB format, [31:0] A:R:G:B 8:8:8:8 little endian.
+	ShmFormatArgb8888 ShmFormat = 0
+	// 32-bit RGB format, [31:0] x:R:G:B 8:8:8:8 little endian.
+	ShmFormatXrgb8888 ShmFormat = 1
+	// 8-bit color index format, [7:0] C.
+	ShmFormatC8 ShmFormat = 0x20203843
+	// 8-bit RGB format, [7:0] R:G:B 3:3:2.
+	ShmFormatRgb332 ShmFormat = 0x38424752
+	// 8-bit BGR format, [7:0] B:G:R 2:3:3.
+	ShmFormatBgr233 ShmFormat = 0x38524742
+	// 16-bit xRGB format, [15:0] x:R:G:B 4:4:4:4 little endian.
+	ShmFormatXrgb4444 ShmFormat = 0x32315258
+	// 16-bit ARGB format, [15:0] A:R:G:B 4:4:4:4 little endian.
+	ShmFormatArgb4444 ShmFormat = 0x32315241
+	// 16-bit xRGB format, [15:0] x:R:G:B 1:5:5:5 little endian.
+	ShmFormatXrgb1555 ShmFormat = 0x35315258
+	// 16-bit ARGB format, [15:0] A:R:G:B 1:5:5:5 little endian.
+	ShmFormatArgb1555 ShmFormat = 0x35315241
+	// 16-bit RGB format, [15:0] R:G:B 5:6:5 little endian.
+	ShmFormatRgb565 ShmFormat = 0x36314752
+	// 16-bit BGR format, [15:0] B:G:R 5:6:5 little endian.
+	ShmFormatBgr565 ShmFormat = 0x36314742
+	// 24-bit RGB format, [23:0] R:G:B little endian.
+	ShmFormatRgb888 ShmFormat = 0x34324752
+	// 24-bit BGR format, [23:0] B:G:R little endian.
+	ShmFormatBgr888 ShmFormat = 0x34324742
+	// 32-bit xBGR format, [31:0] x:B:G:R 8:8:8:8 little endian.
+	ShmFormatXbgr8888 ShmFormat = 0x34324258
+	// 32-bit RGBx format, [31:0] R:G:B:x 8:8:8:8 little endian.
+	ShmFormatRgbx8888 ShmFormat = 0x34325852
+	// 32-bit BGRx format, [31:0] B:G:R:x 8:8:8:8 little endian.
+	ShmFormatBgrx8888 ShmFormat = 0x34325842
+	// 32-bit ABGR format, [31:0] A:B:G:R 8:8:8:8 little endian.
+	ShmFormatAbgr8888 ShmFormat = 0x34324241
+	// 32-bit RGBA format, [31:0] R:G:B:A 8:8:8:8 little endian.
+	ShmFormatRgba8888 ShmFormat = 0x34324152
+	// 32-bit BGRA format, [31:0] B:G:R:A 8:8:8:8 little endian.
+	ShmFormatBgra8888 ShmFormat = 0x34324142
+	// 32-bit xRGB format, [31:0] x:R:G:B 2:10:10:10 little endian.
+	ShmFormatXrgb2101010 ShmFormat = 0x30335258
+	// 32-bit xBGR format, [31:0] x:B:G:R 2:10:10:10 little endian.
+	ShmFormatXbgr2101010 ShmFormat = 0x30334258
+	// 32-bit ARGB format, [31:0] A:R:G:B 2:10:10:10 little endian.
+	ShmFormatArgb2101010 ShmFormat = 0x30335241
+	// 32-bit ABGR format, [31:0] A:B:G:R 2:10:10:10 little endian.
+	ShmFormatAbgr2101010 ShmFormat = 0x30334241
+	// packed YCbCr format, [31:0] Cr0:Y1:Cb0:Y0 8:8:8:8 little endian.
+	ShmFormatYuyv ShmFormat = 0x56595559
+	// 2 plane YCbCr Cr:Cb format, 2x2 subsampled Cr:Cb plane.
+	ShmFormatNv12 ShmFormat = 0x3231564e
+	// [63:0] x:R:G:B 16:16:16:16 little endian.
+	ShmFormatXrgb16161616f ShmFormat = 0x48345258
+	// [63:0] A:B:G:R 16:16:16:16 little endian.
+	ShmFormatAbgr16161616f ShmFormat = 0x48344241
+)
+
+// ShmCreatePool is the wl_shm.create_pool request: create a shm pool.
+//
+// Create a new wl_shm_pool object.
+//
+// The pool can be used to create shared memory based buffer
+// objects. The server will mmap size bytes of the passed file
+// descriptor, to use as backing memory for the pool.
+type ShmCreatePool struct {
+	Id   ObjectId `wayland:"new_id"` // pool to create
+	Fd   Fd       // file descriptor for the pool
+	Size int32    // pool size, in bytes
+}
+
+// Since returns the first version of wl_shm with the request.
+func (ShmCreatePool) Since() uint32 { return 1 }
+
+// ShmRelease is the wl_shm.release request: release the shm object.
+//
+// Using this request a client can tell the server that it is not going to
+// use the shm object anymore.
+//
+// Objects created via this interface remain unaffected.
+//
+// It destroys the object.
+//
+// Since version 2.
+type ShmRelease struct{}
+
+// Since returns the first version of wl_shm with the request.
+func (ShmRelease) Since() uint32 { return 2 }
+
+func (ShmRelease) destroys() {}
+
+// ShmFormatEvent is the wl_shm.format event: pixel format description.
+//
+// Informs the client about a valid pixel format that
+// can be used for buffers. Known formats include
+// argb8888 and xrgb8888.
+type ShmFormatEvent struct {
+	Format ShmFormat // buffer pixel format
+}
+
+// Since returns the first version of wl_shm with the event.
+func (ShmFormatEvent) Since() uint32 { return 1 }
+
+// Opcodes of the requests of wl_buffer.
+const (
+	OpBufferDestroy Opcode = 0
+)
+
+// Opcodes of the events of wl_buffer.
+const (
+	OpBufferRelease Opcode = 0
+)
+
+// BufferDestroy is the wl_buffer.destroy request: destroy a buffer.
+//
+// Destroy a buffer. If and how you need to release the backing
+// storage is defined by the buffer factory interface.
+//
+// It destroys the object.
+type BufferDestroy struct{}
+
+// Since returns the first version of wl_buffer with the request.
+func (BufferDestroy) Since() uint32 { return 1 }
+
+func (BufferDestroy) destroys() {}
+
+// BufferRelease is the wl_buffer.release event: compositor releases buffer.
+//
+// Sent when this wl_buffer is no longer used by the compositor.
+// The client is now free to reuse or destroy this buffer and its
+// backing storage.
+type BufferRelease struct{}
+
+// Since returns the first version of wl_buffer with the event.
+func (BufferRelease) Since() uint32 { return 1 }
+
+// Opcodes of the requests of wl_surface.
+const (
+	OpSurfaceDestroy            Opcode = 0
+	OpSurfaceAttach             Opcode = 1
+	OpSurfaceDamage             Opcode = 2
+	OpSurfaceFrame              Opcode = 3
+	OpSurfaceSetOpaqueRegion    Opcode = 4
+	OpSurfaceSetInputRegion     Opcode = 5
+	OpSurfaceCommit             Opcode = 6
+	OpSurfaceSetBufferTransform Opcode = 7
+	OpSurfaceSetBufferScale     Opcode = 8
+	OpSurfaceDamageBuffer       Opcode = 9
+	OpSurfaceOffset             Opcode = 10
+)
+
+// Opcodes of the events of wl_surface.
+const (
+	OpSurfaceEnter                    Opcode = 0
+	OpSurfaceLeave                    Opcode = 1
+	OpSurfacePreferredBufferScale     Opcode = 2
+	OpSurfacePreferredBufferTransform Opcode = 3
+)
+
+// SurfaceError is the wl_surface.error enum: wl_surface error values.
+//
+// These errors can be emitted in response to wl_surface requests.
+type SurfaceError uint32
+
+const (
+	// buffer scale value is invalid.
+	SurfaceErrorInvalidScale SurfaceError = 0
+	// buffer transform value is invalid.
+	SurfaceErrorInvalidTransform SurfaceError = 1
+	// buffer size is invalid.
+	SurfaceErrorInvalidSize SurfaceError = 2
+	// buffer offset is invalid.
+	SurfaceErrorInvalidOffset SurfaceError = 3
+	// surface was destroyed before its role object.
+	SurfaceErrorDefunctRoleObject SurfaceError = 4
+)
+
+// SurfaceDestroy is the wl_surface.destroy request: delete surface.
+//
+// Deletes the surface and invalidates its object ID.
+//
+// It destroys the object.
+type SurfaceDestroy struct{}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceDestroy) Since() uint32 { return 1 }
+
+func (SurfaceDestroy) destroys() {}
+
+// SurfaceAttach is the wl_surface.attach request: set the surface contents.
+//
+// Set a buffer as the content of this surface.
+//
+// The new size of the surface is calculated based on the buffer
+// size transformed by the inverse buffer_transform and the
+// inverse buffer_scale.
+//
+// Surface contents are double-buffered state, see wl_surface.commit.
+type SurfaceAttach struct {
+	Buffer ObjectId // buffer of surface contents
+	X      int32    // surface-local x coordinate
+	Y      int32    // surface-local y coordinate
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceAttach) Since() uint32 { return 1 }
+
+// SurfaceDamage is the wl_surface.damage request: mark part of the surface damaged.
+//
+// This request is used to describe the regions where the pending
+// buffer is different from the current surface contents, and where
+// the surface therefore needs to be repainted. The compositor
+// ignores the parts of the damage that fall outside of the surface.
+//
+// Damage is double-buffered state, see wl_surface.commit.
+type SurfaceDamage struct {
+	X      int32 // surface-local x coordinate
+	Y      int32 // surface-local y coordinate
+	Width  int32 // width of damage rectangle
+	Height int32 // height of damage rectangle
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceDamage) Since() uint32 { return 1 }
+
+// SurfaceFrame is the wl_surface.frame request: request a frame throttling hint.
+//
+// Request a notification when it is a good time to start drawing a new
+// frame, by creating a frame callback. This is useful for throttling
+// redrawing operations, and driving animations.
+type SurfaceFrame struct {
+	Callback ObjectId `wayland:"new_id"` // callback object for the frame request
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceFrame) Since() uint32 { return 1 }
+
+// SurfaceSetOpaqueRegion is the wl_surface.set_opaque_region request: set opaque region.
+//
+// This request sets the region of the surface that contains
+// opaque content.
+//
+// Opaque region is double-buffered state, see wl_surface.commit.
+type SurfaceSetOpaqueRegion struct {
+	Region ObjectId // opaque region of the surface
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceSetOpaqueRegion) Since() uint32 { return 1 }
+
+// SurfaceSetInputRegion is the wl_surface.set_input_region request: set input region.
+//
+// This request sets the region of the surface that can receive
+// pointer and touch events.
+//
+// Input region is double-buffered state, see wl_surface.commit.
+type SurfaceSetInputRegion struct {
+	Region ObjectId // input region of the surface
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceSetInputRegion) Since() uint32 { return 1 }
+
+// SurfaceCommit is the wl_surface.commit request: commit pending surface state.
+//
+// Surface state (input, opaque, and damage regions, attached buffers,
+// etc.) is double-buffered. Protocol requests modify the pending state,
+// as opposed to the active state in use by the compositor.
+//
+// A commit request atomically creates a content update from the pending
+// state, even if the pending state has not been touched.
+type SurfaceCommit struct{}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceCommit) Since() uint32 { return 1 }
+
+// SurfaceSetBufferTransform is the wl_surface.set_buffer_transform request: sets the buffer transformation.
+//
+// This request sets the transformation that the client has already applied
+// to the content of the buffer.
+//
+// Buffer transform is double-buffered state, see wl_surface.commit.
+//
+// Since version 2.
+type SurfaceSetBufferTransform struct {
+	Transform int32 // transform for interpreting buffer contents
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceSetBufferTransform) Since() uint32 { return 2 }
+
+// SurfaceSetBufferScale is the wl_surface.set_buffer_scale request: sets the buffer scaling factor.
+//
+// This request sets an optional scaling factor on how the compositor
+// interprets the contents of the buffer attached to the window.
+//
+// Buffer scale is double-buffered state, see wl_surface.commit.
+//
+// Since version 3.
+type SurfaceSetBufferScale struct {
+	Scale int32 // scale for interpreting buffer contents
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceSetBufferScale) Since() uint32 { return 3 }
+
+// SurfaceDamageBuffer is the wl_surface.damage_buffer request: mark part of the surface damaged using buffer coordinates.
+//
+// This request is used to describe the regions where the pending
+// buffer is different from the current surface contents, and where
+// the surface therefore needs to be repainted.
+//
+// This request differs from wl_surface.damage in only one way - it
+// takes damage in buffer coordinates instead of surface-local
+// coordinates.
+//
+// Since version 4.
+type SurfaceDamageBuffer struct {
+	X      int32 // buffer-local x coordinate
+	Y      int32 // buffer-local y coordinate
+	Width  int32 // width of damage rectangle
+	Height int32 // height of damage rectangle
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceDamageBuffer) Since() uint32 { return 4 }
+
+// SurfaceOffset is the wl_surface.offset request: set the surface contents offset.
+//
+// The x and y arguments specify the location of the new pending
+// buffer's upper left corner, relative to the current buffer's upper
+// left corner, in surface-local coordinates.
+//
+// Surface location offset is double-buffered state, see
+// wl_surface.commit.
+//
+// Since version 5.
+type SurfaceOffset struct {
+	X int32 // surface-local x coordinate
+	Y int32 // surface-local y coordinate
+}
+
+// Since returns the first version of wl_surface with the request.
+func (SurfaceOffset) Since() uint32 { return 5 }
+
+// SurfaceEnter is the wl_surface.enter event: surface enters an output.
+//
+// This is emitted whenever a surface's creation, movement, or resizing
+// results in some part of it being within the scanout region of an
+// output.
+type SurfaceEnter struct {
+	Output ObjectId // output entered by the surface
+}
+
+// Since returns the first version of wl_surface with the event.
+func (SurfaceEnter) Since() uint32 { return 1 }
+
+// SurfaceLeave is the wl_surface.leave event: surface leaves an output.
+//
+// This is emitted whenever a surface's creation, movement, or resizing
+// results in it no longer having any part of it within the scanout region
+// of an output.
+type SurfaceLeave struct {
+	Output ObjectId // output left by the surface
+}
+
+// Since returns the first version of wl_surface with the event.
+func (SurfaceLeave) Since() uint32 { return 1 }
+
+// SurfacePreferredBufferScale is the wl_surface.preferred_buffer_scale event: preferred buffer scale for the surface.
+//
+// This event indicates the preferred buffer scale for this surface. It is
+// sent whenever the compositor's preference changes.
+//
+// Since version 6.
+type SurfacePreferredBufferScale struct {
+	Factor int32 // preferred scaling factor
+}
+
+// Since returns the first version of wl_surface with the event.
+func (SurfacePreferredBufferScale) Since() uint32 { return 6 }
+
+// SurfacePreferredBufferTransform is the wl_surface.preferred_buffer_transform event: preferred buffer transform for the surface.
+//
+// This event indicates the preferred buffer transform for this surface.
+// It is sent whenever the compositor's preference changes.
+//
+// Since version 6.
+type SurfacePreferredBufferTransform struct {
+	Transform uint32 // preferred transform
+}
+
+// Since returns the first version of wl_surface with the event.
+func (SurfacePreferredBufferTransform) Since() uint32 { return 6 }
+
+// Opcodes of the requests of wl_region.
+const (
+	OpRegionDestroy  Opcode = 0
+	OpRegionAdd      Opcode = 1
+	OpRegionSubtract Opcode = 2
+)
+
+// RegionDestroy is the wl_region.destroy request: destroy region.
+//
+// Destroy the region.  This will invalidate the object ID.
+//
+// It destroys the object.
+type RegionDestroy struct{}
+
+// Since returns the first version of wl_region with the request.
+func (RegionDestroy) Since() uint32 { return 1 }
+
+func (RegionDestroy) destroys() {}
+
+// RegionAdd is the wl_region.add request: add rectangle to region.
+//
+// Add the specified rectangle to the region.
+type RegionAdd struct {
+	X      int32 // region-local x coordinate
+	Y      int32 // region-local y coordinate
+	Width  int32 // rectangle width
+	Height int32 // rectangle height
+}
+
+// Since returns the first version of wl_region with the request.
+func (RegionAdd) Since() uint32 { return 1 }
+
+// RegionSubtract is the wl_region.subtract request: subtract rectangle from region.
+//
+// Subtract the specified rectangle from the region.
+type RegionSubtract struct {
+	X      int32 // region-local x coordinate
+	Y      int32 // region-local y coordinate
+	Width  int32 // rectangle width
+	Height int32 // rectangle height
+}
+
+// Since returns the first version of wl_region with the request.
+func (RegionSubtract) Since() uint32 { return 1 }
+
+func init() {
+	requestNames[DisplayInterface] = []string{"sync", "get_registry"}
+	eventNames[DisplayInterface] = []string{"error", "delete_id"}
+	eventTypes[DisplayInterface] = []interface{}{
+		DisplayErrorEvent{},
+		DisplayDeleteId{},
+	}
+	errorNames[DisplayInterface] = map[uint32]string{
+		0: "invalid_object",
+		1: "invalid_method",
+		2: "no_memory",
+		3: "implementation",
+	}
+	requestNames[RegistryInterface] = []string{"bind"}
+	eventNames[RegistryInterface] = []string{"global", "global_remove"}
+	eventTypes[RegistryInterface] = []interface{}{
+		RegistryGlobal{},
+		RegistryGlobalRemove{},
+	}
+	eventNames[CallbackInterface] = []string{"done"}
+	eventTypes[CallbackInterface] = []interface{}{
+		CallbackDone{},
+	}
+	requestNames[CompositorInterface] = []string{"create_surface", "create_region"}
+	requestNames[ShmPoolInterface] = []string{"create_buffer", "destroy", "resize"}
+	requestNames[ShmInterface] = []string{"create_pool", "release"}
+	eventNames[ShmInterface] = []string{"format"}
+	eventTypes[ShmInterface] = []interface{}{
+		ShmFormatEvent{},
+	}
+	errorNames[ShmInterface] = map[uint32]string{
+		0: "invalid_format",
+		1: "invalid_stride",
+		2: "invalid_fd",
+	}
+	requestNames[BufferInterface] = []string{"destroy"}
+	eventNames[BufferInterface] = []string{"release"}
+	eventTypes[BufferInterface] = []interface{}{
+		BufferRelease{},
+	}
+	requestNames[SurfaceInterface] = []string{"destroy", "attach", "damage", "frame", "set_opaque_region", "set_input_region", "commit", "set_buffer_transform", "set_buffer_scale", "damage_buffer", "offset"}
+	eventNames[SurfaceInterface] = []string{"enter", "leave", "preferred_buffer_scale", "preferred_buffer_transform"}
+	eventTypes[SurfaceInterface] = []interface{}{
+		SurfaceEnter{},
+		SurfaceLeave{},
+		SurfacePreferredBufferScale{},
+		SurfacePreferredBufferTransform{},
+	}
+	errorNames[SurfaceInterface] = map[uint32]string{
+		0: "invalid_scale",
+		1: "invalid_transform",
+		2: "invalid_size",
+		3: "invalid_offset",
+		4: "defunct_role_object",
+	}
+	requestNames[RegionInterface] = []string{"destroy", "add", "subtract"}
+}
@@ -0,0 +1,938 @@
+// Code generated by scanner from xdg-shell.xml; DO NOT EDIT.
+
+package wayland
+
+// Names of the interfaces of the xdg_shell protocol, and the versions described.
+const (
+	XdgWmBaseInterface     = "xdg_wm_base"
+	XdgWmBaseVersion       = 6
+	XdgPositionerInterface = "xdg_positioner"
+	XdgPositionerVersion   = 6
+	XdgSurfaceInterface    = "xdg_surface"
+	XdgSurfaceVersion      = 6
+	XdgToplevelInterface   = "xdg_toplevel"
+	XdgToplevelVersion     = 6
+	XdgPopupInterface      = "xdg_popup"
+	XdgPopupVersion        = 6
+)
+
+// Opcodes of the requests of xdg_wm_base.
+const (
+	OpXdgWmBaseDestroy          Opcode = 0
+	OpXdgWmBaseCreatePositioner Opcode = 1
+	OpXdgWmBaseGetXdgSurface    Opcode = 2
+	OpXdgWmBasePong             Opcode = 3
+)
+
+// Opcodes of the events of xdg_wm_base.
+const (
+	OpXdgWmBasePing Opcode = 0
+)
+
+// XdgWmBaseError is the xdg_wm_base.error enum.
+type XdgWmBaseError uint32
+
+const (
+	// given wl_surface has another role.
+	XdgWmBaseErrorRole XdgWmBaseError = 0
+	// xdg_wm_base was destroyed before children.
+	XdgWmBaseErrorDefunctSurfaces XdgWmBaseError = 1
+	// the client tried to map or destroy a non-topmost popup.
+	XdgWmBaseErrorNotTheTopmostPopup XdgWmBaseError = 2
+	// the client specified an invalid popup parent surface.
+	XdgWmBaseErrorInvalidPopupParent XdgWmBaseError = 3
+	// the client provided an invalid surface state.
+	XdgWmBaseErrorInvalidSurfaceState XdgWmBaseError = 4
+	// the client provided an invalid positioner.
+	XdgWmBaseErrorInvalidPositioner XdgWmBaseError = 5
+	// the client didn’t respond to a ping event in time.
+	XdgWmBaseErrorUnresponsive XdgWmBaseError = 6
+)
+
+// XdgWmBaseDestroy is the xdg_wm_base.destroy request: destroy xdg_wm_base.
+//
+// Destroy this xdg_wm_base object.
+//
+// Destroying a bound xdg_wm_base object while there are surfaces
+// still alive created by this xdg_wm_base object instance is illegal
+// and will result in a defunct_surfaces error.
+//
+// It destroys the object.
+type XdgWmBaseDestroy struct{}
+
+// Since returns the first version of xdg_wm_base with the request.
+func (XdgWmBaseDestroy) Since() uint32 { return 1 }
+
+func (XdgWmBaseDestroy) destroys() {}
+
+// XdgWmBaseCreatePositioner is the xdg_wm_base.create_positioner request: create a positioner object.
+//
+// Create a positioner object. A positioner object is used to position
+// surfaces relative to some parent surface. See the interface description
+// and xdg_surface.get_popup for details.
+type XdgWmBaseCreatePositioner struct {
+	Id ObjectId `wayland:"new_id"`
+}
+
+// Since returns the first version of xdg_wm_base with the request.
+func (XdgWmBaseCreatePositioner) Since() uint32 { return 1 }
+
+// XdgWmBaseGetXdgSurface is the xdg_wm_base.get_xdg_surface request: create a shell surface from a surface.
+//
+// This creates an xdg_surface for the given surface. While xdg_surface
+// itself is not a role, the corresponding surface may only be assigned
+// a role extending xdg_surface, such as xdg_toplevel or xdg_popup.
+type XdgWmBaseGetXdgSurface struct {
+	Id      ObjectId `wayland:"new_id"`
+	Surface ObjectId
+}
+
+// Since returns the first version of xdg_wm_base with the request.
+func (XdgWmBaseGetXdgSurface) Since() uint32 { return 1 }
+
+// XdgWmBasePong is the xdg_wm_base.pong request: respond to a ping event.
+//
+// A client must respond to a ping event with a pong request or
+// the client may be deemed unresponsive. See xdg_wm_base.ping
+// and xdg_wm_base.error.unresponsive.
+type XdgWmBasePong struct {
+	Serial uint32 // serial of the ping event
+}
+
+// Since returns the first version of xdg_wm_base with the request.
+func (XdgWmBasePong) Since() uint32 { return 1 }
+
+// XdgWmBasePing is the xdg_wm_base.ping event: check if the client is alive.
+//
+// The ping event asks the client if it's still alive. Pass the
+// serial specified in the event back to the compositor by sending
+// a "pong" request back with the specified serial. See xdg_wm_base.pong.
+//
+// Compositors can use this to determine if the client is still
+// alive.
+type XdgWmBasePing struct {
+	Serial uint32 // pass this to the pong request
+}
+
+// Since returns the first version of xdg_wm_base with the event.
+func (XdgWmBasePing) Since() uint32 { return 1 }
+
+// Opcodes of the requests of xdg_positioner.
+const (
+	OpXdgPositionerDestroy                 Opcode = 0
+	OpXdgPositionerSetSize                 Opcode = 1
+	OpXdgPositionerSetAnchorRect           Opcode = 2
+	OpXdgPositionerSetAnchor               Opcode = 3
+	OpXdgPositionerSetGravity              Opcode = 4
+	OpXdgPositionerSetConstraintAdjustment Opcode = 5
+	OpXdgPositionerSetOffset               Opcode = 6
+	OpXdgPositionerSetReactive             Opcode = 7
+	OpXdgPositionerSetParentSize           Opcode = 8
+	OpXdgPositionerSetParentConfigure      Opcode = 9
+)
+
+// XdgPositionerError is the xdg_positioner.error enum.
+type XdgPositionerError uint32
+
+const (
+	// invalid input provided.
+	XdgPositionerErrorInvalidInput XdgPositionerError = 0
+)
+
+// XdgPositionerAnchor is the xdg_positioner.anchor enum.
+type XdgPositionerAnchor uint32
+
+const (
+	XdgPositionerAnchorNone        XdgPositionerAnchor = 0
+	XdgPositionerAnchorTop         XdgPositionerAnchor = 1
+	XdgPositionerAnchorBottom      XdgPositionerAnchor = 2
+	XdgPositionerAnchorLeft        XdgPositionerAnchor = 3
+	XdgPositionerAnchorRight       XdgPositionerAnchor = 4
+	XdgPositionerAnchorTopLeft     XdgPositionerAnchor = 5
+	XdgPositionerAnchorBottomLeft  XdgPositionerAnchor = 6
+	XdgPositionerAnchorTopRight    XdgPositionerAnchor = 7
+	XdgPositionerAnchorBottomRight XdgPositionerAnchor = 8
+)
+
+// XdgPositionerGravity is the xdg_positioner.gravity enum.
+type XdgPositionerGravity uint32
+
+const (
+	XdgPositionerGravityNone        XdgPositionerGravity = 0
+	XdgPositionerGravityTop         XdgPositionerGravity = 1
+	XdgPositionerGravityBottom      XdgPositionerGravity = 2
+	XdgPositionerGravityLeft        XdgPositionerGravity = 3
+	XdgPositionerGravityRight       XdgPositionerGravity = 4
+	XdgPositionerGravityTopLeft     XdgPositionerGravity = 5
+	XdgPositionerGravityBottomLeft  XdgPositionerGravity = 6
+	XdgPositionerGravityTopRight    XdgPositionerGravity = 7
+	XdgPositionerGravityBottomRight XdgPositionerGravity = 8
+)
+
+// XdgPositionerConstraintAdjustment is the xdg_positioner.constraint_adjustment enum: constraint adjustments.
+//
+// The constraint adjustment value define ways the compositor will adjust
+// the position of the surface, if the unadjusted position would result
+// in the surface being partly constrained.
+//
+// It is a bitfield.
+type XdgPositionerConstraintAdjustment uint32
+
+const (
+	XdgPositionerConstraintAdjustmentNone    XdgPositionerConstraintAdjustment = 0
+	XdgPositionerConstraintAdjustmentSlideX  XdgPositionerConstraintAdjustment = 1
+	XdgPositionerConstraintAdjustmentSlideY  XdgPositionerConstraintAdjustment = 2
+	XdgPositionerConstraintAdjustmentFlipX   XdgPositionerConstraintAdjustment = 4
+	XdgPositionerConstraintAdjustmentFlipY   XdgPositionerConstraintAdjustment = 8
+	XdgPositionerConstraintAdjustmentResizeX XdgPositionerConstraintAdjustment = 16
+	XdgPositionerConstraintAdjustmentResizeY XdgPositionerConstraintAdjustment = 32
+)
+
+// XdgPositionerDestroy is the xdg_positioner.destroy request: destroy the xdg_positioner object.
+//
+// Notify the compositor that the xdg_positioner will no longer be used.
+//
+// It destroys the object.
+type XdgPositionerDestroy struct{}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerDestroy) Since() uint32 { return 1 }
+
+func (XdgPositionerDestroy) destroys() {}
+
+// XdgPositionerSetSize is the xdg_positioner.set_size request: set the size of the to-be positioned rectangle.
+//
+// Set the size of the surface that is to be positioned with the positioner
+// object. The size is in surface-local coordinates and corresponds to the
+// window geometry. See xdg_surface.set_window_geometry.
+type XdgPositionerSetSize struct {
+	Width  int32 // width of positioned rectangle
+	Height int32 // height of positioned rectangle
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetSize) Since() uint32 { return 1 }
+
+// XdgPositionerSetAnchorRect is the xdg_positioner.set_anchor_rect request: set the anchor rectangle within the parent surface.
+//
+// Specify the anchor rectangle within the parent surface that the child
+// surface will be placed relative to. The rectangle is relative to the
+// window geometry as defined by xdg_surface.set_window_geometry of the
+// parent surface.
+type XdgPositionerSetAnchorRect struct {
+	X      int32 // x position of anchor rectangle
+	Y      int32 // y position of anchor rectangle
+	Width  int32 // width of anchor rectangle
+	Height int32 // height of anchor rectangle
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetAnchorRect) Since() uint32 { return 1 }
+
+// XdgPositionerSetAnchor is the xdg_positioner.set_anchor request: set anchor rectangle anchor.
+//
+// Defines the anchor point for the anchor rectangle. The specified anchor
+// is used derive an anchor point that the child surface will be
+// positioned relative to.
+type XdgPositionerSetAnchor struct {
+	Anchor XdgPositionerAnchor // anchor
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetAnchor) Since() uint32 { return 1 }
+
+// XdgPositionerSetGravity is the xdg_positioner.set_gravity request: set child surface gravity.
+//
+// Defines in what direction a surface should be positioned, relative to
+// the anchor point of the parent surface.
+type XdgPositionerSetGravity struct {
+	Gravity XdgPositionerGravity // gravity direction
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetGravity) Since() uint32 { return 1 }
+
+// XdgPositionerSetConstraintAdjustment is the xdg_positioner.set_constraint_adjustment request: set the adjustment to be done when constrained.
+//
+// Specify how the window should be positioned if the originally intended
+// position caused the surface to be constrained, meaning at least
+// partially outside positioning boundaries set by the compositor.
+type XdgPositionerSetConstraintAdjustment struct {
+	ConstraintAdjustment XdgPositionerConstraintAdjustment // bit mask of constraint adjustments
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetConstraintAdjustment) Since() uint32 { return 1 }
+
+// XdgPositionerSetOffset is the xdg_positioner.set_offset request: set surface position offset.
+//
+// Specify the surface position offset relative to the position of the
+// anchor on the anchor rectangle and the anchor on the surface.
+type XdgPositionerSetOffset struct {
+	X int32 // surface position x offset
+	Y int32 // surface position y offset
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetOffset) Since() uint32 { return 1 }
+
+// XdgPositionerSetReactive is the xdg_positioner.set_reactive request: continuously reconstrain the surface.
+//
+// When set reactive, the surface is reconstrained if the conditions used
+// for constraining changed, e.g. the parent window moved.
+//
+// Since version 3.
+type XdgPositionerSetReactive struct{}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetReactive) Since() uint32 { return 3 }
+
+// XdgPositionerSetParentSize is the xdg_positioner.set_parent_size request.
+//
+// Set the parent window geometry the compositor should use when
+// positioning the popup.
+//
+// Since version 3.
+type XdgPositionerSetParentSize struct {
+	ParentWidth  int32 // future window geometry width of parent
+	ParentHeight int32 // future window geometry height of parent
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetParentSize) Since() uint32 { return 3 }
+
+// XdgPositionerSetParentConfigure is the xdg_positioner.set_parent_configure request: set parent configure this is a response to.
+//
+// Set the serial of an xdg_surface.configure event this positioner will be
+// used in response to.
+//
+// Since version 3.
+type XdgPositionerSetParentConfigure struct {
+	Serial uint32 // serial of parent configure event
+}
+
+// Since returns the first version of xdg_positioner with the request.
+func (XdgPositionerSetParentConfigure) Since() uint32 { return 3 }
+
+// Opcodes of the requests of xdg_surface.
+const (
+	OpXdgSurfaceDestroy           Opcode = 0
+	OpXdgSurfaceGetToplevel       Opcode = 1
+	OpXdgSurfaceGetPopup          Opcode = 2
+	OpXdgSurfaceSetWindowGeometry Opcode = 3
+	OpXdgSurfaceAckConfigure      Opcode = 4
+)
+
+// Opcodes of the events of xdg_surface.
+const (
+	OpXdgSurfaceConfigure Opcode = 0
+)
+
+// XdgSurfaceError is the xdg_surface.error enum.
+type XdgSurfaceError uint32
+
+const (
+	// Surface was not fully constructed.
+	XdgSurfaceErrorNotConstructed XdgSurfaceError = 1
+	// Surface was already constructed.
+	XdgSurfaceErrorAlreadyConstructed XdgSurfaceError = 2
+	// Attaching a buffer to an unconfigured surface.
+	XdgSurfaceErrorUnconfiguredBuffer XdgSurfaceError = 3
+	// Invalid serial number when acking a configure event.
+	XdgSurfaceErrorInvalidSerial XdgSurfaceError = 4
+	// Width or height was zero or negative.
+	XdgSurfaceErrorInvalidSize XdgSurfaceError = 5
+	// Surface was destroyed before its role object.
+	XdgSurfaceErrorDefunctRoleObject XdgSurfaceError = 6
+)
+
+// XdgSurfaceDestroy is the xdg_surface.destroy request: destroy the xdg_surface.
+//
+// Destroy the xdg_surface object. An xdg_surface must only be destroyed
+// after its role object has been destroyed, otherwise
+// a defunct_role_object error is raised.
+//
+// It destroys the object.
+type XdgSurfaceDestroy struct{}
+
+// Since returns the first version of xdg_surface with the request.
+func (XdgSurfaceDestroy) Since() uint32 { return 1 }
+
+func (XdgSurfaceDestroy) destroys() {}
+
+// XdgSurfaceGetToplevel is the xdg_surface.get_toplevel request: assign the xdg_toplevel surface role.
+//
+// This creates an xdg_toplevel object for the given xdg_surface and gives
+// the associated wl_surface the xdg_toplevel role.
+type XdgSurfaceGetToplevel struct {
+	Id ObjectId `wayland:"new_id"`
+}
+
+// Since returns the first version of xdg_surface with the request.
+func (XdgSurfaceGetToplevel) Since() uint32 { return 1 }
+
+// XdgSurfaceGetPopup is the xdg_surface.get_popup request: assign the xdg_popup surface role.
+//
+// This creates an xdg_popup object for the given xdg_surface and gives
+// the associated wl_surface the xdg_popup role.
+type XdgSurfaceGetPopup struct {
+	Id         ObjectId `wayland:"new_id"`
+	Parent     ObjectId
+	Positioner ObjectId
+}
+
+// Since returns the first version of xdg_surface with the request.
+func (XdgSurfaceGetPopup) Since() uint32 { return 1 }
+
+// XdgSurfaceSetWindowGeometry is the xdg_surface.set_window_geometry request: set the new window geometry.
+//
+// The window geometry of a surface is its "visible bounds" from the
+// user's perspective. Client-side decorations often have invisible
+// portions like drop-shadows which should be ignored for the
+// purposes of aligning, placing and constraining windows.
+type XdgSurfaceSetWindowGeometry struct {
+	X      int32
+	Y      int32
+	Width  int32
+	Height int32
+}
+
+// Since returns the first version of xdg_surface with the request.
+func (XdgSurfaceSetWindowGeometry) Since() uint32 { return 1 }
+
+// XdgSurfaceAckConfigure is the xdg_surface.ack_configure request: ack a configure event.
+//
+// When a configure event is received, if a client commits the
+// surface in response to the configure event, then the client
+// must make an ack_configure request sometime before the commit
+// request, passing along the serial of the configure event.
+type XdgSurfaceAckConfigure struct {
+	Serial uint32 // the serial from the configure event
+}
+
+// Since returns the first version of xdg_surface with the request.
+func (XdgSurfaceAckConfigure) Since() uint32 { return 1 }
+
+// XdgSurfaceConfigure is the xdg_surface.configure event: suggest a surface change.
+//
+// The configure event marks the end of a configure sequence. A configure
+// sequence is a set of one or more events configuring the state of the
+// xdg_surface, including the final xdg_surface.configure event.
+type XdgSurfaceConfigure struct {
+	Serial uint32 // serial of the configure event
+}
+
+// Since returns the first version of xdg_surface with the event.
+func (XdgSurfaceConfigure) Since() uint32 { return 1 }
+
+// Opcodes of the requests of xdg_toplevel.
+const (
+	OpXdgToplevelDestroy         Opcode = 0
+	OpXdgToplevelSetParent       Opcode = 1
+	OpXdgToplevelSetTitle        Opcode = 2
+	OpXdgToplevelSetAppId        Opcode = 3
+	OpXdgToplevelShowWindowMenu  Opcode = 4
+	OpXdgToplevelMove            Opcode = 5
+	OpXdgToplevelResize          Opcode = 6
+	OpXdgToplevelSetMaxSize      Opcode = 7
+	OpXdgToplevelSetMinSize      Opcode = 8
+	OpXdgToplevelSetMaximized    Opcode = 9
+	OpXdgToplevelUnsetMaximized  Opcode = 10
+	OpXdgToplevelSetFullscreen   Opcode = 11
+	OpXdgToplevelUnsetFullscreen Opcode = 12
+	OpXdgToplevelSetMinimized    Opcode = 13
+)
+
+// Opcodes of the events of xdg_toplevel.
+const (
+	OpXdgToplevelConfigure           Opcode = 0
+	OpXdgToplevelClose               Opcode = 1
+	OpXdgToplevelConfigureBounds     Opcode = 2
+	OpXdgToplevelWmCapabilitiesEvent Opcode = 3
+)
+
+// XdgToplevelError is the xdg_toplevel.error enum.
+type XdgToplevelError uint32
+
+const (
+	// provided value is not a valid variant of the resize_edge enum.
+	XdgToplevelErrorInvalidResizeEdge XdgToplevelError = 0
+	// invalid parent toplevel.
+	XdgToplevelErrorInvalidParent XdgToplevelError = 1
+	// client provided an invalid min or max size.
+	XdgToplevelErrorInvalidSize XdgToplevelError = 2
+)
+
+// XdgToplevelResizeEdge is the xdg_toplevel.resize_edge enum: edge values for resizing.
+//
+// These values are used to indicate which edge of a surface
+// is being dragged in a resize operation.
+type XdgToplevelResizeEdge uint32
+
+const (
+	XdgToplevelResizeEdgeNone        XdgToplevelResizeEdge = 0
+	XdgToplevelResizeEdgeTop         XdgToplevelResizeEdge = 1
+	XdgToplevelResizeEdgeBottom      XdgToplevelResizeEdge = 2
+	XdgToplevelResizeEdgeLeft        XdgToplevelResizeEdge = 4
+	XdgToplevelResizeEdgeTopLeft     XdgToplevelResizeEdge = 5
+	XdgToplevelResizeEdgeBottomLeft  XdgToplevelResizeEdge = 6
+	XdgToplevelResizeEdgeRight       XdgToplevelResizeEdge = 8
+	XdgToplevelResizeEdgeTopRight    XdgToplevelResizeEdge = 9
+	XdgToplevelResizeEdgeBottomRight XdgToplevelResizeEdge = 10
+)
+
+// XdgToplevelState is the xdg_toplevel.state enum: types of state on the surface.
+//
+// The different state values used on the surface. This is designed for
+// state values like maximized, fullscreen. It is paired with the
+// configure event to ensure that both the client and the compositor
+// setting the state can be synchronized.
+type XdgToplevelState uint32
+
+const (
+	// the surface is maximized.
+	XdgToplevelStateMaximized XdgToplevelState = 1
+	// the surface is fullscreen.
+	XdgToplevelStateFullscreen XdgToplevelState = 2
+	// the surface is being resized.
+	XdgToplevelStateResizing XdgToplevelState = 3
+	// the surface is now activated.
+	XdgToplevelStateActivated XdgToplevelState = 4
+	// the surface’s left edge is tiled.
+	//
+	// Since version 2.
+	XdgToplevelStateTiledLeft XdgToplevelState = 5
+	// the surface’s right edge is tiled.
+	//
+	// Since version 2.
+	XdgToplevelStateTiledRight XdgToplevelState = 6
+	// the surface’s top edge is tiled.
+	//
+	// Since version 2.
+	XdgToplevelStateTiledTop XdgToplevelState = 7
+	// the surface’s bottom edge is tiled.
+	//
+	// Since version 2.
+	XdgToplevelStateTiledBottom XdgToplevelState = 8
+	// surface repaint is suspended.
+	//
+	// Since version 6.
+	XdgToplevelStateSuspended XdgToplevelState = 9
+)
+
+// XdgToplevelWmCapabilities is the xdg_toplevel.wm_capabilities enum.
+//
+// Since version 5.
+type XdgToplevelWmCapabilities uint32
+
+const (
+	// show_window_menu is available.
+	XdgToplevelWmCapabilitiesWindowMenu XdgToplevelWmCapabilities = 1
+	// set_maximized and unset_maximized are available.
+	XdgToplevelWmCapabilitiesMaximize XdgToplevelWmCapabilities = 2
+	// set_fullscreen and unset_fullscreen are available.
+	XdgToplevelWmCapabilitiesFullscreen XdgToplevelWmCapabilities = 3
+	// set_minimized is available.
+	XdgToplevelWmCapabilitiesMinimize XdgToplevelWmCapabilities = 4
+)
+
+// XdgToplevelDestroy is the xdg_toplevel.destroy request: destroy the xdg_toplevel.
+//
+// This request destroys the role surface and unmaps the surface;
+// see "Unmapping" behavior in interface section for details.
+//
+// It destroys the object.
+type XdgToplevelDestroy struct{}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelDestroy) Since() uint32 { return 1 }
+
+func (XdgToplevelDestroy) destroys() {}
+
+// XdgToplevelSetParent is the xdg_toplevel.set_parent request: set the parent of this surface.
+//
+// Set the "parent" of this surface. This surface should be stacked
+// above the parent surface and all other ancestor surfaces.
+type XdgToplevelSetParent struct {
+	Parent ObjectId
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetParent) Since() uint32 { return 1 }
+
+// XdgToplevelSetTitle is the xdg_toplevel.set_title request: set surface title.
+//
+// Set a short title for the surface.
+//
+// This string may be used to identify the surface in a task bar,
+// window list, or other user interface elements provided by the
+// compositor.
+type XdgToplevelSetTitle struct {
+	Title string
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetTitle) Since() uint32 { return 1 }
+
+// XdgToplevelSetAppId is the xdg_toplevel.set_app_id request: set application ID.
+//
+// Set an application identifier for the surface.
+//
+// The app ID identifies the general class of applications to which
+// the surface belongs. The compositor can use this to group multiple
+// surfaces together, or to determine how to launch a new application.
+type XdgToplevelSetAppId struct {
+	AppId string
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetAppId) Since() uint32 { return 1 }
+
+// XdgToplevelShowWindowMenu is the xdg_toplevel.show_window_menu request: show the window menu.
+//
+// Clients implementing client-side decorations might want to show
+// a context menu when right-clicking on the decorations, giving the
+// user a menu that they can use to maximize or minimize the window.
+type XdgToplevelShowWindowMenu struct {
+	Seat   ObjectId // the wl_seat of the user event
+	Serial uint32   // the serial of the user event
+	X      int32    // the x position to pop up the window menu at
+	Y      int32    // the y position to pop up the window menu at
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelShowWindowMenu) Since() uint32 { return 1 }
+
+// XdgToplevelMove is the xdg_toplevel.move request: start an interactive move.
+//
+// Start an interactive, user-driven move of the surface.
+//
+// This request must be used in response to some sort of user action
+// like a button press, key press, or touch down event.
+type XdgToplevelMove struct {
+	Seat   ObjectId // the wl_seat of the user event
+	Serial uint32   // the serial of the user event
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelMove) Since() uint32 { return 1 }
+
+// XdgToplevelResize is the xdg_toplevel.resize request: start an interactive resize.
+//
+// Start a user-driven, interactive resize of the surface.
+//
+// This request must be used in response to some sort of user action
+// like a button press, key press, or touch down event.
+type XdgToplevelResize struct {
+	Seat   ObjectId              // the wl_seat of the user event
+	Serial uint32                // the serial of the user event
+	Edges  XdgToplevelResizeEdge // which edge or corner is being dragged
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelResize) Since() uint32 { return 1 }
+
+// XdgToplevelSetMaxSize is the xdg_toplevel.set_max_size request: set the maximum size.
+//
+// Set a maximum size for the window.
+//
+// The client can specify a maximum size so that the compositor does
+// not try to configure the window beyond this size. A width or height
+// of zero means no maximum.
+type XdgToplevelSetMaxSize struct {
+	Width  int32
+	Height int32
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetMaxSize) Since() uint32 { return 1 }
+
+// XdgToplevelSetMinSize is the xdg_toplevel.set_min_size request: set the minimum size.
+//
+// Set a minimum size for the window.
+//
+// The client can specify a minimum size so that the compositor does
+// not try to configure the window below this size. A width or height
+// of zero means no minimum.
+type XdgToplevelSetMinSize struct {
+	Width  int32
+	Height int32
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetMinSize) Since() uint32 { return 1 }
+
+// XdgToplevelSetMaximized is the xdg_toplevel.set_maximized request: maximize the window.
+//
+// Maximize the surface.
+//
+// After requesting that the surface should be maximized, the compositor
+// will respond by emitting a configure event. Whether this configure
+// actually sets the window maximized is subject to compositor policies.
+type XdgToplevelSetMaximized struct{}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetMaximized) Since() uint32 { return 1 }
+
+// XdgToplevelUnsetMaximized is the xdg_toplevel.unset_maximized request: unmaximize the window.
+//
+// Unmaximize the surface.
+//
+// After requesting that the surface should be unmaximized, the compositor
+// will respond by emitting a configure event.
+type XdgToplevelUnsetMaximized struct{}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelUnsetMaximized) Since() uint32 { return 1 }
+
+// XdgToplevelSetFullscreen is the xdg_toplevel.set_fullscreen request: set the window as fullscreen on an output.
+//
+// Make the surface fullscreen.
+//
+// After requesting that the surface should be fullscreened, the
+// compositor will respond by emitting a configure event. If the
+// output is null, the compositor chooses it.
+type XdgToplevelSetFullscreen struct {
+	Output ObjectId
+}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetFullscreen) Since() uint32 { return 1 }
+
+// XdgToplevelUnsetFullscreen is the xdg_toplevel.unset_fullscreen request: unset the window as fullscreen.
+//
+// Make the surface no longer fullscreen.
+//
+// After requesting that the surface should be unfullscreened, the
+// compositor will respond by emitting a configure event.
+type XdgToplevelUnsetFullscreen struct{}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelUnsetFullscreen) Since() uint32 { return 1 }
+
+// XdgToplevelSetMinimized is the xdg_toplevel.set_minimized request: set the window as minimized.
+//
+// Request that the compositor minimize your surface. There is no
+// way to know if the surface is currently minimized, nor is there
+// any way to unset minimization on this surface.
+type XdgToplevelSetMinimized struct{}
+
+// Since returns the first version of xdg_toplevel with the request.
+func (XdgToplevelSetMinimized) Since() uint32 { return 1 }
+
+// XdgToplevelConfigure is the xdg_toplevel.configure event: suggest a surface change.
+//
+// This configure event asks the client to resize its toplevel surface or
+// to change its state. The configured state should not be applied
+// immediately. See xdg_surface.configure for details.
+//
+// If the width or height arguments are zero, it means the client
+// should decide its own window dimension.
+type XdgToplevelConfigure struct {
+	Width  int32
+	Height int32
+	States []byte
+}
+
+// Since returns the first version of xdg_toplevel with the event.
+func (XdgToplevelConfigure) Since() uint32 { return 1 }
+
+// XdgToplevelClose is the xdg_toplevel.close event: surface wants to be closed.
+//
+// The close event is sent by the compositor when the user
+// wants the surface to be closed. This should be equivalent to
+// the user clicking the close button in client-side decorations,
+// if your application has any.
+type XdgToplevelClose struct{}
+
+// Since returns the first version of xdg_toplevel with the event.
+func (XdgToplevelClose) Since() uint32 { return 1 }
+
+// XdgToplevelConfigureBounds is the xdg_toplevel.configure_bounds event: recommended window geometry bounds.
+//
+// The configure_bounds event may be sent prior to a xdg_toplevel.configure
+// event to communicate the bounds a window geometry size is recommended
+// to constrain to.
+//
+// Since version 4.
+type XdgToplevelConfigureBounds struct {
+	Width  int32
+	Height int32
+}
+
+// Since returns the first version of xdg_toplevel with the event.
+func (XdgToplevelConfigureBounds) Since() uint32 { return 4 }
+
+// XdgToplevelWmCapabilitiesEvent is the xdg_toplevel.wm_capabilities event: compositor capabilities.
+//
+// This event advertises the capabilities supported by the compositor. If
+// a capability isn't supported, clients should hide or disable the UI
+// elements that expose this functionality.
+//
+// Since version 5.
+type XdgToplevelWmCapabilitiesEvent struct {
+	Capabilities []byte // array of 32-bit capabilities
+}
+
+// Since returns the first version of xdg_toplevel with the event.
+func (XdgToplevelWmCapabilitiesEvent) Since() uint32 { return 5 }
+
+// Opcodes of the requests of xdg_popup.
+const (
+	OpXdgPopupDestroy    Opcode = 0
+	OpXdgPopupGrab       Opcode = 1
+	OpXdgPopupReposition Opcode = 2
+)
+
+// Opcodes of the events of xdg_popup.
+const (
+	OpXdgPopupConfigure    Opcode = 0
+	OpXdgPopupPopupDone    Opcode = 1
+	OpXdgPopupRepositioned Opcode = 2
+)
+
+// XdgPopupError is the xdg_popup.error enum.
+type XdgPopupError uint32
+
+const (
+	// tried to grab after being mapped.
+	XdgPopupErrorInvalidGrab XdgPopupError = 0
+)
+
+// XdgPopupDestroy is the xdg_popup.destroy request: remove xdg_popup interface.
+//
+// This destroys the popup. Explicitly destroying the xdg_popup
+// object will also dismiss the popup, and unmap the surface.
+//
+// It destroys the object.
+type XdgPopupDestroy struct{}
+
+// Since returns the first version of xdg_popup with the request.
+func (XdgPopupDestroy) Since() uint32 { return 1 }
+
+func (XdgPopupDestroy) destroys() {}
+
+// XdgPopupGrab is the xdg_popup.grab request: make the popup take an explicit grab.
+//
+// This request makes the created popup take an explicit grab. An explicit
+// grab will be dismissed when the user dismisses the popup, or when the
+// client destroys the xdg_popup.
+type XdgPopupGrab struct {
+	Seat   ObjectId // the wl_seat of the user event
+	Serial uint32   // the serial of the user event
+}
+
+// Since returns the first version of xdg_popup with the request.
+func (XdgPopupGrab) Since() uint32 { return 1 }
+
+// XdgPopupReposition is the xdg_popup.reposition request: recalculate the popup's location.
+//
+// Reposition an already-mapped popup. The popup will be placed given the
+// details in the passed xdg_positioner object, and a
+// xdg_popup.repositioned followed by xdg_popup.configure and
+// xdg_surface.configure will be emitted in response.
+//
+// Since version 3.
+type XdgPopupReposition struct {
+	Positioner ObjectId
+	Token      uint32 // reposition request token
+}
+
+// Since returns the first version of xdg_popup with the request.
+func (XdgPopupReposition) Since() uint32 { return 3 }
+
+// XdgPopupConfigure is the xdg_popup.configure event: configure the popup surface.
+//
+// This event asks the popup surface to configure itself given the
+// configuration. The configured state should not be applied immediately.
+// See xdg_surface.configure for details.
+type XdgPopupConfigure struct {
+	X      int32 // x position relative to parent surface window geometry
+	Y      int32 // y position relative to parent surface window geometry
+	Width  int32 // window geometry width
+	Height int32 // window geometry height
+}
+
+// Since returns the first version of xdg_popup with the event.
+func (XdgPopupConfigure) Since() uint32 { return 1 }
+
+// XdgPopupPopupDone is the xdg_popup.popup_done event: popup interaction is done.
+//
+// The popup_done event is sent out when a popup is dismissed by the
+// compositor. The client should destroy the xdg_popup object at this
+// point.
+type XdgPopupPopupDone struct{}
+
+// Since returns the first version of xdg_popup with the event.
+func (XdgPopupPopupDone) Since() uint32 { return 1 }
+
+// XdgPopupRepositioned is the xdg_popup.repositioned event: signal the completion of a repositioned request.
+//
+// The repositioned event is sent as part of a popup configuration
+// sequence, together with xdg_popup.configure and lastly
+// xdg_surface.configure to notify the completion of a reposition request.
+//
+// Since version 3.
+type XdgPopupRepositioned struct {
+	Token uint32 // reposition request token
+}
+
+// Since returns the first version of xdg_popup with the event.
+func (XdgPopupRepositioned) Since() uint32 { return 3 }
+
+func init() {
+	requestNames[XdgWmBaseInterface] = []string{"destroy", "create_positioner", "get_xdg_surface", "pong"}
+	eventNames[XdgWmBaseInterface] = []string{"ping"}
+	eventTypes[XdgWmBaseInterface] = []interface{}{
+		XdgWmBasePing{},
+	}
+	errorNames[XdgWmBaseInterface] = map[uint32]string{
+		0: "role",
+		1: "defunct_surfaces",
+		2: "not_the_topmost_popup",
+		3: "invalid_popup_parent",
+		4: "invalid_surface_state",
+		5: "invalid_positioner",
+		6: "unresponsive",
+	}
+	requestNames[XdgPositionerInterface] = []string{"destroy", "set_size", "set_anchor_rect", "set_anchor", "set_gravity", "set_constraint_adjustment", "set_offset", "set_reactive", "set_parent_size", "set_parent_configure"}
+	errorNames[XdgPositionerInterface] = map[uint32]string{
+		0: "invalid_input",
+	}
+	requestNames[XdgSurfaceInterface] = []string{"destroy", "get_toplevel", "get_popup", "set_window_geometry", "ack_configure"}
+	eventNames[XdgSurfaceInterface] = []string{"configure"}
+	eventTypes[XdgSurfaceInterface] = []interface{}{
+		XdgSurfaceConfigure{},
+	}
+	errorNames[XdgSurfaceInterface] = map[uint32]string{
+		1: "not_constructed",
+		2: "already_constructed",
+		3: "unconfigured_buffer",
+		4: "invalid_serial",
+		5: "invalid_size",
+		6: "defunct_role_object",
+	}
+	requestNames[XdgToplevelInterface] = []string{"destroy", "set_parent", "set_title", "set_app_id", "show_window_menu", "move", "resize", "set_max_size", "set_min_size", "set_maximized", "unset_maximized", "set_fullscreen", "unset_fullscreen", "set_minimized"}
+	eventNames[XdgToplevelInterface] = []string{"configure", "close", "configure_bounds", "wm_capabilities"}
+	eventTypes[XdgToplevelInterface] = []interface{}{
+		XdgToplevelConfigure{},
+		XdgToplevelClose{},
+		XdgToplevelConfigureBounds{},
+		XdgToplevelWmCapabilitiesEvent{},
+	}
+	errorNames[XdgToplevelInterface] = map[uint32]string{
+		0: "invalid_resize_edge",
+		1: "invalid_parent",
+		2: "invalid_size",
+	}
+	requestNames[XdgPopupInterface] = []string{"destroy", "grab", "reposition"}
+	eventNames[XdgPopupInterface] = []string{"configure", "popup_done", "repositioned"}
+	eventTypes[XdgPopupInterface] = []interface{}{
+		XdgPopupConfigure{},
+		XdgPopupPopupDone{},
+		XdgPopupRepositioned{},
+	}
+	errorNames[XdgPopupInterface] = map[uint32]string{
+		0: "invalid_grab",
+	}
+}
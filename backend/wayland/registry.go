@@ -0,0 +1,148 @@
+package wayland
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Versions of the globals bound by the backend. Compositors supporting newer
+// versions are bound with these, as they could send events the backend does
+// not know about.
+const (
+	// Version 4 adds wl_surface.damage_buffer. Later versions send scale
+	// and transform preferences.
+	compositorVersion = 4
+	shmVersion        = 1
+	// Version 2 adds the tiled states.
+	wmBaseVersion = 2
+)
+
+// Global is an object announced by the compositor, such as an output or a
+// seat, that clients bind to use.
+type Global struct {
+	Name      uint32
+	Interface string
+	Version   uint32
+}
+
+// GlobalEvent is reported when the compositor announces a global once
+// connected, for example when an output is plugged in.
+type GlobalEvent struct {
+	Global Global
+}
+
+// GlobalRemoveEvent is reported when a global goes away, for example when
+// an output is unplugged. Objects bound to it should be destroyed.
+type GlobalRemoveEvent struct {
+	Global Global
+}
+
+// getRegistry creates the registry, which announces the globals.
+func (b *Backend) getRegistry() error {
+	b.registry = b.NewProxy(RegistryInterface, 1)
+	b.RegistryId = b.registry.Id
+	b.registry.Handle(OpRegistryGlobal, b.global)
+	b.registry.Handle(OpRegistryGlobalRemove, b.globalRemove)
+	return b.display.Request(OpDisplayGetRegistry, DisplayGetRegistry{
+		Registry: b.RegistryId,
+	})
+}
+
+// Globals returns the globals announced by the compositor, in the order of
+// their names.
+func (b *Backend) Globals() []Global {
+	globals := make([]Global, 0, len(b.globals))
+	for _, g := range b.globals {
+		globals = append(globals, g)
+	}
+	sort.Slice(globals, func(i, j int) bool { return globals[i].Name < globals[j].Name })
+	return globals
+}
+
+// HasGlobal returns whether the compositor supports an interface with at
+// least the given version, so that optional protocols can be checked for
+// before they are used.
+func (b *Backend) HasGlobal(iface string, version uint32) bool {
+	for _, g := range b.globals {
+		if g.Interface == iface && g.Version >= version {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind binds a global with the highest version supported by both the
+// compositor and the caller, which is the Version of the returned proxy.
+func (b *Backend) Bind(g Global, version uint32) (*Proxy, error) {
+	current, ok := b.globals[g.Name]
+	if !ok || current.Interface != g.Interface {
+		return nil, fmt.Errorf("binding %s: global %d removed", g.Interface, g.Name)
+	}
+	if version > current.Version {
+		version = current.Version
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("binding %s: invalid version 0", g.Interface)
+	}
+
+	p := b.NewProxy(g.Interface, version)
+	err := b.registry.Request(OpRegistryBind, RegistryBind{
+		Name: g.Name,
+		Id:   NewId{Interface: g.Interface, Version: version, Id: p.Id},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("binding %s: %w", g.Interface, err)
+	}
+	return p, nil
+}
+
+// global records an announced global, binding those used by the backend.
+// Errors to send are reported by the next read.
+func (b *Backend) global(ev RegistryGlobal) {
+	g := Global{Name: ev.Name, Interface: ev.Interface, Version: ev.Version}
+	b.globals[g.Name] = g
+	if b.connected {
+		b.pending = append(b.pending, GlobalEvent{Global: g})
+	}
+
+	switch g.Interface {
+	case CompositorInterface:
+		compositor, err := b.Bind(g, compositorVersion)
+		if err != nil {
+			return
+		}
+		b.CompositorId = compositor.Id
+
+	case ShmInterface:
+		shm, err := b.Bind(g, shmVersion)
+		if err != nil {
+			return
+		}
+		shm.Handle(OpShmFormatEvent, func(ev ShmFormatEvent) {
+			b.shmFormats = append(b.shmFormats, ev.Format)
+		})
+		b.ShmId = shm.Id
+
+	case XdgWmBaseInterface:
+		wmBase, err := b.Bind(g, wmBaseVersion)
+		if err != nil {
+			return
+		}
+		wmBase.Handle(OpXdgWmBasePing, func(ev XdgWmBasePing) {
+			// Compositors consider clients that do not answer
+			// unresponsive.
+			wmBase.Request(OpXdgWmBasePong, XdgWmBasePong{Serial: ev.Serial})
+		})
+		b.WmBaseId = wmBase.Id
+	}
+}
+
+// globalRemove forgets a global that went away.
+func (b *Backend) globalRemove(ev RegistryGlobalRemove) {
+	g, ok := b.globals[ev.Name]
+	if !ok {
+		return
+	}
+	delete(b.globals, ev.Name)
+	b.pending = append(b.pending, GlobalRemoveEvent{Global: g})
+}
@@ -0,0 +1,60 @@
+package wayland
+
+import (
+	"testing"
+)
+
+func TestGlobals(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+
+	output := Global{Name: 10, Interface: "wl_output", Version: 3}
+	c.send(b.RegistryId, OpRegistryGlobal, RegistryGlobal{
+		Name:      output.Name,
+		Interface: output.Interface,
+		Version:   output.Version,
+	})
+	ev, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev != (GlobalEvent{Global: output}) {
+		t.Errorf("got event %+v", ev)
+	}
+	if !b.HasGlobal("wl_output", 3) || b.HasGlobal("wl_output", 4) {
+		t.Errorf("globals %v", b.Globals())
+	}
+	if globals := b.Globals(); len(globals) != 4 || globals[3] != output {
+		t.Errorf("globals %v", globals)
+	}
+
+	// Globals are bound with the lowest of both versions.
+	for _, versions := range [][2]uint32{{2, 2}, {9, 3}} {
+		p, err := b.Bind(output, versions[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bind RegistryBind
+		c.expect(b.RegistryId, OpRegistryBind, &bind)
+		want := RegistryBind{Name: 10, Id: NewId{Interface: "wl_output", Version: versions[1], Id: p.Id}}
+		if bind != want || p.Version != versions[1] {
+			t.Errorf("bound %+v for version %d, want %+v", bind, versions[0], want)
+		}
+	}
+
+	c.send(b.RegistryId, OpRegistryGlobalRemove, RegistryGlobalRemove{Name: output.Name})
+	ev, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev != (GlobalRemoveEvent{Global: output}) {
+		t.Errorf("got event %+v", ev)
+	}
+	if b.HasGlobal("wl_output", 1) {
+		t.Errorf("removed global still announced")
+	}
+	_, err = b.Bind(output, 1)
+	if err == nil {
+		t.Errorf("bound removed global")
+	}
+}
@@ -0,0 +1,197 @@
+package wayland
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+)
+
+// framesPerSize is the number of buffers of the same size kept for a
+// surface: one shown by the compositor and one drawn into.
+const framesPerSize = 2
+
+// shmPool is a wl_shm_pool, memory shared with the compositor that buffers
+// are allocated from. It only grows, as wl_shm_pool.resize requires.
+type shmPool struct {
+	proxy *Proxy
+	f     *os.File
+	data  []byte
+
+	// unmapped are the mappings replaced when growing the pool, which
+	// frames handed out before may still use.
+	unmapped [][]byte
+
+	buffers map[ObjectId]*shmBuffer
+}
+
+// shmBuffer is a wl_buffer in a pool, busy from the time it is attached
+// until the compositor releases it.
+type shmBuffer struct {
+	proxy   *Proxy
+	surface ObjectId
+	offset  int
+	width   int
+	height  int
+	stride  int
+	busy    bool
+}
+
+func (s *shmBuffer) size() int {
+	return s.stride * s.height
+}
+
+// ShmFormats returns the pixel formats supported by the compositor for
+// shared memory buffers.
+func (b *Backend) ShmFormats() []ShmFormat {
+	return b.shmFormats
+}
+
+// newShmPool creates a pool of size bytes.
+func (b *Backend) newShmPool(size int) (p *shmPool, err error) {
+	if b.ShmId == 0 {
+		return nil, fmt.Errorf("creating shared memory pool: wl_shm not supported by the compositor")
+	}
+
+	f, err := createShmFile(size)
+	if err != nil {
+		return nil, fmt.Errorf("creating shared memory pool: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("mapping shared memory pool: %w", err)
+	}
+
+	shm := b.Object(b.ShmId)
+	p = &shmPool{
+		proxy:   b.NewProxy(ShmPoolInterface, shm.Version),
+		f:       f,
+		data:    data,
+		buffers: make(map[ObjectId]*shmBuffer),
+	}
+	err = shm.Request(OpShmCreatePool, ShmCreatePool{
+		Id:   p.proxy.Id,
+		Fd:   Fd(f.Fd()),
+		Size: int32(size),
+	})
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, fmt.Errorf("creating shared memory pool: %w", err)
+	}
+	return p, nil
+}
+
+// grow makes the pool at least size bytes large, doubling it to resize it
+// less often.
+func (p *shmPool) grow(size int) error {
+	if size <= len(p.data) {
+		return nil
+	}
+	if size < 2*len(p.data) {
+		size = 2 * len(p.data)
+	}
+
+	err := p.f.Truncate(int64(size))
+	if err != nil {
+		return fmt.Errorf("growing shared memory pool: %w", err)
+	}
+	data, err := syscall.Mmap(int(p.f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("mapping shared memory pool: %w", err)
+	}
+	p.unmapped = append(p.unmapped, p.data)
+	p.data = data
+
+	err = p.proxy.Request(OpShmPoolResize, ShmPoolResize{Size: int32(size)})
+	if err != nil {
+		return fmt.Errorf("growing shared memory pool: %w", err)
+	}
+	return nil
+}
+
+// alloc returns the offset of the first free range of size bytes, growing
+// the pool when none is large enough.
+func (p *shmPool) alloc(size int) (offset int, err error) {
+	used := make([]*shmBuffer, 0, len(p.buffers))
+	for _, buf := range p.buffers {
+		used = append(used, buf)
+	}
+	sort.Slice(used, func(i, j int) bool { return used[i].offset < used[j].offset })
+
+	for _, buf := range used {
+		if buf.offset-offset >= size {
+			return offset, nil
+		}
+		offset = buf.offset + buf.size()
+	}
+	err = p.grow(offset + size)
+	if err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// newBuffer allocates an ARGB8888 buffer in the pool.
+func (p *shmPool) newBuffer(b *Backend, width, height int) (*shmBuffer, error) {
+	buf := &shmBuffer{
+		proxy:  b.NewProxy(BufferInterface, p.proxy.Version),
+		width:  width,
+		height: height,
+		stride: width * 4,
+	}
+
+	var err error
+	buf.offset, err = p.alloc(buf.size())
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.proxy.Request(OpShmPoolCreateBuffer, ShmPoolCreateBuffer{
+		Id:     buf.proxy.Id,
+		Offset: int32(buf.offset),
+		Width:  int32(width),
+		Height: int32(height),
+		Stride: int32(buf.stride),
+		Format: ShmFormatArgb8888,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating buffer: %w", err)
+	}
+	buf.proxy.Handle(OpBufferRelease, func(BufferRelease) {
+		buf.busy = false
+	})
+	p.buffers[buf.proxy.Id] = buf
+	return buf, nil
+}
+
+// destroyBuffer destroys a buffer, which frees its memory in the pool.
+func (p *shmPool) destroyBuffer(buf *shmBuffer) error {
+	delete(p.buffers, buf.proxy.Id)
+	err := buf.proxy.Request(OpBufferDestroy, BufferDestroy{})
+	if err != nil {
+		return fmt.Errorf("destroying buffer: %w", err)
+	}
+	return nil
+}
+
+// bytes returns the memory of a buffer.
+func (p *shmPool) bytes(buf *shmBuffer) []byte {
+	return p.data[buf.offset : buf.offset+buf.size()]
+}
+
+// release unmaps the pool, which invalidates the images of its frames. The
+// compositor keeps the memory until the buffers are destroyed.
+func (p *shmPool) release() {
+	for _, data := range p.unmapped {
+		syscall.Munmap(data)
+	}
+	syscall.Munmap(p.data)
+	p.data, p.unmapped = nil, nil
+	p.f.Close()
+}
@@ -0,0 +1,38 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package wayland
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const mfdCloexec = 1
+
+// sysMemfdCreate is missing from package syscall on amd64.
+var sysMemfdCreate = map[string]uintptr{
+	"amd64": 319,
+	"arm64": 279,
+}[runtime.GOARCH]
+
+// createShmFile creates an anonymous file of size bytes with memfd_create.
+func createShmFile(size int) (*os.File, error) {
+	name := []byte("wayland-shm\x00")
+	fd, _, errno := syscall.Syscall(sysMemfdCreate, uintptr(unsafe.Pointer(&name[0])), mfdCloexec, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("creating memfd: %w", errno)
+	}
+
+	f := os.NewFile(fd, "wayland-shm")
+	err := f.Truncate(int64(size))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sizing memfd: %w", err)
+	}
+	return f, nil
+}
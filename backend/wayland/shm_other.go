@@ -0,0 +1,31 @@
+//go:build !linux || !(amd64 || arm64)
+// +build !linux !amd64,!arm64
+
+package wayland
+
+import (
+	"fmt"
+	"os"
+)
+
+// createShmFile creates a file of size bytes in XDG_RUNTIME_DIR, and
+// unlinks it so that it is only reachable through its descriptor.
+func createShmFile(size int) (*os.File, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return nil, fmt.Errorf("creating shared memory file: 'XDG_RUNTIME_DIR' is not set")
+	}
+
+	f, err := os.CreateTemp(dir, "wayland-shm-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating shared memory file: %w", err)
+	}
+	os.Remove(f.Name())
+
+	err = f.Truncate(int64(size))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sizing shared memory file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,210 @@
+package wayland
+
+import (
+	"image"
+	"image/color"
+	"syscall"
+	"testing"
+)
+
+// fakeCompositor reads requests on the server end of a socket pair.
+type fakeCompositor struct {
+	t    *testing.T
+	conn *Conn
+
+	// b is flushed before reading its requests, if set.
+	b *Backend
+}
+
+// newFakeCompositor returns a backend with the registry and the globals
+// bound, whose next object id is 11.
+func newFakeCompositor(t *testing.T) (*Backend, *fakeCompositor) {
+	client, server := socketPair(t)
+	b := newBackend(client)
+	c := &fakeCompositor{t: t, conn: server, b: b}
+
+	err := b.getRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.expect(DisplayId, OpDisplayGetRegistry, nil)
+	b.global(RegistryGlobal{Name: 1, Interface: CompositorInterface, Version: 4})
+	b.global(RegistryGlobal{Name: 2, Interface: ShmInterface, Version: 1})
+	b.global(RegistryGlobal{Name: 3, Interface: XdgWmBaseInterface, Version: 2})
+	for i := 0; i < 3; i++ {
+		c.expect(b.RegistryId, OpRegistryBind, nil)
+	}
+	b.PrevObjectId = 10
+	b.connected = true
+	return b, c
+}
+
+// expect reads the next request and checks its object and opcode. Its
+// arguments are unmarshalled into data, unless it is nil.
+func (c *fakeCompositor) expect(id ObjectId, opcode Opcode, data interface{}) Message {
+	c.t.Helper()
+
+	if c.b != nil {
+		c.b.Flush()
+	}
+	msg, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if msg.ObjectId != id || msg.Opcode != opcode {
+		c.t.Fatalf("got request %d.%d, want %d.%d", msg.ObjectId, msg.Opcode, id, opcode)
+	}
+	if data != nil {
+		err = msg.Unmarshall(data)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return msg
+}
+
+// send sends an event with the arguments in data.
+func (c *fakeCompositor) send(id ObjectId, opcode Opcode, data interface{}) {
+	c.t.Helper()
+
+	err := c.conn.WriteMessage(newMessage(c.t, id, opcode, data))
+	if err == nil {
+		err = c.conn.Flush()
+	}
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+	w := &Window{b: b, SurfaceId: 3}
+
+	f, err := w.NextFrame(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetRGBA(1, 1, color.RGBA{0x11, 0x22, 0x33, 0xff})
+
+	var pool ShmCreatePool
+	msg := c.expect(b.ShmId, OpShmCreatePool, nil)
+	mustUnmarshall(t, &msg, &pool)
+	defer syscall.Close(int(pool.Fd))
+	if pool.Size < 4*2*4 {
+		t.Errorf("pool of %d bytes", pool.Size)
+	}
+
+	var first ShmPoolCreateBuffer
+	c.expect(pool.Id, OpShmPoolCreateBuffer, &first)
+	if first.Width != 4 || first.Height != 2 || first.Stride != 16 || first.Format != ShmFormatArgb8888 {
+		t.Errorf("created buffer %+v", first)
+	}
+
+	// The pixels are in the memory passed to the compositor.
+	mem, err := syscall.Mmap(int(pool.Fd), 0, int(pool.Size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Munmap(mem)
+	px := mem[int(first.Offset)+16+4:]
+	if px[0] != 0x33 || px[1] != 0x22 || px[2] != 0x11 || px[3] != 0xff {
+		t.Errorf("pixel stored as % x", px[:4])
+	}
+
+	err = f.Commit(image.Rect(1, 1, 2, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attach SurfaceAttach
+	c.expect(w.SurfaceId, OpSurfaceAttach, &attach)
+	if attach.Buffer != first.Id {
+		t.Errorf("attached buffer %d, want %d", attach.Buffer, first.Id)
+	}
+	var damage SurfaceDamage
+	c.expect(w.SurfaceId, OpSurfaceDamage, &damage)
+	if damage != (SurfaceDamage{1, 1, 1, 1}) {
+		t.Errorf("damaged %+v", damage)
+	}
+	c.expect(w.SurfaceId, OpSurfaceCommit, nil)
+
+	// The first buffer is in use, so a second one is allocated next to it.
+	f, err = w.NextFrame(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second ShmPoolCreateBuffer
+	c.expect(pool.Id, OpShmPoolCreateBuffer, &second)
+	if second.Offset != first.Offset+32 {
+		t.Errorf("second buffer at offset %d", second.Offset)
+	}
+	err = f.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.expect(w.SurfaceId, OpSurfaceAttach, nil)
+	c.expect(w.SurfaceId, OpSurfaceDamage, nil)
+	c.expect(w.SurfaceId, OpSurfaceCommit, nil)
+
+	// Both buffers are in use: the next frame waits for one to be
+	// released, and keeps other events for NextEvent.
+	c.send(99, 0, CallbackDone{})
+	c.send(first.Id, OpBufferRelease, BufferRelease{})
+	f, err = w.NextFrame(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.buffer.proxy.Id != first.Id {
+		t.Errorf("reused buffer %d, want %d", f.buffer.proxy.Id, first.Id)
+	}
+	if len(b.pending) != 1 || b.pending[0].(Message).ObjectId != 99 {
+		t.Errorf("pending events %v", b.pending)
+	}
+
+	// Once released, buffers of an older size are destroyed.
+	c.send(second.Id, OpBufferRelease, BufferRelease{})
+	f.Commit()
+	c.expect(w.SurfaceId, OpSurfaceAttach, nil)
+	c.expect(w.SurfaceId, OpSurfaceDamage, nil)
+	c.expect(w.SurfaceId, OpSurfaceCommit, nil)
+	b.Dispatch()
+
+	_, err = w.NextFrame(16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.expect(second.Id, OpBufferDestroy, nil)
+	var resize ShmPoolResize
+	c.expect(pool.Id, OpShmPoolResize, &resize)
+	if resize.Size < 32+16*16*4 {
+		t.Errorf("pool resized to %d bytes", resize.Size)
+	}
+	var third ShmPoolCreateBuffer
+	c.expect(pool.Id, OpShmPoolCreateBuffer, &third)
+	if third.Offset < first.Offset+32 && third.Offset+16*16*4 > first.Offset {
+		t.Errorf("buffer at offset %d overlaps the busy one", third.Offset)
+	}
+}
+
+func TestARGB(t *testing.T) {
+	img := &ARGB{
+		Pix:    make([]byte, 3*2*4),
+		Stride: 12,
+		Rect:   image.Rect(1, 1, 4, 3),
+	}
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	src.SetRGBA(2, 2, color.RGBA{1, 2, 3, 4})
+	img.CopyRGBA(src, src.Rect)
+
+	if c := img.RGBAAt(2, 2); c != (color.RGBA{1, 2, 3, 4}) {
+		t.Errorf("copied pixel %v", c)
+	}
+	if i := img.PixOffset(2, 2); img.Pix[i] != 3 || img.Pix[i+2] != 1 {
+		t.Errorf("pixel stored as % x", img.Pix[i:i+4])
+	}
+
+	sub := img.SubImage(image.Rect(2, 2, 3, 3)).(*ARGB)
+	if c := sub.RGBAAt(2, 2); c != (color.RGBA{1, 2, 3, 4}) {
+		t.Errorf("sub-image pixel %v", c)
+	}
+}
@@ -0,0 +1,117 @@
+package wayland
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// requestNames and eventNames are the names of the messages of each
+// interface, by opcode, as registered by the generated code.
+var (
+	requestNames = make(map[string][]string)
+	eventNames   = make(map[string][]string)
+)
+
+var (
+	fixedType    = reflect.TypeOf(Fixed(0))
+	objectIdType = reflect.TypeOf(ObjectId(0))
+)
+
+// debugEnv returns whether the WAYLAND_DEBUG environment variable asks for
+// the messages of clients to be traced, as it does for libwayland.
+func debugEnv() bool {
+	switch os.Getenv("WAYLAND_DEBUG") {
+	case "1", "client":
+		return true
+	}
+	return false
+}
+
+// traceRequest writes a request sent to an object to b.Debug.
+func (b *Backend) traceRequest(id ObjectId, opcode Opcode, data interface{}) {
+	b.trace("-> ", id, opcode, requestNames, formatArgs(b, reflect.ValueOf(data)))
+}
+
+// traceEvent writes an event to b.Debug. Events that could not be decoded
+// are passed as their Message, and written with the size of their payload.
+func (b *Backend) traceEvent(id ObjectId, opcode Opcode, ev interface{}) {
+	var args string
+	if msg, ok := ev.(Message); ok {
+		args = fmt.Sprintf("%d bytes", len(msg.Payload))
+	} else {
+		args = formatArgs(b, reflect.ValueOf(ev))
+	}
+	b.trace("", id, opcode, eventNames, args)
+}
+
+// trace writes a message in the format of libwayland, such as
+//
+//	[1234567.890] -> wl_surface@3.attach(wl_buffer@7, 0, 0)
+//
+// where requests are marked with an arrow, and the time is in milliseconds.
+func (b *Backend) trace(arrow string, id ObjectId, opcode Opcode, names map[string][]string, args string) {
+	name := fmt.Sprint(opcode)
+	if p := b.objects[id]; p != nil && int(opcode) < len(names[p.Interface]) {
+		name = names[p.Interface][opcode]
+	}
+	us := time.Now().UnixNano() / 1000
+	fmt.Fprintf(b.Debug, "[%7d.%03d] %s%s.%s(%s)\n", uint32(us/1000), us%1000, arrow, b.objectName(id), name, args)
+}
+
+// objectName returns an object as its interface and id, such as
+// wl_surface@3.
+func (b *Backend) objectName(id ObjectId) string {
+	if p := b.objects[id]; p != nil {
+		return p.String()
+	}
+	return fmt.Sprintf("[unknown]@%d", id)
+}
+
+// formatArgs formats the arguments of a message like libwayland does.
+func formatArgs(b *Backend, v reflect.Value) string {
+	args := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Type() == fdType:
+			args = append(args, fmt.Sprintf("fd %d", field.Int()))
+		case field.Type() == fixedType:
+			args = append(args, fmt.Sprintf("%f", Fixed(field.Int()).Float()))
+		case field.Type() == objectIdType:
+			id := ObjectId(field.Uint())
+			switch {
+			case v.Type().Field(i).Tag.Get("wayland") == "new_id":
+				args = append(args, "new id "+b.objectName(id))
+			case id == 0:
+				args = append(args, "nil")
+			default:
+				args = append(args, b.objectName(id))
+			}
+		case field.Type() == newIdType:
+			id := field.Interface().(NewId)
+			args = append(args, fmt.Sprintf("\"%s\", %d, new id %s", id.Interface, id.Version, b.objectName(id.Id)))
+		case field.Type() == nullStringType:
+			s := field.Interface().(NullString)
+			if !s.Valid {
+				args = append(args, "nil")
+				break
+			}
+			args = append(args, "\""+s.String+"\"")
+		case field.Kind() == reflect.String:
+			args = append(args, "\""+field.String()+"\"")
+		case field.Kind() == reflect.Uint32:
+			args = append(args, fmt.Sprint(field.Uint()))
+		case field.Kind() == reflect.Int32:
+			args = append(args, fmt.Sprint(field.Int()))
+		case field.Kind() == reflect.Slice:
+			size := field.Len() * int(field.Type().Elem().Size())
+			args = append(args, fmt.Sprintf("array[%d]", size))
+		default:
+			args = append(args, "?")
+		}
+	}
+	return strings.Join(args, ", ")
+}
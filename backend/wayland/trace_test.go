@@ -0,0 +1,70 @@
+package wayland
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+	var out bytes.Buffer
+	b.Debug = &out
+
+	surface := b.NewProxy(SurfaceInterface, compositorVersion)
+	err := b.request(b.CompositorId, OpCompositorCreateSurface, CompositorCreateSurface{Id: surface.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := b.NewProxy(BufferInterface, 1)
+	surface.Request(OpSurfaceAttach, SurfaceAttach{Buffer: buffer.Id, X: -1})
+	surface.Request(OpSurfaceAttach, SurfaceAttach{})
+	b.global(RegistryGlobal{Name: 7, Interface: "wl_seat", Version: 5})
+	_, err = b.Bind(Global{Name: 7, Interface: "wl_seat", Version: 5}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.send(b.ShmId, OpShmFormatEvent, ShmFormatEvent{Format: ShmFormatXrgb8888})
+	c.send(99, 0, CallbackDone{CallbackData: 1})
+	b.Dispatch()
+
+	// The timestamps are milliseconds, with the microseconds.
+	got := out.String()
+	stamp := regexp.MustCompile(`(?m)^\[ *\d+\.\d{3}\] `)
+	if n := len(stamp.FindAllString(got, -1)); n != 6 {
+		t.Errorf("%d timestamps in\n%s", n, got)
+	}
+	got = stamp.ReplaceAllString(got, "")
+	want := strings.Join([]string{
+		"-> wl_compositor@3.create_surface(new id wl_surface@11)",
+		"-> wl_surface@11.attach(wl_buffer@12, -1, 0)",
+		"-> wl_surface@11.attach(nil, 0, 0)",
+		`-> wl_registry@2.bind(7, "wl_seat", 5, new id wl_seat@13)`,
+		"wl_shm@4.format(1)",
+		"[unknown]@99.0(4 bytes)",
+		"",
+	}, "\n")
+	if got != want {
+		t.Errorf("traced\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatArgs(t *testing.T) {
+	b, _ := newFakeCompositor(t)
+	defer b.Close()
+
+	got := formatArgs(b, reflect.ValueOf(struct {
+		X     Fixed
+		Title NullString
+		Null  NullString
+		Keys  []uint32
+		Fd    Fd
+	}{FixedFromFloat(1.5), NullString{String: "a", Valid: true}, NullString{}, []uint32{1, 2}, 5}))
+	want := `1.500000, "a", nil, array[8], fd 5`
+	if got != want {
+		t.Errorf("formatted %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,265 @@
+package wayland
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+// WindowState is the set of states of a toplevel window, as sent by the
+// compositor with each configuration.
+type WindowState uint16
+
+const (
+	StateMaximized WindowState = 1 << iota
+	StateFullscreen
+	StateResizing
+	StateActivated
+	StateTiledLeft
+	StateTiledRight
+	StateTiledTop
+	StateTiledBottom
+)
+
+// windowStates are the flags of the xdg_toplevel states. Those of newer
+// versions than the one bound are not sent.
+var windowStates = map[XdgToplevelState]WindowState{
+	XdgToplevelStateMaximized:   StateMaximized,
+	XdgToplevelStateFullscreen:  StateFullscreen,
+	XdgToplevelStateResizing:    StateResizing,
+	XdgToplevelStateActivated:   StateActivated,
+	XdgToplevelStateTiledLeft:   StateTiledLeft,
+	XdgToplevelStateTiledRight:  StateTiledRight,
+	XdgToplevelStateTiledTop:    StateTiledTop,
+	XdgToplevelStateTiledBottom: StateTiledBottom,
+}
+
+// ConfigureEvent is reported when the compositor sets the size or the state
+// of a window, and once when it is opened. The next frame should be drawn at
+// the new size.
+type ConfigureEvent struct {
+	Window *Window
+	Width  int
+	Height int
+	State  WindowState
+}
+
+// CloseEvent is reported when the user asks to close a window, for example
+// with its close button. The window stays open until closed with Close.
+type CloseEvent struct {
+	Window *Window
+}
+
+// Window is a toplevel window, a surface with the xdg_toplevel role.
+type Window struct {
+	b            *Backend
+	SurfaceId    ObjectId
+	XdgSurfaceId ObjectId
+	ToplevelId   ObjectId
+	Width        int
+	Height       int
+	State        WindowState
+
+	surface    *Proxy
+	xdgSurface *Proxy
+	toplevel   *Proxy
+
+	// configure is the last xdg_toplevel.configure, applied with the next
+	// xdg_surface.configure.
+	configure  XdgToplevelConfigure
+	configured bool
+}
+
+// OpenWindow opens a toplevel window, which is shown once a frame is
+// presented. It waits for the initial configuration of the compositor,
+// which is also reported with a ConfigureEvent.
+func (b *Backend) OpenWindow(title string, width, height int) (w *Window, err error) {
+	if b.WmBaseId == 0 {
+		return nil, fmt.Errorf("opening window: xdg_wm_base not supported by the compositor")
+	}
+
+	compositor := b.Object(b.CompositorId)
+	wmBase := b.Object(b.WmBaseId)
+	surface := b.NewProxy(SurfaceInterface, compositor.Version)
+	xdgSurface := b.NewProxy(XdgSurfaceInterface, wmBase.Version)
+	toplevel := b.NewProxy(XdgToplevelInterface, wmBase.Version)
+	w = &Window{
+		b:            b,
+		SurfaceId:    surface.Id,
+		XdgSurfaceId: xdgSurface.Id,
+		ToplevelId:   toplevel.Id,
+		Width:        width,
+		Height:       height,
+		surface:      surface,
+		xdgSurface:   xdgSurface,
+		toplevel:     toplevel,
+	}
+
+	w.toplevel.Handle(OpXdgToplevelConfigure, func(ev XdgToplevelConfigure) {
+		w.configure = ev
+	})
+	w.toplevel.Handle(OpXdgToplevelClose, func(XdgToplevelClose) {
+		b.pending = append(b.pending, CloseEvent{Window: w})
+	})
+	w.xdgSurface.Handle(OpXdgSurfaceConfigure, w.surfaceConfigure)
+
+	err = compositor.Request(OpCompositorCreateSurface, CompositorCreateSurface{
+		Id: w.SurfaceId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+	err = wmBase.Request(OpXdgWmBaseGetXdgSurface, XdgWmBaseGetXdgSurface{
+		Id:      w.XdgSurfaceId,
+		Surface: w.SurfaceId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+	err = w.xdgSurface.Request(OpXdgSurfaceGetToplevel, XdgSurfaceGetToplevel{
+		Id: w.ToplevelId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+
+	err = w.SetTitle(title)
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+
+	// Committing without a buffer asks for the initial configuration,
+	// which has to be acknowledged before a buffer is attached.
+	err = w.surface.Request(OpSurfaceCommit, SurfaceCommit{})
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+	for !w.configured {
+		err = b.Dispatch()
+		if err != nil {
+			return nil, fmt.Errorf("opening window: %w", err)
+		}
+	}
+
+	return w, nil
+}
+
+// SetTitle sets the window title shown by the compositor.
+func (w *Window) SetTitle(title string) error {
+	return w.request(OpXdgToplevelSetTitle, XdgToplevelSetTitle{Title: title})
+}
+
+// SetAppId sets the identifier of the application, which compositors use to
+// group its windows and find its desktop entry. It should be the name of the
+// desktop file, such as "org.example.Editor".
+func (w *Window) SetAppId(id string) error {
+	return w.request(OpXdgToplevelSetAppId, XdgToplevelSetAppId{AppId: id})
+}
+
+// SetMinSize sets the minimum size of the window. Zero means no minimum.
+func (w *Window) SetMinSize(width, height int) error {
+	return w.request(OpXdgToplevelSetMinSize, XdgToplevelSetMinSize{
+		Width:  int32(width),
+		Height: int32(height),
+	})
+}
+
+// SetMaxSize sets the maximum size of the window. Zero means no maximum.
+func (w *Window) SetMaxSize(width, height int) error {
+	return w.request(OpXdgToplevelSetMaxSize, XdgToplevelSetMaxSize{
+		Width:  int32(width),
+		Height: int32(height),
+	})
+}
+
+// SetMaximized asks the compositor to maximize or restore the window. The
+// change is reported with a ConfigureEvent, if the compositor agrees.
+func (w *Window) SetMaximized(maximized bool) error {
+	if maximized {
+		return w.request(OpXdgToplevelSetMaximized, XdgToplevelSetMaximized{})
+	}
+	return w.request(OpXdgToplevelUnsetMaximized, XdgToplevelUnsetMaximized{})
+}
+
+// SetFullscreen asks the compositor to show the window fullscreen, on the
+// output of its choice, or to restore it.
+func (w *Window) SetFullscreen(fullscreen bool) error {
+	if fullscreen {
+		return w.request(OpXdgToplevelSetFullscreen, XdgToplevelSetFullscreen{})
+	}
+	return w.request(OpXdgToplevelUnsetFullscreen, XdgToplevelUnsetFullscreen{})
+}
+
+// Minimize asks the compositor to minimize the window. Wayland does not
+// tell when the window is restored.
+func (w *Window) Minimize() error {
+	return w.request(OpXdgToplevelSetMinimized, XdgToplevelSetMinimized{})
+}
+
+func (w *Window) request(opcode Opcode, data interface{}) error {
+	return w.toplevel.Request(opcode, data)
+}
+
+// NextFrame returns a buffer to draw the next frame of the window into. It
+// is usually of the size of the window.
+func (w *Window) NextFrame(width, height int) (f *Frame, err error) {
+	return w.b.nextFrame(w.SurfaceId, width, height)
+}
+
+// Present draws img on the window, with its origin at the top left corner.
+func (w *Window) Present(img *image.RGBA) error {
+	r := img.Bounds()
+	f, err := w.NextFrame(r.Dx(), r.Dy())
+	if err != nil {
+		return fmt.Errorf("presenting frame: %w", err)
+	}
+
+	src := &image.RGBA{
+		Pix:    img.Pix,
+		Stride: img.Stride,
+		Rect:   r.Sub(r.Min),
+	}
+	f.CopyRGBA(src, f.Rect)
+	return f.Commit()
+}
+
+// Close destroys the window.
+func (w *Window) Close() {
+	w.b.destroyFrames(w.SurfaceId)
+
+	w.toplevel.Request(OpXdgToplevelDestroy, XdgToplevelDestroy{})
+	w.xdgSurface.Request(OpXdgSurfaceDestroy, XdgSurfaceDestroy{})
+	w.surface.Request(OpSurfaceDestroy, SurfaceDestroy{})
+}
+
+// surfaceConfigure applies the configuration sent with the last
+// xdg_toplevel.configure, which the xdg_surface.configure event ends.
+func (w *Window) surfaceConfigure(ev XdgSurfaceConfigure) {
+	w.applyConfigure()
+	w.xdgSurface.Request(OpXdgSurfaceAckConfigure, XdgSurfaceAckConfigure{
+		Serial: ev.Serial,
+	})
+	w.b.pending = append(w.b.pending, ConfigureEvent{
+		Window: w,
+		Width:  w.Width,
+		Height: w.Height,
+		State:  w.State,
+	})
+}
+
+// applyConfigure applies the last toplevel configuration. A size of zero
+// leaves the choice to the client, which keeps its current size.
+func (w *Window) applyConfigure() {
+	c := w.configure
+	if c.Width > 0 && c.Height > 0 {
+		w.Width = int(c.Width)
+		w.Height = int(c.Height)
+	}
+
+	w.State = 0
+	for i := 0; i+4 <= len(c.States); i += 4 {
+		s := XdgToplevelState(binary.LittleEndian.Uint32(c.States[i:]))
+		w.State |= windowStates[s]
+	}
+	w.configured = true
+}
@@ -0,0 +1,88 @@
+package wayland
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestOpenWindow(t *testing.T) {
+	b, c := newFakeCompositor(t)
+	defer b.Close()
+
+	// The ids of the surface, xdg_surface and xdg_toplevel of the window.
+	surface, xdgSurface, toplevel := ObjectId(11), ObjectId(12), ObjectId(13)
+
+	// The compositor answers the initial commit in advance.
+	states := make([]byte, 8)
+	binary.LittleEndian.PutUint32(states[0:], 4) // activated
+	binary.LittleEndian.PutUint32(states[4:], 5) // tiled_left
+	c.send(b.WmBaseId, OpXdgWmBasePing, XdgWmBasePing{Serial: 42})
+	c.send(toplevel, OpXdgToplevelConfigure, XdgToplevelConfigure{Width: 800, Height: 600, States: states})
+	c.send(xdgSurface, OpXdgSurfaceConfigure, XdgSurfaceConfigure{Serial: 7})
+
+	w, err := b.OpenWindow("test", 640, 480)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Width != 800 || w.Height != 600 || w.State != StateActivated|StateTiledLeft {
+		t.Errorf("window configured as %dx%d with state %b", w.Width, w.Height, w.State)
+	}
+
+	var getSurface XdgWmBaseGetXdgSurface
+	c.expect(b.CompositorId, OpCompositorCreateSurface, nil)
+	c.expect(b.WmBaseId, OpXdgWmBaseGetXdgSurface, &getSurface)
+	if getSurface.Id != xdgSurface || getSurface.Surface != surface {
+		t.Errorf("got xdg_surface %+v", getSurface)
+	}
+	c.expect(xdgSurface, OpXdgSurfaceGetToplevel, nil)
+	var title XdgToplevelSetTitle
+	c.expect(toplevel, OpXdgToplevelSetTitle, &title)
+	if title.Title != "test" {
+		t.Errorf("set title %q", title.Title)
+	}
+	c.expect(surface, OpSurfaceCommit, nil)
+	var pong XdgWmBasePong
+	c.expect(b.WmBaseId, OpXdgWmBasePong, &pong)
+	if pong.Serial != 42 {
+		t.Errorf("answered ping %d with pong %d", 42, pong.Serial)
+	}
+	var ack XdgSurfaceAckConfigure
+	c.expect(xdgSurface, OpXdgSurfaceAckConfigure, &ack)
+	if ack.Serial != 7 {
+		t.Errorf("acknowledged configure %d, want 7", ack.Serial)
+	}
+
+	ev, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev != (ConfigureEvent{Window: w, Width: 800, Height: 600, State: StateActivated | StateTiledLeft}) {
+		t.Errorf("got event %+v", ev)
+	}
+
+	// A configuration without size keeps the current one.
+	c.send(toplevel, OpXdgToplevelConfigure, XdgToplevelConfigure{})
+	c.send(xdgSurface, OpXdgSurfaceConfigure, XdgSurfaceConfigure{Serial: 8})
+	c.send(toplevel, OpXdgToplevelClose, XdgToplevelClose{})
+
+	ev, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev != (ConfigureEvent{Window: w, Width: 800, Height: 600}) {
+		t.Errorf("got event %+v", ev)
+	}
+	ev, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev != (CloseEvent{Window: w}) {
+		t.Errorf("got event %+v, want close", ev)
+	}
+
+	w.Close()
+	c.expect(xdgSurface, OpXdgSurfaceAckConfigure, nil)
+	c.expect(toplevel, OpXdgToplevelDestroy, nil)
+	c.expect(xdgSurface, OpXdgSurfaceDestroy, nil)
+	c.expect(surface, OpSurfaceDestroy, nil)
+}
@@ -1,42 +1,111 @@
 package x
 
 import (
+	"bufio"
+	"context"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	ErrInit           = errors.New("initializing X connection")
-	ErrNotImplemented = errors.New("Not implemented")
+	ErrInit             = errors.New("initializing X connection")
+	ErrNotImplemented   = errors.New("Not implemented")
+	ErrMissingExtension = errors.New("extension not supported by the X server")
+	ErrDisconnected     = errors.New("disconnected from the X server")
 )
 
+// DisconnectedEvent is the last event reported, once the connection to the
+// server is lost. Windows and other resources are gone with the
+// connection, and every later call fails with ErrDisconnected.
+type DisconnectedEvent struct {
+	// Cause is the error that broke the connection, such as io.EOF when
+	// the server exits.
+	Cause error
+}
+
 type Backend struct {
 	conn         net.Conn
+	r            *bufio.Reader
+	w            *bufio.Writer
 	byteOrder    binary.ByteOrder
 	bytesWritten int
-	bytesRead    int
 	err          error
 
 	initResponse InitResponse
 	nextId       Card32
+	screen       int
+	sequence     Card16
+
+	events            [][]byte
+	eventTypes        map[Card8]reflect.Type
+	genericEventTypes map[genericEventKey]reflect.Type
+	atoms             map[string]Atom
+	extensions        map[string]extension
+	windows           map[WindowId]*Window
+
+	cursorFont  Font
+	cursors     map[CursorShape]Cursor
+	pictFormats []PictFormatInfo
+	keymap      *keymap
+	hotkeys     []*Hotkey
+	trayIcons   map[WindowId]*TrayIcon
+	popups      map[WindowId]*Popup
+	// popupGrabs are the open popups grabbing input, the last one holding
+	// the grabs.
+	popupGrabs []*Popup
+
+	// rootEventMask is the union of the events selected on the root
+	// window, which replace the previous selection of the client.
+	rootEventMask Card32
+
+	// disconnected is the cause of the loss of the connection, which is
+	// reported once with DisconnectedEvent.
+	disconnected error
+	reported     bool
 }
 
-type Window struct{}
+// Init connects to the X server named by the DISPLAY environment variable.
+func (b *Backend) Init() error {
+	return b.InitContext(context.Background())
+}
 
-func (b *Backend) Init() (err error) {
-	b.conn, err = connect()
+// InitContext is like Init, but gives up connecting when ctx is done, for
+// example if the server accepts the connection but never answers.
+func (b *Backend) InitContext(ctx context.Context) (err error) {
+	b.conn, b.screen, err = connect(ctx)
 	if err != nil {
 		return fmt.Errorf("initializing backend connection: %w", err)
 	}
 
+	err = b.setup(ctx)
+	if err != nil {
+		b.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// setup performs the connection handshake.
+func (b *Backend) setup(ctx context.Context) (err error) {
+	b.r = bufio.NewReader(b.conn)
+	b.w = bufio.NewWriter(b.conn)
 	b.byteOrder = binary.BigEndian
+	b.atoms = make(map[string]Atom)
+	b.extensions = make(map[string]extension)
+	b.windows = make(map[WindowId]*Window)
+	b.trayIcons = make(map[WindowId]*TrayIcon)
+	b.popups = make(map[WindowId]*Popup)
+
+	stop := watchContext(ctx, b.conn)
+	defer stop()
 
 	b.write(Card8('B')) // Big Endian
 	b.writeUnused(1)
@@ -47,40 +116,86 @@ func (b *Backend) Init() (err error) {
 	b.write(Card16(0)) // Auth data length
 	b.writeUnused(2)   // Padding
 
+	b.flush()
 	if b.err != nil {
-		return fmt.Errorf("sending init request: %w", b.err)
+		return fmt.Errorf("sending init request: %w", contextError(ctx, b.err))
 	}
 
-	var success Card8
-	b.read(&success)
-
-	if b.err != nil {
-		return fmt.Errorf("reading init response: %w", b.err)
+	var header [8]byte
+	_, err = io.ReadFull(b.r, header[:])
+	if err != nil {
+		return fmt.Errorf("reading init response: %w", contextError(ctx, err))
 	}
 
+	success := Card8(header[0])
 	if success != 1 {
 		return fmt.Errorf("init response %d: %w", success, ErrNotImplemented)
 	}
 
-	//b.readInitResponse(&b.initResponse)
-	b.unmarshall(&b.initResponse)
-	if b.err != nil {
-		return fmt.Errorf("reading init response: %w", b.err)
+	length := int(b.byteOrder.Uint16(header[6:8])) * 4
+	buf := make([]byte, len(header)+length)
+	copy(buf, header[:])
+	_, err = io.ReadFull(b.r, buf[len(header):])
+	if err != nil {
+		return fmt.Errorf("reading init response: %w", contextError(ctx, err))
 	}
 
-	pprint(b.initResponse)
+	b.initResponse, err = decodeSetup(buf, b.byteOrder)
+	if err != nil {
+		return fmt.Errorf("reading init response: %w", err)
+	}
+	if b.screen >= len(b.initResponse.Roots) {
+		return fmt.Errorf("screen %d out of range: %w", b.screen, ErrInit)
+	}
 
 	return nil
 }
 
+// decodeSetup decodes the reply to the connection setup, which starts with
+// the status byte.
+func decodeSetup(buf []byte, byteOrder binary.ByteOrder) (r InitResponse, err error) {
+	var success Card8
+	d := newDecoder(buf, byteOrder)
+	d.read(&success)
+	d.unmarshall(&r)
+	if d.err != nil {
+		return r, d.err
+	}
+	if len(r.Roots) == 0 {
+		return r, fmt.Errorf("no screens: %w", ErrMalformed)
+	}
+	return r, nil
+}
+
+// Close sends pending requests and closes the connection, which destroys
+// all windows and other resources of the backend.
 func (b *Backend) Close() {
+	b.flush()
+	if b.err == nil {
+		b.err = fmt.Errorf("backend closed: %w", ErrDisconnected)
+	}
 	b.conn.Close()
 }
 
-func (b *Backend) OpenWindow(title string, width, height int) (w *Window, err error) {
-	w = new(Window)
+// disconnect records that the connection was lost because of cause and
+// releases the state kept for server resources, which no longer exist. It
+// returns the error returned by later calls.
+func (b *Backend) disconnect(cause error) error {
+	if b.disconnected != nil || b.err != nil {
+		return b.err
+	}
+
+	b.disconnected = cause
+	b.err = fmt.Errorf("%w: %v", ErrDisconnected, cause)
+	b.conn.Close()
 
-	return w, nil
+	b.windows = make(map[WindowId]*Window)
+	b.trayIcons = make(map[WindowId]*TrayIcon)
+	b.popups = make(map[WindowId]*Popup)
+	b.hotkeys = nil
+	b.keymap = nil
+	b.cursors = nil
+	return b.err
 }
 
 func (b *Backend) allocId() (n Card32) {
@@ -89,20 +204,20 @@ func (b *Backend) allocId() (n Card32) {
 	return
 }
 
-func connect() (conn net.Conn, err error) {
-	host, display, _, err := parseDisplay(os.Getenv("DISPLAY"))
+func connect(ctx context.Context) (conn net.Conn, screen int, err error) {
+	host, display, screen, err := parseDisplay(os.Getenv("DISPLAY"))
 	if err != nil {
 		err = fmt.Errorf("parsing `DISPLAY` environment variable: %w", err)
 		return
 	}
 
-	if host == "" || host == "host/unix" {
+	var d net.Dialer
+	if host == "" || host == "unix" || host == "host/unix" {
 		path := fmt.Sprintf("/tmp/.X11-unix/X%d", display)
-		conn, err = net.Dial("unix", path)
+		conn, err = d.DialContext(ctx, "unix", path)
 	} else {
 		port := 6000 + display
-		path := fmt.Sprintf("host:%d", port)
-		conn, err = net.Dial("tcp", path)
+		conn, err = d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
 	}
 
 	if err != nil {
@@ -113,6 +228,36 @@ func connect() (conn net.Conn, err error) {
 	return
 }
 
+// watchContext makes blocking I/O on conn fail once ctx is done, until the
+// returned function is called.
+func watchContext(ctx context.Context, conn net.Conn) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		conn.SetDeadline(time.Time{})
+	}
+}
+
+// contextError returns the error of ctx if it is done, as I/O errors are
+// then caused by watchContext.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
 func parseDisplay(spec string) (host string, display, screen int, err error) {
 	host, spec, ok := strings.Cut(spec, ":")
 	if !ok {
@@ -145,11 +290,3 @@ func parseDisplay(spec string) (host string, display, screen int, err error) {
 
 	return
 }
-
-func pprint(v interface{}) {
-	j, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		log.Fatal(err)
-	}
-	fmt.Println(string(j))
-}
@@ -1,7 +1,12 @@
 package x
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestParseDisplay(t *testing.T) {
@@ -27,3 +32,63 @@ func TestParseDisplay(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupContext(t *testing.T) {
+	for _, cancel := range []bool{false, true} {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		// The server reads the setup request but never answers.
+		go io.Copy(io.Discard, server)
+
+		ctx, stop := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		want := context.DeadlineExceeded
+		if cancel {
+			ctx, stop = context.WithCancel(context.Background())
+			time.AfterFunc(10*time.Millisecond, stop)
+			want = context.Canceled
+		}
+
+		b := Backend{conn: client}
+		err := b.setup(ctx)
+		stop()
+		if !errors.Is(err, want) {
+			t.Errorf("setup with cancel %v returned %v, want %v", cancel, err, want)
+		}
+	}
+}
+
+func TestDisconnected(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		return extensionReply(req)
+	})
+
+	_, err := b.OpenWindow("lost", 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.atom("X_TEST_BARRIER")
+	s.conn.Close()
+
+	ev, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	disconnected, ok := ev.(DisconnectedEvent)
+	if !ok || !errors.Is(disconnected.Cause, io.EOF) {
+		t.Fatalf("got %#v, want DisconnectedEvent caused by EOF", ev)
+	}
+	if len(b.windows) != 0 {
+		t.Errorf("%d windows left after disconnection", len(b.windows))
+	}
+
+	_, err = b.NextEvent()
+	if !errors.Is(err, ErrDisconnected) {
+		t.Errorf("NextEvent after disconnection returned %v", err)
+	}
+	_, err = b.atom("AFTER_DISCONNECTION")
+	if !errors.Is(err, ErrDisconnected) {
+		t.Errorf("request after disconnection returned %v", err)
+	}
+}
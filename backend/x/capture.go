@@ -0,0 +1,155 @@
+package x
+
+import (
+	"fmt"
+	"image"
+)
+
+// captureTileSize bounds the size of the image data transferred by each
+// GetImage request of a capture.
+const captureTileSize = 4 << 20
+
+type getImageReply struct {
+	Header replyHeader
+	Visual VisualId
+}
+
+type shmGetImageReply struct {
+	Header replyHeader
+	Visual VisualId
+	Size   Card32
+}
+
+// Capture returns the contents of the rectangle r of a window or pixmap.
+// Windows must be mapped, and parts of them that are obscured or off
+// screen have undefined contents. Large captures are transferred in tiles
+// of scanlines, through shared memory when the server supports it.
+func (b *Backend) Capture(d Drawable, r image.Rectangle) (img *image.RGBA, err error) {
+	r = r.Canon()
+	img = image.NewRGBA(r)
+	if r.Empty() {
+		return img, nil
+	}
+
+	// The format is only known once the first tile arrives, so it is
+	// fetched alone to size the following ones.
+	pf, err := b.captureTile(d, img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), nil)
+	if err != nil {
+		return nil, fmt.Errorf("capturing image: %w", err)
+	}
+
+	stride := pf.stride(r.Dx())
+	rows := captureTileSize / stride
+	if rows < 1 {
+		rows = 1
+	}
+
+	shm, err := b.attachShm(rows * stride)
+	if err == nil {
+		defer b.detachShm(shm)
+	}
+
+	for y := r.Min.Y + 1; y < r.Max.Y; y += rows {
+		tile := image.Rect(r.Min.X, y, r.Max.X, y+rows).Intersect(r)
+		_, err = b.captureTile(d, img, tile, shm)
+		if err != nil {
+			return nil, fmt.Errorf("capturing image: %w", err)
+		}
+	}
+
+	return img, nil
+}
+
+// Capture returns the contents of the window.
+func (w *Window) Capture() (*image.RGBA, error) {
+	return w.b.Capture(Drawable(w.Id), image.Rect(0, 0, w.Width, w.Height))
+}
+
+// CaptureScreen returns the contents of the whole screen.
+func (b *Backend) CaptureScreen() (*image.RGBA, error) {
+	screen := b.rootScreen()
+	r := image.Rect(0, 0, int(screen.WidthInPixels), int(screen.HeightInPixels))
+	return b.Capture(Drawable(screen.Root), r)
+}
+
+// captureTile reads the rectangle r of the drawable into the same
+// rectangle of img, through shm if it is not nil.
+func (b *Backend) captureTile(d Drawable, img *image.RGBA, r image.Rectangle, shm *shmSegment) (pf pixelFormat, err error) {
+	var depth Card8
+	var visual VisualId
+	var data []byte
+
+	if shm != nil {
+		seq := b.beginExtensionRequest(b.extensions["MIT-SHM"], shmGetImage, 32)
+		b.write(d)
+		b.write(Int16(r.Min.X))
+		b.write(Int16(r.Min.Y))
+		b.write(Card16(r.Dx()))
+		b.write(Card16(r.Dy()))
+		b.write(Card32(0xffffffff)) // Plane mask
+		b.write(ZPixmap)
+		b.writeUnused(3)
+		b.write(shm.seg)
+		b.write(Card32(0)) // Offset
+
+		buf, err := b.waitReply(seq)
+		if err != nil {
+			return pf, err
+		}
+		var reply shmGetImageReply
+		err = b.decode(buf, &reply)
+		if err != nil {
+			return pf, err
+		}
+		if int(reply.Size) > len(shm.data) {
+			return pf, fmt.Errorf("image of %d bytes overflows shared memory segment", reply.Size)
+		}
+		depth, visual, data = reply.Header.Data, reply.Visual, shm.data[:reply.Size]
+	} else {
+		seq := b.beginRequest(OpGetImage, Card8(ZPixmap), 20)
+		b.write(d)
+		b.write(Int16(r.Min.X))
+		b.write(Int16(r.Min.Y))
+		b.write(Card16(r.Dx()))
+		b.write(Card16(r.Dy()))
+		b.write(Card32(0xffffffff)) // Plane mask
+
+		buf, err := b.waitReply(seq)
+		if err != nil {
+			return pf, err
+		}
+		var reply getImageReply
+		err = b.decode(buf, &reply)
+		if err != nil {
+			return pf, err
+		}
+		depth, visual, data = reply.Header.Data, reply.Visual, buf[32:]
+	}
+
+	pf, err = b.drawableFormat(depth, visual)
+	if err != nil {
+		return pf, err
+	}
+	if len(data) < pf.stride(r.Dx())*r.Dy() {
+		return pf, fmt.Errorf("image data of %d bytes is too short for %v", len(data), r)
+	}
+
+	pf.decode(img, r, data)
+	return pf, nil
+}
+
+// drawableFormat returns the image format of a drawable from the depth and
+// visual reported by GetImage. Pixmaps have no visual, so the one of the
+// root window is assumed for them, or ARGB for depth 32.
+func (b *Backend) drawableFormat(depth Card8, visual VisualId) (pf pixelFormat, err error) {
+	screen := b.rootScreen()
+	switch {
+	case visual != 0:
+		return b.pixelFormat(depth, visual)
+	case depth == screen.RootDepth:
+		return b.pixelFormat(depth, screen.RootVisual)
+	case depth == 32:
+		return b.argbFormat()
+	}
+	return pf, fmt.Errorf("depth %d without visual: %w", depth, ErrNotImplemented)
+}
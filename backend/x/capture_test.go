@@ -0,0 +1,57 @@
+package x
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	pixel := func(x, y int) color.RGBA {
+		return color.RGBA{uint8(x), uint8(y), uint8(x + y), 0xff}
+	}
+
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode == OpGetImage {
+			x := int(int16(binary.BigEndian.Uint16(req.Body[4:6])))
+			y := int(int16(binary.BigEndian.Uint16(req.Body[6:8])))
+			w := int(binary.BigEndian.Uint16(req.Body[8:10]))
+			h := int(binary.BigEndian.Uint16(req.Body[10:12]))
+
+			reply := make([]byte, 32+w*h*4)
+			reply[1] = 24
+			binary.BigEndian.PutUint32(reply[8:], 0x21)
+			data := reply[32:]
+			for j := 0; j < h; j++ {
+				for i := 0; i < w; i++ {
+					c := pixel(x+i, y+j)
+					p := data[(j*w+i)*4:]
+					p[0], p[1], p[2] = c.B, c.G, c.R
+				}
+			}
+			return reply
+		}
+		return nil
+	})
+
+	r := image.Rect(3, 4, 13, 9)
+	img, err := b.Capture(Drawable(0x100), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != r {
+		t.Fatalf("bounds %v, want %v", img.Bounds(), r)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if got, want := img.RGBAAt(x, y), pixel(x, y); got != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	if n := len(s.requestsWithOpcode(OpGetImage)); n != 2 {
+		t.Errorf("%d GetImage requests, want 2", n)
+	}
+}
@@ -0,0 +1,244 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+var ErrHotspot = errors.New("cursor hotspot outside of the image")
+
+// CursorShape is one of the standard cursors of the X cursor font.
+type CursorShape int
+
+const (
+	CursorArrow CursorShape = iota
+	CursorText
+	CursorHand
+	CursorWait
+	CursorCrosshair
+	CursorMove
+	CursorNotAllowed
+	CursorResizeNS
+	CursorResizeEW
+	CursorResizeN
+	CursorResizeS
+	CursorResizeE
+	CursorResizeW
+	CursorResizeNE
+	CursorResizeNW
+	CursorResizeSE
+	CursorResizeSW
+)
+
+// cursorGlyphs maps shapes to their glyph in the cursor font. The mask of
+// each glyph is the glyph that follows it.
+var cursorGlyphs = [...]Card16{
+	CursorArrow:      68,  // left_ptr
+	CursorText:       152, // xterm
+	CursorHand:       60,  // hand2
+	CursorWait:       150, // watch
+	CursorCrosshair:  34,  // crosshair
+	CursorMove:       52,  // fleur
+	CursorNotAllowed: 0,   // X_cursor
+	CursorResizeNS:   116, // sb_v_double_arrow
+	CursorResizeEW:   108, // sb_h_double_arrow
+	CursorResizeN:    138, // top_side
+	CursorResizeS:    16,  // bottom_side
+	CursorResizeE:    96,  // right_side
+	CursorResizeW:    70,  // left_side
+	CursorResizeNE:   136, // top_right_corner
+	CursorResizeNW:   134, // top_left_corner
+	CursorResizeSE:   14,  // bottom_right_corner
+	CursorResizeSW:   12,  // bottom_left_corner
+}
+
+// Minor opcodes of the XFIXES extension.
+const (
+	xfixesQueryVersion Card8 = 0
+	xfixesHideCursor   Card8 = 29
+	xfixesShowCursor   Card8 = 30
+)
+
+const (
+	xfixesMajorVersion = 4
+	xfixesMinorVersion = 0
+)
+
+// ShapeCursor returns the standard cursor of the given shape, creating it
+// from the cursor font the first time it is used.
+func (b *Backend) ShapeCursor(shape CursorShape) (c Cursor, err error) {
+	if shape < 0 || int(shape) >= len(cursorGlyphs) {
+		return c, fmt.Errorf("cursor shape %d: %w", shape, ErrNotImplemented)
+	}
+
+	c, ok := b.cursors[shape]
+	if ok {
+		return c, nil
+	}
+
+	if b.cursorFont == 0 {
+		const name = "cursor"
+		b.cursorFont = Font(b.allocId())
+		b.beginRequest(OpOpenFont, 0, 12+len(name))
+		b.write(b.cursorFont)
+		b.write(Card16(len(name)))
+		b.writeUnused(2)
+		b.writeString(name)
+	}
+
+	glyph := cursorGlyphs[shape]
+	c = Cursor(b.allocId())
+	b.beginRequest(OpCreateGlyphCursor, 0, 32)
+	b.write(c)
+	b.write(b.cursorFont) // Source font
+	b.write(b.cursorFont) // Mask font
+	b.write(glyph)
+	b.write(glyph + 1)
+	b.write([3]Card16{0, 0, 0})                // Foreground
+	b.write([3]Card16{0xffff, 0xffff, 0xffff}) // Background
+	if b.err != nil {
+		return c, fmt.Errorf("creating cursor: %w", b.err)
+	}
+
+	if b.cursors == nil {
+		b.cursors = make(map[CursorShape]Cursor)
+	}
+	b.cursors[shape] = c
+	return c, nil
+}
+
+// ImageCursor creates a full color cursor from img, with the hotspot given
+// in image coordinates, which must be a pixel of img. The cursor must be
+// released with FreeCursor.
+func (b *Backend) ImageCursor(img image.Image, hotspot image.Point) (c Cursor, err error) {
+	if !hotspot.In(img.Bounds()) {
+		return c, fmt.Errorf("creating image cursor: hotspot %v not in %v: %w", hotspot, img.Bounds(), ErrHotspot)
+	}
+
+	ext, err := b.renderExtension()
+	if err != nil {
+		return c, fmt.Errorf("creating image cursor: %w", err)
+	}
+	format, err := b.argbPictFormat()
+	if err != nil {
+		return c, fmt.Errorf("creating image cursor: %w", err)
+	}
+	pf, err := b.argbFormat()
+	if err != nil {
+		return c, fmt.Errorf("creating image cursor: %w", err)
+	}
+
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	root := Drawable(b.rootScreen().Root)
+	pixmap := b.createPixmap(root, 32, bounds.Dx(), bounds.Dy())
+	gc := b.createGC(Drawable(pixmap))
+	err = b.putImage(Drawable(pixmap), gc, &pf, rgba, rgba.Bounds(), image.Point{})
+	b.freeGC(gc)
+	if err != nil {
+		b.freePixmap(pixmap)
+		return c, fmt.Errorf("creating image cursor: %w", err)
+	}
+
+	pic, err := b.createPicture(Drawable(pixmap), format)
+	b.freePixmap(pixmap)
+	if err != nil {
+		return c, fmt.Errorf("creating image cursor: %w", err)
+	}
+
+	hotspot = hotspot.Sub(bounds.Min)
+	c = Cursor(b.allocId())
+	b.beginExtensionRequest(ext, renderCreateCursor, 16)
+	b.write(c)
+	b.write(pic)
+	b.write(Card16(hotspot.X))
+	b.write(Card16(hotspot.Y))
+	b.freePicture(pic)
+
+	if b.err != nil {
+		return c, fmt.Errorf("creating image cursor: %w", b.err)
+	}
+	return c, nil
+}
+
+// FreeCursor releases a cursor created with ImageCursor. Windows using it
+// keep showing it until their cursor is changed.
+func (b *Backend) FreeCursor(c Cursor) {
+	b.beginRequest(OpFreeCursor, 0, 8)
+	b.write(c)
+}
+
+// SetCursor sets the cursor shown while the pointer is in the window. The
+// zero Cursor makes the window use the cursor of its parent.
+func (w *Window) SetCursor(c Cursor) error {
+	w.changeAttributes(CWCursor, Card32(c))
+	w.b.flush()
+	return w.b.err
+}
+
+type xfixesQueryVersionReply struct {
+	Header       replyHeader
+	MajorVersion Card32
+	MinorVersion Card32
+}
+
+// xfixesExtension queries the XFIXES extension, negotiating its version the
+// first time it is used.
+func (b *Backend) xfixesExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("XFIXES")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("XFIXES: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, xfixesQueryVersion, 12)
+	b.write(Card32(xfixesMajorVersion))
+	b.write(Card32(xfixesMinorVersion))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing XFIXES extension: %w", err)
+	}
+
+	var reply xfixesQueryVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing XFIXES extension: %w", err)
+	}
+
+	ext.initialized = true
+	b.extensions["XFIXES"] = ext
+	return ext, nil
+}
+
+// HideCursor hides the cursor while it is over any window of the
+// application, until ShowCursor is called.
+func (w *Window) HideCursor() error {
+	return w.xfixesCursor(xfixesHideCursor)
+}
+
+// ShowCursor undoes HideCursor.
+func (w *Window) ShowCursor() error {
+	return w.xfixesCursor(xfixesShowCursor)
+}
+
+func (w *Window) xfixesCursor(minor Card8) error {
+	ext, err := w.b.xfixesExtension()
+	if err != nil {
+		return fmt.Errorf("changing cursor visibility: %w", err)
+	}
+
+	w.b.beginExtensionRequest(ext, minor, 8)
+	w.b.write(w.Id)
+	w.b.flush()
+	return w.b.err
+}
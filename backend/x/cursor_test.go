@@ -0,0 +1,24 @@
+package x
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestImageCursorHotspot(t *testing.T) {
+	b, s := newFakeBackend(t, nil)
+
+	img := image.NewRGBA(image.Rect(10, 10, 42, 42))
+	for _, hotspot := range []image.Point{{-1, 12}, {0, 0}, {42, 20}, {20, 42}} {
+		_, err := b.ImageCursor(img, hotspot)
+		if !errors.Is(err, ErrHotspot) {
+			t.Errorf("hotspot %v: got error %v, want ErrHotspot", hotspot, err)
+		}
+	}
+
+	b.atom("CURSOR_TEST_BARRIER")
+	if n := len(s.requestsWithOpcode(OpQueryExtension)); n != 0 {
+		t.Errorf("RENDER queried %d times for invalid cursors", n)
+	}
+}
@@ -1,8 +1,11 @@
 package x
 
 import (
+	"bytes"
 	"encoding/binary"
-	// "fmt"
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
 )
 
@@ -10,34 +13,67 @@ func (b *Backend) marshall(data interface{}) {
 	b.write(data)
 }
 
-func (b *Backend) unmarshall(data interface{}) {
-	b.unmarshallValue(reflect.ValueOf(data).Elem())
+// ErrMalformed is returned for packets whose contents are inconsistent,
+// such as lists longer than the packet holding them.
+var ErrMalformed = errors.New("malformed packet")
+
+// decoder reads protocol structures out of a packet that has already been
+// received in full. Lengths read from the packet are checked against its
+// size before anything is allocated for them.
+type decoder struct {
+	r         io.Reader
+	byteOrder binary.ByteOrder
+	size      int
+	bytesRead int
+	err       error
 }
 
-func (b *Backend) unmarshallValue(value reflect.Value) {
+func newDecoder(buf []byte, byteOrder binary.ByteOrder) *decoder {
+	return &decoder{r: bytes.NewReader(buf), byteOrder: byteOrder, size: len(buf)}
+}
+
+// decode unmarshalls a received packet into data.
+func (b *Backend) decode(buf []byte, data interface{}) error {
+	d := newDecoder(buf, b.byteOrder)
+	d.unmarshall(data)
+	return d.err
+}
+
+func (d *decoder) unmarshall(data interface{}) {
+	d.unmarshallValue(reflect.ValueOf(data).Elem())
+}
+
+func (d *decoder) unmarshallValue(value reflect.Value) {
+	if d.err != nil {
+		return
+	}
+
 	switch value.Kind() {
 	case reflect.Struct:
 		for i := 0; i < value.NumField(); i++ {
-			b.unmarshallField(value, i)
+			d.unmarshallField(value, i)
 		}
 	case reflect.Array:
 		for i := 0; i < value.Len(); i++ {
-			b.unmarshallValue(value.Index(i))
+			d.unmarshallValue(value.Index(i))
 		}
-	case reflect.Slice:
-		panic("cannot unmashall value without length information")
+	case reflect.Slice, reflect.String:
+		d.err = fmt.Errorf("cannot unmarshall %s without length information", value.Type())
 	default:
-		b.read(value.Addr().Interface())
+		d.read(value.Addr().Interface())
 	}
 }
 
-func (b *Backend) unmarshallField(value reflect.Value, field int) {
+func (d *decoder) unmarshallField(value reflect.Value, field int) {
 	fieldValue := value.Field(field)
 	fieldKind := fieldValue.Kind()
 	sfield := value.Type().Field(field)
 
 	if fieldKind != reflect.Slice && fieldKind != reflect.String {
-		b.unmarshallValue(fieldValue)
+		d.unmarshallValue(fieldValue)
+		return
+	}
+	if d.err != nil {
 		return
 	}
 
@@ -46,41 +82,105 @@ func (b *Backend) unmarshallField(value reflect.Value, field int) {
 
 	lengthField := fieldTag.Get("lengthField")
 	if lengthField == "" {
-		panic("no length field for " + sfield.Name)
+		d.err = fmt.Errorf("no length field for %s", sfield.Name)
+		return
 	}
 
 	lengthValue := value.FieldByName(lengthField)
+	switch lengthValue.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+	default:
+		d.err = fmt.Errorf("invalid length field %s for %s", lengthField, sfield.Name)
+		return
+	}
 	length := lengthValue.Uint()
 
+	elemSize := 1
 	if fieldKind == reflect.Slice {
-		slc := fieldValue
-		tmp := reflect.New(fieldType.Elem()).Elem()
+		elemSize = minSize(fieldType.Elem())
+		if elemSize == 0 {
+			elemSize = 1
+		}
+	}
+	if length > uint64(d.size-d.bytesRead)/uint64(elemSize) {
+		d.err = fmt.Errorf("%s of length %d overflows packet of %d bytes: %w", sfield.Name, length, d.size, ErrMalformed)
+		return
+	}
+
+	if fieldKind == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8 {
+		buf := make([]byte, length)
+		d.read(buf)
+		fieldValue.Set(reflect.ValueOf(buf).Convert(fieldType))
+		d.readPadding()
+	} else if fieldKind == reflect.Slice {
+		slc := reflect.Zero(fieldType)
+		if length > 0 {
+			slc = reflect.MakeSlice(fieldType, 0, int(length))
+		}
 
-		for i := 0; i < int(length); i++ {
-			b.unmarshallValue(tmp)
+		for i := 0; i < int(length) && d.err == nil; i++ {
+			// Each element needs its own value, or the lists it holds
+			// would be shared with the previous one.
+			tmp := reflect.New(fieldType.Elem()).Elem()
+			d.unmarshallValue(tmp)
 			slc = reflect.Append(slc, tmp)
 		}
 
 		fieldValue.Set(slc)
 	} else {
-		var buf []byte
-		var tmp byte
+		buf := make([]byte, length)
+		d.read(buf)
+		d.readPadding()
+		fieldValue.SetString(string(buf))
+	}
+}
 
-		for i := 0; i < int(length); i++ {
-			b.read(&tmp)
-			buf = append(buf, tmp)
+// minSize returns the smallest number of bytes a value of type t takes on
+// the wire, counting lists as empty.
+func minSize(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < t.NumField(); i++ {
+			n += minSize(t.Field(i).Type)
 		}
-		b.readPadding()
-		fieldValue.SetString(string(buf))
+		return n
+	case reflect.Array:
+		return t.Len() * minSize(t.Elem())
+	case reflect.Slice, reflect.String:
+		return 0
+	default:
+		return int(t.Size())
 	}
 }
 
+func (d *decoder) read(data interface{}) {
+	if d.err != nil {
+		return
+	}
+
+	d.err = binary.Read(d.r, d.byteOrder, data)
+	d.bytesRead += binary.Size(data)
+}
+
+func (d *decoder) readUnused(n int) {
+	var buf [6]Card8
+	d.read(buf[0:n])
+}
+
+func (d *decoder) readPadding() {
+	d.readUnused((4 - d.bytesRead%4) % 4)
+}
+
 func (b *Backend) write(data interface{}) {
 	if b.err != nil {
 		return
 	}
 
-	b.err = binary.Write(b.conn, b.byteOrder, data)
+	err := binary.Write(b.w, b.byteOrder, data)
+	if err != nil {
+		b.disconnect(err)
+	}
 	b.bytesWritten += binary.Size(data)
 }
 
@@ -93,20 +193,23 @@ func (b *Backend) writePadding() {
 	b.writeUnused((4 - b.bytesWritten%4) % 4)
 }
 
-func (b *Backend) read(data interface{}) {
+func (b *Backend) writeString(s string) {
+	b.write([]byte(s))
+	b.writePadding()
+}
+
+func (b *Backend) flush() {
 	if b.err != nil {
 		return
 	}
 
-	b.err = binary.Read(b.conn, b.byteOrder, data)
-	b.bytesRead += binary.Size(data)
-}
-
-func (b *Backend) readUnused(n int) {
-	var buf [6]Card8
-	b.read(buf[0:n])
+	err := b.w.Flush()
+	if err != nil {
+		b.disconnect(err)
+	}
 }
 
-func (b *Backend) readPadding() {
-	b.readUnused((4 - b.bytesRead%4) % 4)
+// pad returns n rounded up to a multiple of four.
+func pad(n int) int {
+	return (n + 3) &^ 3
 }
@@ -0,0 +1,416 @@
+package x
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// Event codes of the core protocol events.
+const (
+	KeyPressCode        Card8 = 2
+	KeyReleaseCode      Card8 = 3
+	ButtonPressCode     Card8 = 4
+	ButtonReleaseCode   Card8 = 5
+	MotionNotifyCode    Card8 = 6
+	EnterNotifyCode     Card8 = 7
+	LeaveNotifyCode     Card8 = 8
+	FocusInCode         Card8 = 9
+	FocusOutCode        Card8 = 10
+	ExposeCode          Card8 = 12
+	DestroyNotifyCode   Card8 = 17
+	UnmapNotifyCode     Card8 = 18
+	MapNotifyCode       Card8 = 19
+	ReparentNotifyCode  Card8 = 21
+	ConfigureNotifyCode Card8 = 22
+	PropertyNotifyCode  Card8 = 28
+	ClientMessageCode   Card8 = 33
+	MappingNotifyCode   Card8 = 34
+	GenericEventCode    Card8 = 35
+)
+
+// eventCodeMask strips the bit set on events sent with SendEvent.
+const eventCodeMask = 0x7f
+
+const eventPacketSize = 32
+
+type KeyPressEvent struct {
+	Code       Card8
+	Detail     KeyCode
+	Sequence   Card16
+	Time       Timestamp
+	Root       WindowId
+	Event      WindowId
+	Child      WindowId
+	RootX      Int16
+	RootY      Int16
+	EventX     Int16
+	EventY     Int16
+	State      Card16
+	SameScreen Bool
+	Pad0       Card8
+}
+
+type KeyReleaseEvent KeyPressEvent
+
+type ButtonPressEvent struct {
+	Code       Card8
+	Detail     Card8
+	Sequence   Card16
+	Time       Timestamp
+	Root       WindowId
+	Event      WindowId
+	Child      WindowId
+	RootX      Int16
+	RootY      Int16
+	EventX     Int16
+	EventY     Int16
+	State      Card16
+	SameScreen Bool
+	Pad0       Card8
+}
+
+type ButtonReleaseEvent ButtonPressEvent
+
+type MotionNotifyEvent ButtonPressEvent
+
+type EnterNotifyEvent struct {
+	Code            Card8
+	Detail          Card8
+	Sequence        Card16
+	Time            Timestamp
+	Root            WindowId
+	Event           WindowId
+	Child           WindowId
+	RootX           Int16
+	RootY           Int16
+	EventX          Int16
+	EventY          Int16
+	State           Card16
+	Mode            Card8
+	SameScreenFocus Card8
+}
+
+type LeaveNotifyEvent EnterNotifyEvent
+
+type FocusInEvent struct {
+	Code     Card8
+	Detail   Card8
+	Sequence Card16
+	Event    WindowId
+	Mode     Card8
+	Pad0     [23]Card8
+}
+
+type FocusOutEvent FocusInEvent
+
+type ExposeEvent struct {
+	Code     Card8
+	Pad0     Card8
+	Sequence Card16
+	Window   WindowId
+	X        Card16
+	Y        Card16
+	Width    Card16
+	Height   Card16
+	Count    Card16
+	Pad1     [14]Card8
+}
+
+type DestroyNotifyEvent struct {
+	Code     Card8
+	Pad0     Card8
+	Sequence Card16
+	Event    WindowId
+	Window   WindowId
+	Pad1     [20]Card8
+}
+
+type UnmapNotifyEvent struct {
+	Code          Card8
+	Pad0          Card8
+	Sequence      Card16
+	Event         WindowId
+	Window        WindowId
+	FromConfigure Bool
+	Pad1          [19]Card8
+}
+
+type MapNotifyEvent struct {
+	Code             Card8
+	Pad0             Card8
+	Sequence         Card16
+	Event            WindowId
+	Window           WindowId
+	OverrideRedirect Bool
+	Pad1             [19]Card8
+}
+
+type ReparentNotifyEvent struct {
+	Code             Card8
+	Pad0             Card8
+	Sequence         Card16
+	Event            WindowId
+	Window           WindowId
+	Parent           WindowId
+	X                Int16
+	Y                Int16
+	OverrideRedirect Bool
+	Pad1             [11]Card8
+}
+
+type ConfigureNotifyEvent struct {
+	Code             Card8
+	Pad0             Card8
+	Sequence         Card16
+	Event            WindowId
+	Window           WindowId
+	AboveSibling     WindowId
+	X                Int16
+	Y                Int16
+	Width            Card16
+	Height           Card16
+	BorderWidth      Card16
+	OverrideRedirect Bool
+	Pad1             [5]Card8
+}
+
+type PropertyNotifyEvent struct {
+	Code     Card8
+	Pad0     Card8
+	Sequence Card16
+	Window   WindowId
+	Atom     Atom
+	Time     Timestamp
+	State    Card8
+	Pad1     [15]Card8
+}
+
+type ClientMessageEvent struct {
+	Code     Card8
+	Format   Card8
+	Sequence Card16
+	Window   WindowId
+	Type     Atom
+	Data     [5]Card32
+}
+
+const (
+	MappingModifier Card8 = iota
+	MappingKeyboard
+	MappingPointer
+)
+
+type MappingNotifyEvent struct {
+	Code         Card8
+	Pad0         Card8
+	Sequence     Card16
+	Request      Card8
+	FirstKeycode KeyCode
+	Count        Card8
+	Pad1         [25]Card8
+}
+
+// GenericEvent is the common prefix of events sent through the Generic
+// Event Extension. Length counts the four byte units following the first
+// 32 bytes.
+type GenericEvent struct {
+	Code      Card8
+	Extension Card8
+	Sequence  Card16
+	Length    Card32
+	EventType Card16
+}
+
+// UnknownEvent is an event the backend has no decoder for.
+type UnknownEvent struct {
+	Data []byte
+}
+
+var coreEventTypes = map[Card8]reflect.Type{
+	KeyPressCode:        reflect.TypeOf(KeyPressEvent{}),
+	KeyReleaseCode:      reflect.TypeOf(KeyReleaseEvent{}),
+	ButtonPressCode:     reflect.TypeOf(ButtonPressEvent{}),
+	ButtonReleaseCode:   reflect.TypeOf(ButtonReleaseEvent{}),
+	MotionNotifyCode:    reflect.TypeOf(MotionNotifyEvent{}),
+	EnterNotifyCode:     reflect.TypeOf(EnterNotifyEvent{}),
+	LeaveNotifyCode:     reflect.TypeOf(LeaveNotifyEvent{}),
+	FocusInCode:         reflect.TypeOf(FocusInEvent{}),
+	FocusOutCode:        reflect.TypeOf(FocusOutEvent{}),
+	ExposeCode:          reflect.TypeOf(ExposeEvent{}),
+	DestroyNotifyCode:   reflect.TypeOf(DestroyNotifyEvent{}),
+	UnmapNotifyCode:     reflect.TypeOf(UnmapNotifyEvent{}),
+	MapNotifyCode:       reflect.TypeOf(MapNotifyEvent{}),
+	ReparentNotifyCode:  reflect.TypeOf(ReparentNotifyEvent{}),
+	ConfigureNotifyCode: reflect.TypeOf(ConfigureNotifyEvent{}),
+	PropertyNotifyCode:  reflect.TypeOf(PropertyNotifyEvent{}),
+	ClientMessageCode:   reflect.TypeOf(ClientMessageEvent{}),
+	MappingNotifyCode:   reflect.TypeOf(MappingNotifyEvent{}),
+}
+
+type genericEventKey struct {
+	extension Card8
+	eventType Card16
+}
+
+// registerEvent makes events with the given code decode into values of the
+// type of ev. Extensions use it for the events at their event base.
+func (b *Backend) registerEvent(code Card8, ev interface{}) {
+	if b.eventTypes == nil {
+		b.eventTypes = make(map[Card8]reflect.Type)
+	}
+	b.eventTypes[code] = reflect.TypeOf(ev)
+}
+
+// registerGenericEvent makes generic events of an extension decode into
+// values of the type of ev.
+func (b *Backend) registerGenericEvent(extension Card8, eventType Card16, ev interface{}) {
+	if b.genericEventTypes == nil {
+		b.genericEventTypes = make(map[genericEventKey]reflect.Type)
+	}
+	b.genericEventTypes[genericEventKey{extension, eventType}] = reflect.TypeOf(ev)
+}
+
+func (b *Backend) decodeEvent(buf []byte) (ev interface{}, err error) {
+	if len(buf) < eventPacketSize {
+		return nil, fmt.Errorf("event of %d bytes: %w", len(buf), ErrMalformed)
+	}
+	code := Card8(buf[0]) & eventCodeMask
+
+	typ, ok := coreEventTypes[code]
+	if !ok {
+		typ, ok = b.eventTypes[code]
+	}
+	if !ok && code == GenericEventCode {
+		key := genericEventKey{
+			extension: Card8(buf[1]),
+			eventType: Card16(b.byteOrder.Uint16(buf[8:10])),
+		}
+		typ, ok = b.genericEventTypes[key]
+	}
+	if !ok {
+		return UnknownEvent{Data: buf}, nil
+	}
+
+	v := reflect.New(typ)
+	err = binary.Read(bytes.NewReader(buf), b.byteOrder, v.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("decoding event %d: %w", code, err)
+	}
+	return v.Elem().Interface(), nil
+}
+
+func (b *Backend) encodeEvent(ev interface{}) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, b.byteOrder, ev)
+	for buf.Len() < eventPacketSize {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// NextEvent flushes pending requests and returns the next event sent by the
+// server, blocking until one arrives. Errors caused by requests without a
+// reply are returned as a *ProtocolError and do not invalidate the
+// connection. When the connection is lost, DisconnectedEvent is returned
+// once, and ErrDisconnected afterwards.
+func (b *Backend) NextEvent() (ev interface{}, err error) {
+	for len(b.events) == 0 {
+		if b.disconnected != nil && !b.reported {
+			b.reported = true
+			return DisconnectedEvent{Cause: b.disconnected}, nil
+		}
+
+		b.flush()
+		if b.err != nil {
+			return nil, fmt.Errorf("sending requests: %w", b.err)
+		}
+
+		buf, err := b.readPacket()
+		if err != nil {
+			// The lost connection is reported by the next iteration.
+			continue
+		}
+		if buf[0] != 1 {
+			b.events = append(b.events, buf)
+		}
+	}
+
+	buf := b.events[0]
+	b.events = b.events[1:]
+
+	if buf[0] == 0 {
+		return nil, b.decodeError(buf)
+	}
+
+	ev, err = b.decodeEvent(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.handleEvent(ev)
+}
+
+// handleEvent updates the backend state tracked for windows before the
+// event is handed to the application, translating extension events into
+// the backend's own where needed.
+func (b *Backend) handleEvent(ev interface{}) (interface{}, error) {
+	switch e := ev.(type) {
+	case ConfigureNotifyEvent:
+		w, ok := b.windows[e.Window]
+		if ok {
+			w.configure(e)
+		}
+	case ClientMessageEvent:
+		err := b.trayClientMessage(e)
+		if err != nil {
+			return ev, err
+		}
+		w, ok := b.windows[e.Window]
+		if ok {
+			return ev, w.clientMessage(e)
+		}
+	case ReparentNotifyEvent:
+		b.trayReparented(e)
+	case DestroyNotifyEvent:
+		b.windowDestroyed(e.Window)
+	case KeyPressEvent:
+		if e.Event == b.rootScreen().Root {
+			h := b.matchHotkey(e.Detail, 0, e.State)
+			if h != nil {
+				return HotkeyEvent{Hotkey: h, Time: e.Time, RootX: e.RootX, RootY: e.RootY}, nil
+			}
+		}
+	case ButtonPressEvent:
+		if e.Event == b.rootScreen().Root {
+			h := b.matchHotkey(0, e.Detail, e.State)
+			if h != nil {
+				return HotkeyEvent{Hotkey: h, Time: e.Time, RootX: e.RootX, RootY: e.RootY}, nil
+			}
+		}
+		click, ok := b.trayClick(e)
+		if ok {
+			return click, nil
+		}
+		dismiss, ok := b.popupClick(e)
+		if ok {
+			return dismiss, nil
+		}
+	case MappingNotifyEvent:
+		if e.Request != MappingPointer {
+			return ev, b.remapHotkeys()
+		}
+	case PresentCompleteNotifyEvent:
+		w, ok := b.windows[e.Window]
+		if ok {
+			return w.presentComplete(e), nil
+		}
+	case PresentIdleNotifyEvent:
+		w, ok := b.windows[e.Window]
+		if ok {
+			w.presentIdle(e)
+		}
+	}
+	return ev, nil
+}
@@ -0,0 +1,255 @@
+//go:build go1.18
+// +build go1.18
+
+package x
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xvfbSetupReply is the setup reply an Xvfb server started with its default
+// 1280x1024x24 screen sends to a big endian client. It is written out field
+// by field from the connection setup section of the protocol specification,
+// not produced by our own encoding, so decoding it checks the layout of
+// InitResponse against the wire format. Replies captured from actual
+// servers with TestCaptureSetup are kept in testdata/fuzz/FuzzDecodeSetup.
+var xvfbSetupReply = []byte{
+	0x01, 0x00, // success, unused
+	0x00, 0x0b, 0x00, 0x00, // protocol version 11.0
+	0x00, 0x45, // length of the rest in 4 byte units
+	0x00, 0xb8, 0xa5, 0x93, // release number
+	0x00, 0x20, 0x00, 0x00, // resource id base
+	0x00, 0x1f, 0xff, 0xff, // resource id mask
+	0x00, 0x00, 0x01, 0x00, // motion buffer size
+	0x00, 0x14, 0xff, 0xff, // vendor length, maximum request length
+	0x01, 0x07, 0x00, 0x00, // screens, pixmap formats, image and bitmap bit order LSBFirst
+	0x20, 0x20, 0x08, 0xff, // scanline unit and pad, min and max keycode
+	0x00, 0x00, 0x00, 0x00, // unused
+	0x54, 0x68, 0x65, 0x20, 0x58, 0x2e, 0x4f, 0x72, 0x67, 0x20, // vendor "The X.Org Foundation"
+	0x46, 0x6f, 0x75, 0x6e, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, // vendor, continued without padding
+	0x01, 0x01, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 1, 1 bit per pixel, scanline pad 32
+	0x04, 0x08, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 4, 8 bits per pixel, scanline pad 32
+	0x08, 0x08, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 8, 8 bits per pixel, scanline pad 32
+	0x0f, 0x10, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 15, 16 bits per pixel, scanline pad 32
+	0x10, 0x10, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 16, 16 bits per pixel, scanline pad 32
+	0x18, 0x20, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 24, 32 bits per pixel, scanline pad 32
+	0x20, 0x20, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, // format: depth 32, 32 bits per pixel, scanline pad 32
+	0x00, 0x00, 0x03, 0xb5, // root window
+	0x00, 0x00, 0x00, 0x22, // default colormap
+	0x00, 0xff, 0xff, 0xff, // white pixel
+	0x00, 0x00, 0x00, 0x00, // black pixel
+	0x00, 0xfa, 0x80, 0x00, // current input masks
+	0x05, 0x00, 0x04, 0x00, // 1280x1024 pixels
+	0x01, 0x52, 0x01, 0x0e, // 338x270 millimeters
+	0x00, 0x01, 0x00, 0x01, // min and max installed maps
+	0x00, 0x00, 0x00, 0x21, // root visual
+	0x00, 0x00, 0x18, 0x07, // backing stores Never, no save unders, root depth 24, depths
+	0x18, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, // depth 24 with 2 visuals
+	0x00, 0x00, 0x00, 0x21, 0x04, 0x08, 0x01, 0x00, // visual 0x21, TrueColor, 8 bits per RGB, 256 colormap entries
+	0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, // red, green and blue masks
+	0x00, 0x00, 0x00, 0x00, // unused
+	0x00, 0x00, 0x00, 0x22, 0x05, 0x08, 0x01, 0x00, // visual 0x22, DirectColor, 8 bits per RGB, 256 colormap entries
+	0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, // red, green and blue masks
+	0x00, 0x00, 0x00, 0x00, // unused
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // depth 1 without visuals
+	0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // depth 4 without visuals
+	0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // depth 8 without visuals
+	0x0f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // depth 15 without visuals
+	0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // depth 16 without visuals
+	0x20, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, // depth 32 with 1 visual
+	0x00, 0x00, 0x03, 0xb4, 0x04, 0x08, 0x01, 0x00, // visual 0x3b4, TrueColor, 8 bits per RGB, 256 colormap entries
+	0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, // red, green and blue masks
+	0x00, 0x00, 0x00, 0x00, // unused
+}
+
+// xvfbSetup is xvfbSetupReply decoded.
+func xvfbSetup() InitResponse {
+	noVisuals := func(depth Card8) Depth { return Depth{Depth: depth} }
+	visuals := func(depth Card8, visuals ...VisualType) Depth {
+		return Depth{Depth: depth, VisualsLength: Card16(len(visuals)), Visuals: visuals}
+	}
+	trueColor := func(id VisualId, class VisualClass) VisualType {
+		return VisualType{
+			VisualId:        id,
+			Class:           class,
+			BitsPerRgbValue: 8,
+			ColormapEntries: 256,
+			RedMask:         0xff0000,
+			GreenMask:       0x00ff00,
+			BlueMask:        0x0000ff,
+		}
+	}
+
+	return InitResponse{
+		ProtocolMajorVersion: 11,
+		Pad1:                 69,
+		ReleaseNumber:        12101011,
+		ResourceIdBase:       0x00200000,
+		ResourceIdMask:       0x001fffff,
+		MotionBufferSize:     256,
+		VendorLength:         20,
+		MaximumRequestLength: 65535,
+		RootsLength:          1,
+		PixmapFormatsLength:  7,
+		ImageByteOrder:       LSBFirst,
+		BitmapBitOrder:       LSBFirst,
+		BitmapScanlineUnit:   32,
+		BitmapScanlinePad:    32,
+		MinKeyCode:           8,
+		MaxKeyCode:           255,
+		Vendor:               "The X.Org Foundation",
+		PixmapFormats: []Format{
+			{Depth: 1, BitsPerPixel: 1, ScanlinePad: 32},
+			{Depth: 4, BitsPerPixel: 8, ScanlinePad: 32},
+			{Depth: 8, BitsPerPixel: 8, ScanlinePad: 32},
+			{Depth: 15, BitsPerPixel: 16, ScanlinePad: 32},
+			{Depth: 16, BitsPerPixel: 16, ScanlinePad: 32},
+			{Depth: 24, BitsPerPixel: 32, ScanlinePad: 32},
+			{Depth: 32, BitsPerPixel: 32, ScanlinePad: 32},
+		},
+		Roots: []Screen{{
+			Root:                0x3b5,
+			DefaultColormap:     0x22,
+			WhitePixel:          0xffffff,
+			CurrentInputMasks:   0xfa8000,
+			WidthInPixels:       1280,
+			HeightInPixels:      1024,
+			WidthInMillimiters:  338,
+			HeightInMillimiters: 270,
+			MinInstalledMaps:    1,
+			MaxInstalledMaps:    1,
+			RootVisual:          0x21,
+			RootDepth:           24,
+			AllowedDepthsLength: 7,
+			AllowedDepths: []Depth{
+				visuals(24, trueColor(0x21, TrueColor), trueColor(0x22, DirectColor)),
+				noVisuals(1),
+				noVisuals(4),
+				noVisuals(8),
+				noVisuals(15),
+				noVisuals(16),
+				visuals(32, trueColor(0x3b4, TrueColor)),
+			},
+		}},
+	}
+}
+
+func TestDecodeSetup(t *testing.T) {
+	got, err := decodeSetup(xvfbSetupReply, binary.BigEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := xvfbSetup(); !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+
+	_, err = decodeSetup(xvfbSetupReply[:len(xvfbSetupReply)-4], binary.BigEndian)
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("decoding truncated setup returned %v", err)
+	}
+
+	// A screen count larger than the reply must fail without allocating
+	// for it.
+	buf := append([]byte(nil), xvfbSetupReply...)
+	buf[28] = 255
+	_, err = decodeSetup(buf, binary.BigEndian)
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("decoding setup with too many screens returned %v", err)
+	}
+}
+
+func FuzzDecodeSetup(f *testing.F) {
+	f.Add(xvfbSetupReply)
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		r, err := decodeSetup(buf, binary.BigEndian)
+		if err != nil {
+			return
+		}
+		if len(r.Roots) == 0 {
+			t.Fatal("decoded setup without screens")
+		}
+	})
+}
+
+var captureSetup = flag.Bool("capture-setup", false, "add the setup reply of the X server in DISPLAY to the FuzzDecodeSetup corpus")
+
+// recordingConn keeps the bytes read from a connection.
+type recordingConn struct {
+	net.Conn
+	read bytes.Buffer
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Write(p[:n])
+	return n, err
+}
+
+// TestCaptureSetup adds the setup reply of the X server in DISPLAY to the
+// seed corpus of FuzzDecodeSetup, when run with -capture-setup.
+func TestCaptureSetup(t *testing.T) {
+	if !*captureSetup {
+		t.Skip("run with -capture-setup to capture the setup reply of the X server")
+	}
+
+	ctx := context.Background()
+	conn, _, err := connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordingConn{Conn: conn}
+	b := &Backend{conn: rec}
+	defer b.Close()
+	err = b.setup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := rec.read.Bytes()
+	buf = buf[:8+4*int(binary.BigEndian.Uint16(buf[6:8]))]
+	r := b.initResponse
+	name := fmt.Sprintf("%s-%d", strings.ReplaceAll(r.Vendor, " ", "_"), r.ReleaseNumber)
+	dir := filepath.Join("testdata", "fuzz", "FuzzDecodeSetup")
+	err = os.MkdirAll(dir, 0o755)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", buf)), 0o644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("captured %d bytes from %s %d", len(buf), r.Vendor, r.ReleaseNumber)
+}
+
+func FuzzDecodeEvent(f *testing.F) {
+	b := &Backend{
+		byteOrder:    binary.BigEndian,
+		initResponse: xvfbSetup(),
+	}
+	b.registerEvent(64, ShapeNotifyEvent{})
+	b.registerGenericEvent(130, PresentCompleteNotifyType, PresentCompleteNotifyEvent{})
+
+	f.Add(b.encodeEvent(&KeyPressEvent{Code: KeyPressCode, Detail: 38, Root: 0x3b5, Event: 0x200001}))
+	f.Add(b.encodeEvent(&ConfigureNotifyEvent{Code: ConfigureNotifyCode, Window: 0x200001, Width: 640, Height: 480}))
+	f.Add(b.encodeEvent(&ClientMessageEvent{Code: ClientMessageCode, Format: 32, Window: 0x200001, Data: [5]Card32{1, 2}}))
+	f.Add(b.encodeEvent(&ShapeNotifyEvent{Code: 64, Window: 0x200001}))
+	f.Add(b.encodeEvent(&PresentCompleteNotifyEvent{Code: GenericEventCode, Extension: 130, Length: 2, EventType: PresentCompleteNotifyType}))
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		ev, err := b.decodeEvent(buf)
+		if err == nil && ev == nil {
+			t.Fatal("decoded nil event without error")
+		}
+	})
+}
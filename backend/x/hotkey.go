@@ -0,0 +1,327 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrAccelerator = errors.New("invalid accelerator")
+
+// Hotkey is a key or button combination grabbed on the root window, which
+// is reported with HotkeyEvent regardless of which window has the focus.
+type Hotkey struct {
+	Accelerator string
+
+	modifiers Card16
+	keycodes  []KeyCode
+	button    Card8
+	lockMasks []Card16
+
+	// grabbed is unset while the hotkey cannot be grabbed, after a change
+	// of the keyboard mapping unmapped its keys.
+	grabbed bool
+}
+
+// HotkeyEvent reports that a registered hotkey was pressed.
+type HotkeyEvent struct {
+	Hotkey *Hotkey
+	Time   Timestamp
+	RootX  Int16
+	RootY  Int16
+}
+
+var keyNames = map[string]Keysym{
+	"space":     KeysymSpace,
+	"backspace": KeysymBackSpace,
+	"tab":       KeysymTab,
+	"return":    KeysymReturn,
+	"enter":     KeysymReturn,
+	"pause":     KeysymPause,
+	"escape":    KeysymEscape,
+	"esc":       KeysymEscape,
+	"home":      KeysymHome,
+	"left":      KeysymLeft,
+	"up":        KeysymUp,
+	"right":     KeysymRight,
+	"down":      KeysymDown,
+	"pageup":    KeysymPageUp,
+	"prior":     KeysymPageUp,
+	"pagedown":  KeysymPageDown,
+	"next":      KeysymPageDown,
+	"end":       KeysymEnd,
+	"print":     KeysymPrint,
+	"insert":    KeysymInsert,
+	"menu":      KeysymMenu,
+	"delete":    KeysymDelete,
+	"plus":      '+',
+	"minus":     '-',
+}
+
+// accelerator is a parsed accelerator string. Modifiers that depend on the
+// keyboard mapping are kept as keysyms until the hotkey is grabbed.
+type accelerator struct {
+	modifiers    Card16
+	modifierSyms [][]Keysym
+	keysym       Keysym
+	button       Card8
+}
+
+// parseAccelerator parses accelerators such as "Super+Space",
+// "Ctrl+Shift+T" or "Alt+Button1". Names are case insensitive.
+func parseAccelerator(s string) (acc accelerator, err error) {
+	parts := strings.Split(s, "+")
+	key := parts[len(parts)-1]
+	if key == "" {
+		return acc, fmt.Errorf("missing key in %q: %w", s, ErrAccelerator)
+	}
+
+	for _, mod := range parts[:len(parts)-1] {
+		switch strings.ToLower(mod) {
+		case "shift":
+			acc.modifiers |= ShiftMask
+		case "ctrl", "control":
+			acc.modifiers |= ControlMask
+		case "alt":
+			acc.modifierSyms = append(acc.modifierSyms, []Keysym{KeysymAltL, KeysymAltR})
+		case "meta":
+			acc.modifierSyms = append(acc.modifierSyms, []Keysym{KeysymMetaL, KeysymMetaR})
+		case "super", "win", "logo":
+			acc.modifierSyms = append(acc.modifierSyms, []Keysym{KeysymSuperL, KeysymSuperR})
+		case "mod1":
+			acc.modifiers |= Mod1Mask
+		case "mod2":
+			acc.modifiers |= Mod2Mask
+		case "mod3":
+			acc.modifiers |= Mod3Mask
+		case "mod4":
+			acc.modifiers |= Mod4Mask
+		case "mod5":
+			acc.modifiers |= Mod5Mask
+		default:
+			return acc, fmt.Errorf("unknown modifier %q in %q: %w", mod, s, ErrAccelerator)
+		}
+	}
+
+	lower := strings.ToLower(key)
+	if sym, ok := keyNames[lower]; ok {
+		acc.keysym = sym
+		return acc, nil
+	}
+
+	if strings.HasPrefix(lower, "button") {
+		n, err := strconv.Atoi(lower[len("button"):])
+		if err != nil || n < 1 || n > 255 {
+			return acc, fmt.Errorf("invalid button %q in %q: %w", key, s, ErrAccelerator)
+		}
+		acc.button = Card8(n)
+		return acc, nil
+	}
+
+	if len(lower) > 1 && lower[0] == 'f' {
+		n, err := strconv.Atoi(lower[1:])
+		if err == nil && n >= 1 && n <= 35 {
+			acc.keysym = KeysymF1 + Keysym(n-1)
+			return acc, nil
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(lower)
+	if size == len(lower) && r != utf8.RuneError {
+		acc.keysym = RuneKeysym(r)
+		return acc, nil
+	}
+
+	return acc, fmt.Errorf("unknown key %q in %q: %w", key, s, ErrAccelerator)
+}
+
+// RegisterHotkey grabs the accelerator on the root window, so that it is
+// reported with HotkeyEvent even while other applications have the focus.
+// The grab ignores the state of Caps Lock and Num Lock.
+func (b *Backend) RegisterHotkey(accel string) (h *Hotkey, err error) {
+	acc, err := parseAccelerator(accel)
+	if err != nil {
+		return nil, err
+	}
+
+	km, err := b.keyboardMapping()
+	if err != nil {
+		return nil, fmt.Errorf("registering hotkey %q: %w", accel, err)
+	}
+
+	h = &Hotkey{
+		Accelerator: accel,
+		modifiers:   acc.modifiers,
+		button:      acc.button,
+	}
+	for _, syms := range acc.modifierSyms {
+		mask := km.modifierMask(syms...)
+		if mask == 0 {
+			return nil, fmt.Errorf("registering hotkey %q: modifier key %#x not mapped: %w", accel, syms[0], ErrAccelerator)
+		}
+		h.modifiers |= mask
+	}
+
+	if acc.button == 0 {
+		h.keycodes, _ = km.keycodes(acc.keysym)
+		if len(h.keycodes) == 0 {
+			return nil, fmt.Errorf("registering hotkey %q: key %#x not mapped: %w", accel, acc.keysym, ErrAccelerator)
+		}
+	}
+
+	numLock := km.modifierMask(KeysymNumLock)
+	h.lockMasks = []Card16{0, LockMask}
+	if numLock != 0 {
+		h.lockMasks = append(h.lockMasks, numLock, numLock|LockMask)
+	}
+
+	first := b.sequence + 1
+	b.grabHotkey(h)
+	err = b.checkRequests(first, b.sequence)
+	if err != nil {
+		// Some of the combinations may have been grabbed.
+		b.ungrabKeys(h)
+		b.flush()
+		return nil, fmt.Errorf("registering hotkey %q: %w", accel, err)
+	}
+
+	h.grabbed = true
+	b.hotkeys = append(b.hotkeys, h)
+	return h, nil
+}
+
+// UnregisterHotkey releases the grabs of a hotkey.
+func (b *Backend) UnregisterHotkey(h *Hotkey) error {
+	for i, other := range b.hotkeys {
+		if other == h {
+			b.hotkeys = append(b.hotkeys[:i], b.hotkeys[i+1:]...)
+			b.ungrabHotkey(h)
+			b.flush()
+			return b.err
+		}
+	}
+	return nil
+}
+
+func (b *Backend) grabHotkey(h *Hotkey) {
+	root := b.rootScreen().Root
+
+	for _, lock := range h.lockMasks {
+		if h.button != 0 {
+			b.beginRequest(OpGrabButton, Card8(False), 24)
+			b.write(root)
+			b.write(Card16(EVButtonPress.Mask() | EVButtonRelease.Mask()))
+			b.write(GrabModeAsync) // Pointer mode
+			b.write(GrabModeAsync) // Keyboard mode
+			b.write(WindowId(0))   // Confine to
+			b.write(Cursor(0))
+			b.write(h.button)
+			b.writeUnused(1)
+			b.write(h.modifiers | lock)
+			continue
+		}
+
+		for _, kc := range h.keycodes {
+			b.beginRequest(OpGrabKey, Card8(False), 16)
+			b.write(root)
+			b.write(h.modifiers | lock)
+			b.write(kc)
+			b.write(GrabModeAsync) // Pointer mode
+			b.write(GrabModeAsync) // Keyboard mode
+			b.writeUnused(3)
+		}
+	}
+}
+
+func (b *Backend) ungrabHotkey(h *Hotkey) {
+	if !h.grabbed {
+		return
+	}
+	h.grabbed = false
+	b.ungrabKeys(h)
+}
+
+// ungrabKeys releases every grab of a hotkey, whether it holds it or not.
+func (b *Backend) ungrabKeys(h *Hotkey) {
+	root := b.rootScreen().Root
+
+	for _, lock := range h.lockMasks {
+		if h.button != 0 {
+			b.beginRequest(OpUngrabButton, h.button, 12)
+			b.write(root)
+			b.write(h.modifiers | lock)
+			b.writeUnused(2)
+			continue
+		}
+
+		for _, kc := range h.keycodes {
+			b.beginRequest(OpUngrabKey, Card8(kc), 12)
+			b.write(root)
+			b.write(h.modifiers | lock)
+			b.writeUnused(2)
+		}
+	}
+}
+
+// matchHotkey finds the hotkey grabbed for a key or button press on the
+// root window.
+func (b *Backend) matchHotkey(keycode KeyCode, button Card8, state Card16) *Hotkey {
+	for _, h := range b.hotkeys {
+		if !h.grabbed {
+			continue
+		}
+		ignored := Card16(0)
+		for _, lock := range h.lockMasks {
+			ignored |= lock
+		}
+		if state&^ignored&0xff != h.modifiers {
+			continue
+		}
+
+		if button != 0 {
+			if h.button == button {
+				return h
+			}
+			continue
+		}
+		for _, kc := range h.keycodes {
+			if kc == keycode {
+				return h
+			}
+		}
+	}
+	return nil
+}
+
+// remapHotkeys grabs the hotkeys again after the keyboard mapping changed.
+// Hotkeys that cannot be grabbed stay registered, and are tried again after
+// the next change. The first failure is returned.
+func (b *Backend) remapHotkeys() error {
+	hotkeys := b.hotkeys
+	b.hotkeys = nil
+	b.keymap = nil
+
+	var first error
+	failed := 0
+	for _, h := range hotkeys {
+		b.ungrabHotkey(h)
+		nh, err := b.RegisterHotkey(h.Accelerator)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+			b.hotkeys = append(b.hotkeys, h)
+			continue
+		}
+		*h = *nh
+		b.hotkeys[len(b.hotkeys)-1] = h
+	}
+
+	if first != nil {
+		return fmt.Errorf("remapping hotkeys: %d of %d not grabbed: %w", failed, len(hotkeys), first)
+	}
+	return nil
+}
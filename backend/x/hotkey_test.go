@@ -0,0 +1,154 @@
+package x
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestParseAccelerator(t *testing.T) {
+	var cases = []struct {
+		Accel     string
+		Modifiers Card16
+		Keysym    Keysym
+		Button    Card8
+	}{
+		{"Super+Space", 0, KeysymSpace, 0},
+		{"ctrl+shift+t", ControlMask | ShiftMask, 't', 0},
+		{"Ctrl+T", ControlMask, 't', 0},
+		{"Alt+F4", 0, KeysymF1 + 3, 0},
+		{"Mod4+Button3", Mod4Mask, 0, 3},
+		{"Control+plus", ControlMask, '+', 0},
+		{"é", 0, 0xe9, 0},
+	}
+
+	for _, c := range cases {
+		acc, err := parseAccelerator(c.Accel)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Accel, err)
+		}
+		if acc.modifiers != c.Modifiers || acc.keysym != c.Keysym || acc.button != c.Button {
+			t.Fatalf("%s: got modifiers=%#x keysym=%#x button=%d", c.Accel, acc.modifiers, acc.keysym, acc.button)
+		}
+	}
+
+	for _, accel := range []string{"", "Ctrl+", "Hyper+A", "Ctrl+Button0", "NoSuchKey"} {
+		_, err := parseAccelerator(accel)
+		if !errors.Is(err, ErrAccelerator) {
+			t.Fatalf("%q: got error %v, want ErrAccelerator", accel, err)
+		}
+	}
+}
+
+func TestMatchHotkeyIgnoresLocks(t *testing.T) {
+	var b Backend
+	h := &Hotkey{
+		modifiers: Mod4Mask,
+		keycodes:  []KeyCode{65},
+		lockMasks: []Card16{0, LockMask, Mod2Mask, Mod2Mask | LockMask},
+		grabbed:   true,
+	}
+	b.hotkeys = []*Hotkey{h}
+
+	if b.matchHotkey(65, 0, Mod4Mask|Mod2Mask|LockMask) != h {
+		t.Fatal("hotkey not matched with Num Lock and Caps Lock on")
+	}
+	if b.matchHotkey(65, 0, Mod4Mask|ShiftMask) != nil {
+		t.Fatal("hotkey matched with an extra modifier")
+	}
+	if b.matchHotkey(66, 0, Mod4Mask) != nil {
+		t.Fatal("hotkey matched another key")
+	}
+}
+
+func TestRemapHotkeysKeepsFailed(t *testing.T) {
+	mappings := 0
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		switch req.Opcode {
+		case OpGetKeyboardMapping:
+			// The key is unmapped by the second mapping.
+			mappings++
+			reply := make([]byte, 36)
+			reply[1] = 1
+			if mappings == 1 {
+				binary.BigEndian.PutUint32(reply[32:], uint32(RuneKeysym('a')))
+			}
+			return reply
+		case OpGetModifierMapping, OpGetInputFocus:
+			return make([]byte, 32)
+		}
+		return nil
+	})
+
+	h, err := b.RegisterHotkey("Ctrl+A")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.send(b.encodeEvent(&MappingNotifyEvent{Code: MappingNotifyCode, Request: MappingKeyboard}))
+	_, err = b.NextEvent()
+	if !errors.Is(err, ErrAccelerator) {
+		t.Fatalf("remapping failed with %v", err)
+	}
+	if len(b.hotkeys) != 1 || b.hotkeys[0] != h || h.grabbed {
+		t.Errorf("hotkeys %v after the failed remap", b.hotkeys)
+	}
+	if b.matchHotkey(b.initResponse.MinKeyCode, 0, ControlMask) != nil {
+		t.Error("ungrabbed hotkey matched")
+	}
+}
+
+func TestRegisterHotkeyReleasesPartialGrab(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		switch req.Opcode {
+		case OpGetKeyboardMapping:
+			reply := make([]byte, 36)
+			reply[1] = 1
+			binary.BigEndian.PutUint32(reply[32:], uint32(RuneKeysym('a')))
+			return reply
+		case OpGetModifierMapping, OpGetInputFocus:
+			return make([]byte, 32)
+		}
+		return nil
+	})
+	// Another client grabbed the key with Caps Lock on.
+	s.failRequests(func(req fakeRequest) Card8 {
+		if req.Opcode == OpGrabKey && Card16(binary.BigEndian.Uint16(req.Body[4:]))&LockMask != 0 {
+			return 10 // Access
+		}
+		return 0
+	})
+
+	_, err := b.RegisterHotkey("Ctrl+A")
+	var perr *ProtocolError
+	if !errors.As(err, &perr) || perr.Code != 10 {
+		t.Fatalf("registering a grabbed hotkey returned %v", err)
+	}
+
+	b.atom("X_TEST_BARRIER")
+	grabs := s.requestsWithOpcode(OpGrabKey)
+	ungrabs := s.requestsWithOpcode(OpUngrabKey)
+	if len(grabs) != 2 || len(ungrabs) != len(grabs) {
+		t.Errorf("%d ungrabs for %d grabs", len(ungrabs), len(grabs))
+	}
+	if len(b.hotkeys) != 0 {
+		t.Errorf("hotkeys %v", b.hotkeys)
+	}
+}
+
+func TestKeyboardMappingTooShort(t *testing.T) {
+	b, _ := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode == OpGetKeyboardMapping {
+			// Two keysyms per keycode, but only one sent.
+			reply := make([]byte, 36)
+			reply[1] = 2
+			return reply
+		}
+		return nil
+	})
+
+	_, err := b.RegisterHotkey("Ctrl+A")
+	if !errors.Is(err, ErrMalformed) {
+		t.Fatalf("short keyboard mapping returned %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package x
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+// pixelFormat describes how the pixels of a drawable are laid out in the
+// ZPixmap images sent with PutImage.
+type pixelFormat struct {
+	depth        Card8
+	bitsPerPixel int
+	scanlinePad  int
+	byteOrder    ByteOrder
+	channels     [4]channel // red, green, blue, alpha
+}
+
+// channel locates an 8 bit color component inside a pixel value.
+type channel struct {
+	shift int
+	bits  int
+}
+
+func newChannel(mask uint32) channel {
+	if mask == 0 {
+		return channel{}
+	}
+	return channel{
+		shift: bits.TrailingZeros32(mask),
+		bits:  bits.OnesCount32(mask),
+	}
+}
+
+func (c channel) decode(v uint32) uint8 {
+	if c.bits == 0 {
+		return 0xff
+	}
+	max := uint32(1)<<c.bits - 1
+	v = v >> c.shift & max
+	if c.bits >= 8 {
+		return uint8(v >> (c.bits - 8))
+	}
+	return uint8((v*0xff + max/2) / max)
+}
+
+func (c channel) encode(v uint8) uint32 {
+	if c.bits == 0 {
+		return 0
+	}
+	if c.bits >= 8 {
+		return uint32(v) << (c.bits - 8) << c.shift
+	}
+	return uint32(v) >> (8 - c.bits) << c.shift
+}
+
+// pixelFormat finds the image format used for drawables of the given depth
+// and visual.
+func (b *Backend) pixelFormat(depth Card8, visual VisualId) (pf pixelFormat, err error) {
+	pf.depth = depth
+	pf.byteOrder = b.initResponse.ImageByteOrder
+
+	found := false
+	for _, f := range b.initResponse.PixmapFormats {
+		if f.Depth == depth {
+			pf.bitsPerPixel = int(f.BitsPerPixel)
+			pf.scanlinePad = int(f.ScanlinePad)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return pf, fmt.Errorf("no pixmap format for depth %d: %w", depth, ErrNotImplemented)
+	}
+
+	vt, ok := b.visualType(visual)
+	if !ok {
+		return pf, fmt.Errorf("unknown visual %d: %w", visual, ErrNotImplemented)
+	}
+	if vt.Class != TrueColor && vt.Class != DirectColor {
+		return pf, fmt.Errorf("visual class %d: %w", vt.Class, ErrNotImplemented)
+	}
+	if pf.bitsPerPixel != 16 && pf.bitsPerPixel != 24 && pf.bitsPerPixel != 32 {
+		return pf, fmt.Errorf("%d bits per pixel: %w", pf.bitsPerPixel, ErrNotImplemented)
+	}
+
+	pf.setMasks(uint32(vt.RedMask), uint32(vt.GreenMask), uint32(vt.BlueMask))
+	return pf, nil
+}
+
+// argbFormat returns the image format of depth 32 pixmaps holding ARGB
+// pixels, such as the ones used for cursors.
+func (b *Backend) argbFormat() (pf pixelFormat, err error) {
+	pf.depth = 32
+	pf.byteOrder = b.initResponse.ImageByteOrder
+
+	for _, f := range b.initResponse.PixmapFormats {
+		if f.Depth == 32 {
+			pf.bitsPerPixel = int(f.BitsPerPixel)
+			pf.scanlinePad = int(f.ScanlinePad)
+		}
+	}
+	if pf.bitsPerPixel != 32 {
+		return pf, fmt.Errorf("no 32 bit pixmap format: %w", ErrNotImplemented)
+	}
+
+	pf.setMasks(0xff0000, 0x00ff00, 0x0000ff)
+	return pf, nil
+}
+
+// setMasks sets the color channels of the format. Bits of depth 32 pixels
+// not used for color hold the alpha channel.
+func (pf *pixelFormat) setMasks(red, green, blue uint32) {
+	alpha := uint32(0)
+	if pf.depth == 32 {
+		alpha = ^(red | green | blue)
+	}
+
+	pf.channels = [4]channel{
+		newChannel(red),
+		newChannel(green),
+		newChannel(blue),
+		newChannel(alpha),
+	}
+}
+
+func (b *Backend) visualType(visual VisualId) (vt VisualType, ok bool) {
+	for _, s := range b.initResponse.Roots {
+		for _, d := range s.AllowedDepths {
+			for _, v := range d.Visuals {
+				if v.VisualId == visual {
+					return v, true
+				}
+			}
+		}
+	}
+	return vt, false
+}
+
+// stride returns the number of bytes in a scanline of the given width.
+func (pf *pixelFormat) stride(width int) int {
+	n := width * pf.bitsPerPixel
+	n = (n + pf.scanlinePad - 1) / pf.scanlinePad * pf.scanlinePad
+	return n / 8
+}
+
+// encode converts the rectangle r of img into dst, which must hold
+// r.Dy() scanlines.
+func (pf *pixelFormat) encode(dst []byte, img *image.RGBA, r image.Rectangle) {
+	bpp := pf.bitsPerPixel / 8
+	stride := pf.stride(r.Dx())
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := dst[(y-r.Min.Y)*stride:]
+		src := img.Pix[img.PixOffset(r.Min.X, y):]
+
+		for x := 0; x < r.Dx(); x++ {
+			p := src[4*x : 4*x+4]
+			v := pf.channels[0].encode(p[0]) |
+				pf.channels[1].encode(p[1]) |
+				pf.channels[2].encode(p[2]) |
+				pf.channels[3].encode(p[3])
+
+			out := row[bpp*x : bpp*x+bpp]
+			for i := 0; i < bpp; i++ {
+				if pf.byteOrder == LSBFirst {
+					out[i] = byte(v >> (8 * i))
+				} else {
+					out[i] = byte(v >> (8 * (bpp - 1 - i)))
+				}
+			}
+		}
+	}
+}
+
+// decode converts scanlines in src into the rectangle r of img. The stride
+// of src is the one of scanlines of width r.Dx().
+func (pf *pixelFormat) decode(img *image.RGBA, r image.Rectangle, src []byte) {
+	bpp := pf.bitsPerPixel / 8
+	stride := pf.stride(r.Dx())
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := src[(y-r.Min.Y)*stride:]
+		dst := img.Pix[img.PixOffset(r.Min.X, y):]
+
+		for x := 0; x < r.Dx(); x++ {
+			in := row[bpp*x : bpp*x+bpp]
+			var v uint32
+			for i := 0; i < bpp; i++ {
+				if pf.byteOrder == LSBFirst {
+					v |= uint32(in[i]) << (8 * i)
+				} else {
+					v = v<<8 | uint32(in[i])
+				}
+			}
+
+			p := dst[4*x : 4*x+4]
+			p[0] = pf.channels[0].decode(v)
+			p[1] = pf.channels[1].decode(v)
+			p[2] = pf.channels[2].decode(v)
+			p[3] = pf.channels[3].decode(v)
+		}
+	}
+}
+
+// putImage uploads the rectangle r of img to the drawable at dst, splitting
+// it in bands of scanlines that fit the maximum request length.
+func (b *Backend) putImage(drawable Drawable, gc GContext, pf *pixelFormat, img *image.RGBA, r image.Rectangle, dst image.Point) error {
+	r = r.Intersect(img.Bounds())
+	if r.Empty() {
+		return nil
+	}
+
+	return b.putRows(drawable, gc, pf.depth, pf.stride(r.Dx()), r, dst, func(data []byte, band image.Rectangle) {
+		pf.encode(data, img, band)
+	})
+}
+
+// putRows sends the ZPixmap image of the rectangle r in bands of scanlines
+// that fit the maximum request length. The scanlines of each band are
+// produced by encode.
+func (b *Backend) putRows(drawable Drawable, gc GContext, depth Card8, stride int, r image.Rectangle, dst image.Point, encode func(data []byte, band image.Rectangle)) error {
+	maxLength := int(b.initResponse.MaximumRequestLength) * 4
+	rows := (maxLength - 24) / stride
+	if rows < 1 {
+		return fmt.Errorf("scanline of %d bytes exceeds the maximum request length", stride)
+	}
+
+	buf := make([]byte, rows*stride)
+	for y := r.Min.Y; y < r.Max.Y; y += rows {
+		band := image.Rect(r.Min.X, y, r.Max.X, y+rows).Intersect(r)
+		data := buf[:band.Dy()*stride]
+		for i := range data {
+			data[i] = 0
+		}
+		encode(data, band)
+
+		b.beginRequest(OpPutImage, Card8(ZPixmap), 24+len(data))
+		b.write(drawable)
+		b.write(gc)
+		b.write(Card16(band.Dx()))
+		b.write(Card16(band.Dy()))
+		b.write(Int16(dst.X))
+		b.write(Int16(dst.Y + y - r.Min.Y))
+		b.write(Card8(0)) // Left pad
+		b.write(depth)
+		b.writeUnused(2)
+		b.write(data)
+		b.writePadding()
+	}
+
+	return b.err
+}
+
+// bitmapStride returns the number of bytes in a scanline of a depth 1
+// image of the given width.
+func (b *Backend) bitmapStride(width int) int {
+	scanlinePad := int(b.initResponse.BitmapScanlinePad)
+	for _, f := range b.initResponse.PixmapFormats {
+		if f.Depth == 1 {
+			scanlinePad = int(f.ScanlinePad)
+		}
+	}
+	if scanlinePad == 0 {
+		scanlinePad = 32
+	}
+	n := (width + scanlinePad - 1) / scanlinePad * scanlinePad
+	return n / 8
+}
+
+// putBitmap uploads a depth 1 image to the drawable. A pixel is set where
+// mask is at least half opaque.
+func (b *Backend) putBitmap(drawable Drawable, gc GContext, mask image.Image, dst image.Point) error {
+	r := mask.Bounds()
+	if r.Empty() {
+		return nil
+	}
+
+	lsbFirst := b.initResponse.BitmapBitOrder == LSBFirst
+	stride := b.bitmapStride(r.Dx())
+	return b.putRows(drawable, gc, 1, stride, r, dst, func(data []byte, band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			row := data[(y-band.Min.Y)*stride:]
+			for x := 0; x < band.Dx(); x++ {
+				_, _, _, a := mask.At(band.Min.X+x, y).RGBA()
+				if a < 0x8000 {
+					continue
+				}
+				if lsbFirst {
+					row[x/8] |= 1 << (x % 8)
+				} else {
+					row[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+		}
+	})
+}
+
+// createGC creates a graphics context for drawable that does not generate
+// graphics exposures.
+func (b *Backend) createGC(drawable Drawable) GContext {
+	gc := GContext(b.allocId())
+	b.beginRequest(OpCreateGC, 0, 20)
+	b.write(gc)
+	b.write(drawable)
+	b.write(GCGraphicsExposures)
+	b.writeValues(0)
+	return gc
+}
+
+func (b *Backend) freeGC(gc GContext) {
+	b.beginRequest(OpFreeGC, 0, 8)
+	b.write(gc)
+}
+
+func (b *Backend) createPixmap(drawable Drawable, depth Card8, width, height int) Pixmap {
+	pixmap := Pixmap(b.allocId())
+	b.beginRequest(OpCreatePixmap, depth, 16)
+	b.write(pixmap)
+	b.write(drawable)
+	b.write(Card16(width))
+	b.write(Card16(height))
+	return pixmap
+}
+
+func (b *Backend) freePixmap(pixmap Pixmap) {
+	b.beginRequest(OpFreePixmap, 0, 8)
+	b.write(pixmap)
+}
@@ -0,0 +1,429 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// MapState is whether a window is mapped and visible.
+type MapState Card8
+
+const (
+	IsUnmapped   MapState = 0
+	IsUnviewable MapState = 1 // Mapped, but an ancestor is not
+	IsViewable   MapState = 2
+)
+
+// WindowAttributes is the reply of GetWindowAttributes.
+type WindowAttributes struct {
+	Header             replyHeader
+	Visual             VisualId
+	Class              WindowClass
+	BitGravity         Card8
+	WinGravity         Card8
+	BackingPlanes      Card32
+	BackingPixel       Card32
+	SaveUnder          Bool
+	MapIsInstalled     Bool
+	MapState           MapState
+	OverrideRedirect   Bool
+	Colormap           Colormap
+	AllEventMasks      Card32
+	YourEventMask      Card32
+	DoNotPropagateMask Card16
+	Pad0               [2]Card8
+}
+
+// Geometry is the reply of GetGeometry. The position is relative to the
+// parent window, and excludes the border.
+type Geometry struct {
+	Header      replyHeader
+	Root        WindowId
+	X           Int16
+	Y           Int16
+	Width       Card16
+	Height      Card16
+	BorderWidth Card16
+	Pad0        [10]Card8
+}
+
+type queryTreeReply struct {
+	Header         replyHeader
+	Root           WindowId
+	Parent         WindowId
+	ChildrenLength Card16
+	Pad0           [14]Card8
+	Children       []WindowId `lengthField:"ChildrenLength"`
+}
+
+type translateCoordinatesReply struct {
+	Header replyHeader
+	Child  WindowId
+	DstX   Int16
+	DstY   Int16
+}
+
+// QueryTree returns the root and parent of a window, and its children in
+// stacking order from bottom to top.
+func (b *Backend) QueryTree(window WindowId) (root, parent WindowId, children []WindowId, err error) {
+	seq := b.beginRequest(OpQueryTree, 0, 8)
+	b.write(window)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("querying tree of window %d: %w", window, err)
+	}
+
+	var reply queryTreeReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("querying tree of window %d: %w", window, err)
+	}
+	return reply.Root, reply.Parent, reply.Children, nil
+}
+
+// GetGeometry returns the position and size of a window or pixmap.
+func (b *Backend) GetGeometry(d Drawable) (g Geometry, err error) {
+	seq := b.beginRequest(OpGetGeometry, 0, 8)
+	b.write(d)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return g, fmt.Errorf("getting geometry of drawable %d: %w", d, err)
+	}
+
+	err = b.decode(buf, &g)
+	if err != nil {
+		return g, fmt.Errorf("getting geometry of drawable %d: %w", d, err)
+	}
+	return g, nil
+}
+
+// GetWindowAttributes returns the attributes of a window.
+func (b *Backend) GetWindowAttributes(window WindowId) (attrs WindowAttributes, err error) {
+	seq := b.beginRequest(OpGetWindowAttributes, 0, 8)
+	b.write(window)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return attrs, fmt.Errorf("getting attributes of window %d: %w", window, err)
+	}
+
+	err = b.decode(buf, &attrs)
+	if err != nil {
+		return attrs, fmt.Errorf("getting attributes of window %d: %w", window, err)
+	}
+	return attrs, nil
+}
+
+// TranslateCoordinates converts a position relative to the src window to
+// one relative to the dst window. It also returns the child of dst that
+// contains the position, if any.
+func (b *Backend) TranslateCoordinates(src, dst WindowId, x, y int) (dstX, dstY int, child WindowId, err error) {
+	seq := b.beginRequest(OpTranslateCoordinates, 0, 16)
+	b.write(src)
+	b.write(dst)
+	b.write(Int16(x))
+	b.write(Int16(y))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("translating coordinates: %w", err)
+	}
+
+	var reply translateCoordinatesReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("translating coordinates: %w", err)
+	}
+	return int(reply.DstX), int(reply.DstY), reply.Child, nil
+}
+
+// ClientList returns the top-level windows managed by the window manager,
+// in mapping order, as listed in _NET_CLIENT_LIST.
+func (b *Backend) ClientList() ([]WindowId, error) {
+	values, err := b.rootWindows("_NET_CLIENT_LIST")
+	if err != nil {
+		return nil, fmt.Errorf("getting client list: %w", err)
+	}
+	return values, nil
+}
+
+// ActiveWindow returns the window with the focus according to the window
+// manager, or zero if there is none.
+func (b *Backend) ActiveWindow() (WindowId, error) {
+	values, err := b.rootWindows("_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return 0, fmt.Errorf("getting active window: %w", err)
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+	return values[0], nil
+}
+
+// rootWindows reads a WINDOW list property of the root window. A missing
+// property, as without an EWMH window manager, is an empty list.
+func (b *Backend) rootWindows(name string) ([]WindowId, error) {
+	atom, err := b.atom(name)
+	if err != nil {
+		return nil, err
+	}
+
+	prop, err := b.getProperty(b.rootScreen().Root, atom, AtomWindow, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	if prop.Format != 32 {
+		return nil, nil
+	}
+
+	values := b.values32(prop)
+	windows := make([]WindowId, len(values))
+	for i, v := range values {
+		windows[i] = WindowId(v)
+	}
+	return windows, nil
+}
+
+// WindowState is the set of _NET_WM_STATE flags of a window.
+type WindowState uint16
+
+const (
+	StateModal WindowState = 1 << iota
+	StateSticky
+	StateMaximizedVert
+	StateMaximizedHorz
+	StateShaded
+	StateSkipTaskbar
+	StateSkipPager
+	StateHidden
+	StateFullscreen
+	StateAbove
+	StateBelow
+	StateDemandsAttention
+	StateFocused
+)
+
+var windowStateNames = [...]string{
+	"_NET_WM_STATE_MODAL",
+	"_NET_WM_STATE_STICKY",
+	"_NET_WM_STATE_MAXIMIZED_VERT",
+	"_NET_WM_STATE_MAXIMIZED_HORZ",
+	"_NET_WM_STATE_SHADED",
+	"_NET_WM_STATE_SKIP_TASKBAR",
+	"_NET_WM_STATE_SKIP_PAGER",
+	"_NET_WM_STATE_HIDDEN",
+	"_NET_WM_STATE_FULLSCREEN",
+	"_NET_WM_STATE_ABOVE",
+	"_NET_WM_STATE_BELOW",
+	"_NET_WM_STATE_DEMANDS_ATTENTION",
+	"_NET_WM_STATE_FOCUSED",
+}
+
+// WindowInfo describes a window and its descendants.
+type WindowInfo struct {
+	Id WindowId
+
+	// Title is _NET_WM_NAME, or WM_NAME for clients without EWMH support.
+	Title string
+
+	// Instance and Class are the two strings of WM_CLASS.
+	Instance string
+	Class    string
+
+	// PID is the _NET_WM_PID of the client, or zero if it is unknown.
+	PID int
+
+	// Bounds is the area of the window in root window coordinates,
+	// without the border.
+	Bounds      image.Rectangle
+	BorderWidth int
+
+	MapState         MapState
+	OverrideRedirect bool
+	State            WindowState
+	Active           bool
+
+	// Children are the child windows in stacking order, from bottom to
+	// top.
+	Children []*WindowInfo
+}
+
+// InspectWindow describes a window without its children.
+func (b *Backend) InspectWindow(window WindowId) (info *WindowInfo, err error) {
+	active, err := b.ActiveWindow()
+	if err != nil {
+		return nil, err
+	}
+	return b.inspectWindow(window, active)
+}
+
+// WindowTree describes a window and all of its descendants. Windows
+// destroyed while the tree is read are left out.
+func (b *Backend) WindowTree(window WindowId) (info *WindowInfo, err error) {
+	active, err := b.ActiveWindow()
+	if err != nil {
+		return nil, err
+	}
+	return b.windowTree(window, active)
+}
+
+// TopLevelWindows describes the windows of the client list with their
+// descendants. Without an EWMH window manager, the children of the root
+// window are used instead.
+func (b *Backend) TopLevelWindows() (infos []*WindowInfo, err error) {
+	windows, err := b.ClientList()
+	if err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		_, _, windows, err = b.QueryTree(b.rootScreen().Root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	active, err := b.ActiveWindow()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, window := range windows {
+		info, err := b.windowTree(window, active)
+		if isWindowGone(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (b *Backend) windowTree(window, active WindowId) (info *WindowInfo, err error) {
+	info, err = b.inspectWindow(window, active)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, children, err := b.QueryTree(window)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		childInfo, err := b.windowTree(child, active)
+		if isWindowGone(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		info.Children = append(info.Children, childInfo)
+	}
+	return info, nil
+}
+
+func (b *Backend) inspectWindow(window, active WindowId) (info *WindowInfo, err error) {
+	attrs, err := b.GetWindowAttributes(window)
+	if err != nil {
+		return nil, err
+	}
+	g, err := b.GetGeometry(Drawable(window))
+	if err != nil {
+		return nil, err
+	}
+	x, y, _, err := b.TranslateCoordinates(window, g.Root, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	info = &WindowInfo{
+		Id:               window,
+		Bounds:           image.Rect(x, y, x+int(g.Width), y+int(g.Height)),
+		BorderWidth:      int(g.BorderWidth),
+		MapState:         attrs.MapState,
+		OverrideRedirect: attrs.OverrideRedirect == True,
+		Active:           window == active,
+	}
+
+	info.Title, err = b.windowTitle(window)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := b.getProperty(window, AtomWMClass, AtomString, 1024)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(class.Value), "\x00", 3)
+	info.Instance = parts[0]
+	if len(parts) > 1 {
+		info.Class = parts[1]
+	}
+
+	atoms, err := b.internAtoms(append([]string{"_NET_WM_PID", "_NET_WM_STATE"}, windowStateNames[:]...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := b.getProperty(window, atoms[0], AtomCardinal, 4)
+	if err != nil {
+		return nil, err
+	}
+	if pid.Format == 32 && len(pid.Value) == 4 {
+		info.PID = int(b.values32(pid)[0])
+	}
+
+	state, err := b.getProperty(window, atoms[1], AtomAtom, 1024)
+	if err != nil {
+		return nil, err
+	}
+	if state.Format == 32 {
+		for _, v := range b.values32(state) {
+			for i, atom := range atoms[2:] {
+				if Atom(v) == atom {
+					info.State |= 1 << i
+				}
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// windowTitle reads _NET_WM_NAME, falling back to WM_NAME. Both are read
+// whatever their type, as the server returns no value for another type
+// than the one asked for, and some clients set _NET_WM_NAME as a STRING.
+func (b *Backend) windowTitle(window WindowId) (string, error) {
+	netWMName, err := b.atom("_NET_WM_NAME")
+	if err != nil {
+		return "", err
+	}
+
+	name, err := b.getProperty(window, netWMName, AnyPropertyType, 4096)
+	if err != nil {
+		return "", err
+	}
+	if len(name.Value) == 0 {
+		name, err = b.getProperty(window, AtomWMName, AnyPropertyType, 4096)
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(name.Value), nil
+}
+
+// isWindowGone reports whether err is caused by a window that no longer
+// exists.
+func isWindowGone(err error) bool {
+	var perr *ProtocolError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	return perr.Code == errWindow || perr.Code == errDrawable
+}
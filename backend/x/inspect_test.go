@@ -0,0 +1,201 @@
+package x
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestTopLevelWindows(t *testing.T) {
+	const (
+		root   = 0x100
+		client = 0x300
+		child  = 0x301
+		gone   = 0x399
+	)
+
+	var s *fakeServer
+	property := func(window uint32, name string) (typ Atom, format int, value []byte) {
+		switch {
+		case window == root && name == "_NET_CLIENT_LIST":
+			value = make([]byte, 8)
+			binary.BigEndian.PutUint32(value[0:], gone)
+			binary.BigEndian.PutUint32(value[4:], client)
+			return AtomWindow, 32, value
+		case window == root && name == "_NET_ACTIVE_WINDOW":
+			value = make([]byte, 4)
+			binary.BigEndian.PutUint32(value, client)
+			return AtomWindow, 32, value
+		case window == client && name == "_NET_WM_NAME":
+			return s.atom("UTF8_STRING"), 8, []byte("Termínal")
+		case window == client && name == "WM_CLASS":
+			return AtomString, 8, []byte("term\x00Term\x00")
+		case window == client && name == "_NET_WM_PID":
+			value = make([]byte, 4)
+			binary.BigEndian.PutUint32(value, 4242)
+			return AtomCardinal, 32, value
+		case window == client && name == "_NET_WM_STATE":
+			value = make([]byte, 8)
+			binary.BigEndian.PutUint32(value[0:], uint32(s.atom("_NET_WM_STATE_FULLSCREEN")))
+			binary.BigEndian.PutUint32(value[4:], uint32(s.atom("_NET_WM_STATE_ABOVE")))
+			return AtomAtom, 32, value
+		case window == child && name == "WM_NAME":
+			return AtomString, 8, []byte("inner")
+		}
+		return AtomNone, 0, nil
+	}
+
+	atomName := func(atom Atom) string {
+		switch atom {
+		case AtomWMName:
+			return "WM_NAME"
+		case AtomWMClass:
+			return "WM_CLASS"
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for name, a := range s.atoms {
+			if a == atom {
+				return name
+			}
+		}
+		return ""
+	}
+
+	var b *Backend
+	b, s = newFakeBackend(t, func(req fakeRequest) []byte {
+		var window uint32
+		if len(req.Body) >= 4 {
+			window = binary.BigEndian.Uint32(req.Body)
+		}
+		if window == gone && req.Opcode != OpGetProperty {
+			packet := make([]byte, 32)
+			packet[1] = byte(errWindow)
+			binary.BigEndian.PutUint16(packet[2:], uint16(req.Sequence))
+			binary.BigEndian.PutUint32(packet[4:], gone)
+			packet[10] = byte(req.Opcode)
+			s.conn.Write(packet)
+			return nil
+		}
+
+		reply := make([]byte, 32)
+		switch req.Opcode {
+		case OpGetProperty:
+			typ, format, value := property(window, atomName(Atom(binary.BigEndian.Uint32(req.Body[4:]))))
+			reply[1] = byte(format)
+			binary.BigEndian.PutUint32(reply[8:], uint32(typ))
+			if format != 0 {
+				binary.BigEndian.PutUint32(reply[16:], uint32(len(value)*8/format))
+			}
+			return append(reply, value...)
+		case OpGetWindowAttributes:
+			reply = make([]byte, 44)
+			reply[26] = byte(IsViewable)
+			return reply
+		case OpGetGeometry:
+			binary.BigEndian.PutUint32(reply[8:], root)
+			binary.BigEndian.PutUint16(reply[16:], 200)
+			binary.BigEndian.PutUint16(reply[18:], 100)
+			return reply
+		case OpTranslateCoordinates:
+			x, y := 10, 20
+			if window == child {
+				x, y = 15, 40
+			}
+			binary.BigEndian.PutUint16(reply[12:], uint16(x))
+			binary.BigEndian.PutUint16(reply[14:], uint16(y))
+			return reply
+		case OpQueryTree:
+			binary.BigEndian.PutUint32(reply[8:], root)
+			if window == client {
+				binary.BigEndian.PutUint16(reply[16:], 1)
+				reply = append(reply, 0, 0, 0, 0)
+				binary.BigEndian.PutUint32(reply[32:], child)
+			}
+			return reply
+		}
+		return nil
+	})
+
+	infos, err := b.TopLevelWindows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("%d top-level windows, want 1", len(infos))
+	}
+
+	info := infos[0]
+	if info.Id != client || info.Title != "Termínal" || info.Instance != "term" || info.Class != "Term" {
+		t.Errorf("client window %d %q %q %q", info.Id, info.Title, info.Instance, info.Class)
+	}
+	if info.PID != 4242 {
+		t.Errorf("PID %d, want 4242", info.PID)
+	}
+	if info.State != StateFullscreen|StateAbove || !info.Active || info.MapState != IsViewable {
+		t.Errorf("state %#x, active %v, map state %d", info.State, info.Active, info.MapState)
+	}
+	if info.Bounds != image.Rect(10, 20, 210, 120) {
+		t.Errorf("bounds %v", info.Bounds)
+	}
+
+	if len(info.Children) != 1 {
+		t.Fatalf("%d children, want 1", len(info.Children))
+	}
+	if c := info.Children[0]; c.Id != child || c.Title != "inner" || c.Active || c.Bounds.Min != image.Pt(15, 40) {
+		t.Errorf("child window %d %q active %v at %v", c.Id, c.Title, c.Active, c.Bounds.Min)
+	}
+}
+
+func TestWindowTitle(t *testing.T) {
+	const (
+		latin  = 0x300
+		legacy = 0x301
+	)
+
+	var s *fakeServer
+	var b *Backend
+	b, s = newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode != OpGetProperty {
+			return nil
+		}
+		window := binary.BigEndian.Uint32(req.Body)
+		property := Atom(binary.BigEndian.Uint32(req.Body[4:]))
+		requested := Atom(binary.BigEndian.Uint32(req.Body[8:]))
+
+		var typ Atom
+		var value []byte
+		switch {
+		case window == latin && property == s.atom("_NET_WM_NAME"):
+			typ, value = AtomString, []byte("caf\xe9")
+		case window == legacy && property == AtomWMName:
+			typ, value = AtomString, []byte("xterm")
+		}
+
+		// Like the server, values of another type are not returned.
+		reply := make([]byte, 32)
+		binary.BigEndian.PutUint32(reply[8:], uint32(typ))
+		if typ == AtomNone || requested != AnyPropertyType && requested != typ {
+			return reply
+		}
+		reply[1] = 8
+		binary.BigEndian.PutUint32(reply[16:], uint32(len(value)))
+		return append(reply, value...)
+	})
+
+	for _, tt := range []struct {
+		window WindowId
+		want   string
+	}{
+		{latin, "caf\xe9"},
+		{legacy, "xterm"},
+	} {
+		title, err := b.windowTitle(tt.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title != tt.want {
+			t.Errorf("title of window %#x is %q, want %q", tt.window, title, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package x
+
+import (
+	"fmt"
+)
+
+type Keysym uint32
+
+const (
+	KeysymNoSymbol  Keysym = 0
+	KeysymSpace     Keysym = 0x0020
+	KeysymBackSpace Keysym = 0xff08
+	KeysymTab       Keysym = 0xff09
+	KeysymReturn    Keysym = 0xff0d
+	KeysymPause     Keysym = 0xff13
+	KeysymEscape    Keysym = 0xff1b
+	KeysymHome      Keysym = 0xff50
+	KeysymLeft      Keysym = 0xff51
+	KeysymUp        Keysym = 0xff52
+	KeysymRight     Keysym = 0xff53
+	KeysymDown      Keysym = 0xff54
+	KeysymPageUp    Keysym = 0xff55
+	KeysymPageDown  Keysym = 0xff56
+	KeysymEnd       Keysym = 0xff57
+	KeysymPrint     Keysym = 0xff61
+	KeysymInsert    Keysym = 0xff63
+	KeysymMenu      Keysym = 0xff67
+	KeysymNumLock   Keysym = 0xff7f
+	KeysymF1        Keysym = 0xffbe
+	KeysymShiftL    Keysym = 0xffe1
+	KeysymShiftR    Keysym = 0xffe2
+	KeysymControlL  Keysym = 0xffe3
+	KeysymControlR  Keysym = 0xffe4
+	KeysymCapsLock  Keysym = 0xffe5
+	KeysymMetaL     Keysym = 0xffe7
+	KeysymMetaR     Keysym = 0xffe8
+	KeysymAltL      Keysym = 0xffe9
+	KeysymAltR      Keysym = 0xffea
+	KeysymSuperL    Keysym = 0xffeb
+	KeysymSuperR    Keysym = 0xffec
+	KeysymDelete    Keysym = 0xffff
+)
+
+// RuneKeysym returns the keysym that produces r. Latin-1 characters have
+// their own keysyms, the rest of Unicode is mapped with the 0x01000000
+// offset.
+func RuneKeysym(r rune) Keysym {
+	if (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff) {
+		return Keysym(r)
+	}
+	switch r {
+	case '\b':
+		return KeysymBackSpace
+	case '\t':
+		return KeysymTab
+	case '\n', '\r':
+		return KeysymReturn
+	case 0x1b:
+		return KeysymEscape
+	case 0x7f:
+		return KeysymDelete
+	}
+	return Keysym(0x01000000 | r)
+}
+
+// keymap is the keyboard mapping of the server, with the keysyms of each
+// keycode and the keycodes of each modifier.
+type keymap struct {
+	minKeycode        KeyCode
+	keysymsPerKeycode int
+	keysyms           []Keysym
+	modifiers         [8][]KeyCode
+}
+
+type getKeyboardMappingReply struct {
+	Header replyHeader
+	Pad0   [24]Card8
+}
+
+type getModifierMappingReply struct {
+	Header replyHeader
+	Pad0   [24]Card8
+}
+
+// keyboardMapping returns the keyboard mapping, loading it the first time
+// it is used and after it changes.
+func (b *Backend) keyboardMapping() (km *keymap, err error) {
+	if b.keymap != nil {
+		return b.keymap, nil
+	}
+
+	first := b.initResponse.MinKeyCode
+	count := int(b.initResponse.MaxKeyCode) - int(first) + 1
+
+	seq := b.beginRequest(OpGetKeyboardMapping, 0, 8)
+	b.write(first)
+	b.write(Card8(count))
+	b.writeUnused(2)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return nil, fmt.Errorf("getting keyboard mapping: %w", err)
+	}
+
+	var reply getKeyboardMappingReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("getting keyboard mapping: %w", err)
+	}
+
+	km = &keymap{
+		minKeycode:        first,
+		keysymsPerKeycode: int(reply.Header.Data),
+	}
+	keysyms := buf[32:]
+	n := km.keysymsPerKeycode * count
+	if len(keysyms) < 4*n {
+		return nil, fmt.Errorf("getting keyboard mapping: %d bytes for %d keycodes of %d keysyms: %w", len(keysyms), count, km.keysymsPerKeycode, ErrMalformed)
+	}
+	keysyms = keysyms[:4*n]
+	km.keysyms = make([]Keysym, len(keysyms)/4)
+	for i := range km.keysyms {
+		km.keysyms[i] = Keysym(b.byteOrder.Uint32(keysyms[4*i:]))
+	}
+
+	seq = b.beginRequest(OpGetModifierMapping, 0, 4)
+	buf, err = b.waitReply(seq)
+	if err != nil {
+		return nil, fmt.Errorf("getting modifier mapping: %w", err)
+	}
+
+	var modReply getModifierMappingReply
+	err = b.decode(buf, &modReply)
+	if err != nil {
+		return nil, fmt.Errorf("getting modifier mapping: %w", err)
+	}
+
+	perModifier := int(modReply.Header.Data)
+	keycodes := buf[32:]
+	if len(keycodes) < 8*perModifier {
+		return nil, fmt.Errorf("modifier mapping of %d bytes is too short", len(keycodes))
+	}
+	for m := range km.modifiers {
+		for _, kc := range keycodes[m*perModifier : (m+1)*perModifier] {
+			if kc != 0 {
+				km.modifiers[m] = append(km.modifiers[m], KeyCode(kc))
+			}
+		}
+	}
+
+	b.keymap = km
+	return km, nil
+}
+
+// keysym returns the keysym in the given column of the keycode.
+func (km *keymap) keysym(kc KeyCode, column int) Keysym {
+	if kc < km.minKeycode || column >= km.keysymsPerKeycode {
+		return KeysymNoSymbol
+	}
+	i := int(kc-km.minKeycode)*km.keysymsPerKeycode + column
+	if i >= len(km.keysyms) {
+		return KeysymNoSymbol
+	}
+	return km.keysyms[i]
+}
+
+// keycodes returns the keycodes that produce the keysym, along with the
+// column it was found in, which is 1 for keysyms typed with Shift.
+func (km *keymap) keycodes(sym Keysym) (kcs []KeyCode, column int) {
+	for column = 0; column < km.keysymsPerKeycode && column < 2; column++ {
+		for i := 0; i*km.keysymsPerKeycode < len(km.keysyms); i++ {
+			if km.keysyms[i*km.keysymsPerKeycode+column] == sym {
+				kcs = append(kcs, km.minKeycode+KeyCode(i))
+			}
+		}
+		if len(kcs) > 0 {
+			return kcs, column
+		}
+	}
+	return nil, 0
+}
+
+// modifierMask returns the modifier mask bound to any of the keysyms, or 0
+// if none of them is a modifier key.
+func (km *keymap) modifierMask(syms ...Keysym) Card16 {
+	for m, kcs := range km.modifiers {
+		for _, kc := range kcs {
+			for col := 0; col < km.keysymsPerKeycode; col++ {
+				for _, sym := range syms {
+					if km.keysym(kc, col) == sym {
+						return 1 << m
+					}
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,167 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Minor opcodes of the RANDR extension.
+const (
+	randrQueryVersion Card8 = 0
+	randrGetMonitors  Card8 = 42
+)
+
+const (
+	randrMajorVersion = 1
+	randrMinorVersion = 5
+)
+
+type randrQueryVersionReply struct {
+	Header       replyHeader
+	MajorVersion Card32
+	MinorVersion Card32
+}
+
+// MonitorInfo describes a monitor showing part of the screen.
+type MonitorInfo struct {
+	Name          Atom
+	Primary       Bool
+	Automatic     Bool
+	OutputsLength Card16
+	X             Int16
+	Y             Int16
+	Width         Card16
+	Height        Card16
+	WidthMM       Card32
+	HeightMM      Card32
+	Outputs       []Card32 `lengthField:"OutputsLength"`
+}
+
+// Bounds returns the area of the screen shown by the monitor.
+func (m MonitorInfo) Bounds() image.Rectangle {
+	return image.Rect(int(m.X), int(m.Y), int(m.X)+int(m.Width), int(m.Y)+int(m.Height))
+}
+
+type randrGetMonitorsReply struct {
+	Header         replyHeader
+	Timestamp      Timestamp
+	MonitorsLength Card32
+	OutputsLength  Card32
+	Pad0           [12]Card8
+	Monitors       []MonitorInfo `lengthField:"MonitorsLength"`
+}
+
+// randrExtension queries the RANDR extension, which must support monitors.
+func (b *Backend) randrExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("RANDR")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("RANDR: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, randrQueryVersion, 12)
+	b.write(Card32(randrMajorVersion))
+	b.write(Card32(randrMinorVersion))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing RANDR extension: %w", err)
+	}
+
+	var reply randrQueryVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing RANDR extension: %w", err)
+	}
+	if reply.MajorVersion < 1 || reply.MajorVersion == 1 && reply.MinorVersion < 5 {
+		return ext, fmt.Errorf("RANDR %d.%d without monitors: %w",
+			reply.MajorVersion, reply.MinorVersion, ErrMissingExtension)
+	}
+
+	ext.initialized = true
+	b.extensions["RANDR"] = ext
+	return ext, nil
+}
+
+// Monitors returns the active monitors of the screen. Without RANDR 1.5,
+// the whole screen is reported as a single primary monitor.
+func (b *Backend) Monitors() ([]MonitorInfo, error) {
+	screen := b.rootScreen()
+	whole := []MonitorInfo{{
+		Primary:  True,
+		Width:    screen.WidthInPixels,
+		Height:   screen.HeightInPixels,
+		WidthMM:  Card32(screen.WidthInMillimiters),
+		HeightMM: Card32(screen.HeightInMillimiters),
+	}}
+
+	ext, err := b.randrExtension()
+	if errors.Is(err, ErrMissingExtension) {
+		return whole, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting monitors: %w", err)
+	}
+
+	seq := b.beginExtensionRequest(ext, randrGetMonitors, 12)
+	b.write(screen.Root)
+	b.write(True) // Active monitors only
+	b.writeUnused(3)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return nil, fmt.Errorf("getting monitors: %w", err)
+	}
+
+	var reply randrGetMonitorsReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("getting monitors: %w", err)
+	}
+	if len(reply.Monitors) == 0 {
+		return whole, nil
+	}
+	return reply.Monitors, nil
+}
+
+// monitorAt returns the bounds of the monitor containing p, or of the
+// nearest one when p is outside of all monitors.
+func (b *Backend) monitorAt(p image.Point) (image.Rectangle, error) {
+	monitors, err := b.Monitors()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	best := monitors[0].Bounds()
+	bestDist := -1
+	for _, m := range monitors {
+		r := m.Bounds()
+		if p.In(r) {
+			return r, nil
+		}
+
+		// Distance from p to the closest point of r.
+		q := image.Pt(clamp(p.X, r.Min.X, r.Max.X-1), clamp(p.Y, r.Min.Y, r.Max.Y-1))
+		d := (p.X-q.X)*(p.X-q.X) + (p.Y-q.Y)*(p.Y-q.Y)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = r, d
+		}
+	}
+	return best, nil
+}
+
+func clamp(v, min, max int) int {
+	if v > max {
+		v = max
+	}
+	if v < min {
+		v = min
+	}
+	return v
+}
@@ -0,0 +1,293 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var ErrGrab = errors.New("grab failed")
+
+// PopupKind is the role of a popup window, which decides how it is placed
+// and whether it grabs input.
+type PopupKind int
+
+const (
+	// PopupDropdownMenu opens below its anchor, like the menu of a menu
+	// bar or a combo box.
+	PopupDropdownMenu PopupKind = iota
+	// PopupMenu opens beside its anchor, like a submenu or a context menu
+	// anchored at the pointer.
+	PopupMenu
+	// PopupTooltip opens below its anchor and does not grab input.
+	PopupTooltip
+)
+
+var popupWindowTypes = [...]string{
+	PopupDropdownMenu: "_NET_WM_WINDOW_TYPE_DROPDOWN_MENU",
+	PopupMenu:         "_NET_WM_WINDOW_TYPE_POPUP_MENU",
+	PopupTooltip:      "_NET_WM_WINDOW_TYPE_TOOLTIP",
+}
+
+// Grab statuses of GrabPointer and GrabKeyboard replies.
+const (
+	grabSuccess        = 0
+	grabAlreadyGrabbed = 1
+	grabInvalidTime    = 2
+	grabNotViewable    = 3
+	grabFrozen         = 4
+)
+
+var grabStatusNames = [...]string{
+	grabSuccess:        "success",
+	grabAlreadyGrabbed: "already grabbed",
+	grabInvalidTime:    "invalid time",
+	grabNotViewable:    "not viewable",
+	grabFrozen:         "frozen",
+}
+
+// popupPointerMask selects the pointer events reported to a popup while it
+// grabs the pointer.
+var popupPointerMask = EVButtonPress.Mask() |
+	EVButtonRelease.Mask() |
+	EVEnterWindow.Mask() |
+	EVLeaveWindow.Mask() |
+	EVPointerMotion.Mask()
+
+// Popup is an override-redirect window, which the window manager neither
+// decorates nor moves. Menus grab the pointer and keyboard while they are
+// open, so that all input is reported to them, and are dismissed by a
+// click outside of them.
+type Popup struct {
+	*Window
+
+	Parent *Window
+	Kind   PopupKind
+
+	grabbed bool
+}
+
+// PopupDismissEvent reports that a popup was closed because of a click
+// outside of it.
+type PopupDismissEvent struct {
+	Popup *Popup
+}
+
+// OpenPopup opens a popup of the given size next to anchor, a rectangle in
+// the coordinates of w such as the bounds of a menu item, or an empty
+// rectangle at the pointer. The popup is flipped to the other side of the
+// anchor when it would not fit on the monitor.
+func (w *Window) OpenPopup(kind PopupKind, anchor image.Rectangle, width, height int) (p *Popup, err error) {
+	b := w.b
+	screen := b.rootScreen()
+
+	x, y, _, err := b.TranslateCoordinates(w.Id, screen.Root, anchor.Min.X, anchor.Min.Y)
+	if err != nil {
+		return nil, fmt.Errorf("opening popup: %w", err)
+	}
+	anchor = anchor.Add(image.Pt(x, y).Sub(anchor.Min))
+
+	monitor, err := b.monitorAt(anchor.Min)
+	if err != nil {
+		return nil, fmt.Errorf("opening popup: %w", err)
+	}
+	r := placePopup(kind, anchor, image.Pt(width, height), monitor)
+
+	pw, err := b.createWindow(screen.Root, r,
+		CWBackPixel|CWOverrideRedirect|CWSaveUnder|CWEventMask,
+		screen.BlackPixel, Card32(True), Card32(True), windowEventMask)
+	if err != nil {
+		return nil, fmt.Errorf("opening popup: %w", err)
+	}
+	p = &Popup{Window: pw, Parent: w, Kind: kind}
+
+	atoms, err := b.internAtoms("_NET_WM_WINDOW_TYPE", popupWindowTypes[kind])
+	if err != nil {
+		pw.Close()
+		return nil, fmt.Errorf("opening popup: %w", err)
+	}
+	b.changeProperty32(pw.Id, atoms[0], AtomAtom, Card32(atoms[1]))
+	b.changeProperty32(pw.Id, AtomWMTransientFor, AtomWindow, Card32(w.Id))
+	pw.mapWindow()
+
+	if kind != PopupTooltip {
+		err = p.grab()
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("opening popup: %w", err)
+		}
+	}
+
+	b.popups[pw.Id] = p
+	b.flush()
+	return p, b.err
+}
+
+// placePopup returns the bounds of a popup of the given size, placed
+// against anchor on the side preferred for its kind, or the opposite side
+// when it does not fit on the monitor.
+func placePopup(kind PopupKind, anchor image.Rectangle, size image.Point, monitor image.Rectangle) image.Rectangle {
+	var p image.Point
+	switch kind {
+	case PopupMenu:
+		p = image.Pt(anchor.Max.X, anchor.Min.Y)
+		if p.X+size.X > monitor.Max.X {
+			p.X = anchor.Min.X - size.X
+		}
+		if p.Y+size.Y > monitor.Max.Y {
+			p.Y = anchor.Max.Y - size.Y
+		}
+	default:
+		p = image.Pt(anchor.Min.X, anchor.Max.Y)
+		if p.X+size.X > monitor.Max.X {
+			p.X = anchor.Max.X - size.X
+		}
+		if p.Y+size.Y > monitor.Max.Y {
+			p.Y = anchor.Min.Y - size.Y
+		}
+	}
+
+	// Popups larger than the free space on both sides are kept on the
+	// monitor, even if they cover the anchor.
+	p.X = clamp(p.X, monitor.Min.X, monitor.Max.X-size.X)
+	p.Y = clamp(p.Y, monitor.Min.Y, monitor.Max.Y-size.Y)
+	return image.Rectangle{p, p.Add(size)}
+}
+
+type grabReply struct {
+	Header replyHeader
+}
+
+// grab directs all pointer and keyboard input to the popup, taking the
+// grabs from the popup it was opened from until it closes.
+func (p *Popup) grab() error {
+	err := p.grabDevices()
+	if err != nil {
+		return err
+	}
+	p.grabbed = true
+	p.b.popupGrabs = append(p.b.popupGrabs, p)
+	return nil
+}
+
+func (p *Popup) grabDevices() error {
+	b := p.b
+
+	seq := b.beginRequest(OpGrabPointer, Card8(False), 24)
+	b.write(p.Id)
+	b.write(Card16(popupPointerMask))
+	b.write(GrabModeAsync) // Pointer mode
+	b.write(GrabModeAsync) // Keyboard mode
+	b.write(WindowId(0))   // Confine to
+	b.write(Cursor(0))
+	b.write(Timestamp(0)) // Current time
+
+	err := b.grabStatus(seq, "pointer")
+	if err != nil {
+		return err
+	}
+
+	seq = b.beginRequest(OpGrabKeyboard, Card8(False), 16)
+	b.write(p.Id)
+	b.write(Timestamp(0))  // Current time
+	b.write(GrabModeAsync) // Pointer mode
+	b.write(GrabModeAsync) // Keyboard mode
+	b.writeUnused(2)
+
+	err = b.grabStatus(seq, "keyboard")
+	if err != nil {
+		b.ungrabPointer()
+		return err
+	}
+	return nil
+}
+
+func (b *Backend) grabStatus(seq Card16, device string) error {
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return fmt.Errorf("grabbing %s: %w", device, err)
+	}
+
+	var reply grabReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return fmt.Errorf("grabbing %s: %w", device, err)
+	}
+
+	status := reply.Header.Data
+	if status == grabSuccess {
+		return nil
+	}
+	name := "unknown status"
+	if int(status) < len(grabStatusNames) {
+		name = grabStatusNames[status]
+	}
+	return fmt.Errorf("grabbing %s: %s: %w", device, name, ErrGrab)
+}
+
+func (b *Backend) ungrabPointer() {
+	b.beginRequest(OpUngrabPointer, 0, 8)
+	b.write(Timestamp(0)) // Current time
+}
+
+func (b *Backend) ungrabKeyboard() {
+	b.beginRequest(OpUngrabKeyboard, 0, 8)
+	b.write(Timestamp(0)) // Current time
+}
+
+// Close releases the grabs of the popup and destroys it. The grabs go back
+// to the popup it was opened from, if that one is still open.
+func (p *Popup) Close() {
+	if p.grabbed {
+		p.b.releasePopupGrab(p)
+	}
+	delete(p.b.popups, p.Id)
+	p.Window.Close()
+}
+
+// releasePopupGrab removes a popup from the grabbing ones. If it held the
+// grabs, they are passed back to the previous popup, and released with the
+// last one. Popups that can no longer grab input stop grabbing.
+func (b *Backend) releasePopupGrab(p *Popup) {
+	p.grabbed = false
+	held := false
+	for i, other := range b.popupGrabs {
+		if other == p {
+			held = i == len(b.popupGrabs)-1
+			b.popupGrabs = append(b.popupGrabs[:i], b.popupGrabs[i+1:]...)
+			break
+		}
+	}
+	if !held {
+		return
+	}
+
+	for len(b.popupGrabs) > 0 {
+		last := b.popupGrabs[len(b.popupGrabs)-1]
+		if last.grabDevices() == nil {
+			return
+		}
+		last.grabbed = false
+		b.popupGrabs = b.popupGrabs[:len(b.popupGrabs)-1]
+	}
+	b.ungrabPointer()
+	b.ungrabKeyboard()
+}
+
+// popupClick dismisses a grabbing popup on a button press outside of it.
+// While the pointer is grabbed, presses anywhere are reported relative to
+// the popup.
+func (b *Backend) popupClick(ev ButtonPressEvent) (PopupDismissEvent, bool) {
+	p, ok := b.popups[ev.Event]
+	if !ok || !p.grabbed {
+		return PopupDismissEvent{}, false
+	}
+
+	pt := image.Pt(int(ev.EventX), int(ev.EventY))
+	if pt.In(image.Rect(0, 0, p.Width, p.Height)) {
+		return PopupDismissEvent{}, false
+	}
+
+	p.Close()
+	return PopupDismissEvent{Popup: p}, true
+}
@@ -0,0 +1,180 @@
+package x
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestPlacePopup(t *testing.T) {
+	monitor := image.Rect(0, 0, 1000, 800)
+	size := image.Pt(200, 300)
+
+	tests := []struct {
+		name   string
+		kind   PopupKind
+		anchor image.Rectangle
+		want   image.Point
+	}{
+		{"dropdown below", PopupDropdownMenu, image.Rect(100, 100, 180, 120), image.Pt(100, 120)},
+		{"dropdown above", PopupDropdownMenu, image.Rect(100, 600, 180, 620), image.Pt(100, 300)},
+		{"dropdown right aligned", PopupDropdownMenu, image.Rect(900, 100, 980, 120), image.Pt(780, 120)},
+		{"menu beside", PopupMenu, image.Rect(100, 100, 180, 120), image.Pt(180, 100)},
+		{"menu flipped", PopupMenu, image.Rect(850, 600, 900, 620), image.Pt(650, 320)},
+		{"context menu at pointer", PopupMenu, image.Rect(990, 10, 990, 10), image.Pt(790, 10)},
+		{"tooltip clamped", PopupTooltip, image.Rect(-50, 100, -10, 120), image.Pt(0, 120)},
+	}
+
+	for _, test := range tests {
+		got := placePopup(test.kind, test.anchor, size, monitor)
+		if want := (image.Rectangle{test.want, test.want.Add(size)}); got != want {
+			t.Errorf("%s: placed at %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestPopupDismiss(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		switch req.Opcode {
+		case OpQueryExtension:
+			return extensionReply(req)
+		case OpTranslateCoordinates:
+			reply := make([]byte, 32)
+			binary.BigEndian.PutUint16(reply[12:], 1800)
+			binary.BigEndian.PutUint16(reply[14:], 1000)
+			return reply
+		case OpGrabPointer, OpGrabKeyboard:
+			return make([]byte, 32)
+		}
+		return nil
+	})
+
+	parent, err := b.OpenWindow("parent", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := parent.OpenPopup(PopupDropdownMenu, image.Rect(0, 0, 50, 20), 200, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := s.requestsWithOpcode(OpCreateWindow)
+	body := created[len(created)-1].Body
+	x := int16(binary.BigEndian.Uint16(body[8:]))
+	y := int16(binary.BigEndian.Uint16(body[10:]))
+	if x != 1650 || y != 700 {
+		t.Errorf("popup created at (%d, %d), want flipped to (1650, 700)", x, y)
+	}
+	if n := len(s.requestsWithOpcode(OpGrabKeyboard)); n != 1 {
+		t.Errorf("%d keyboard grabs, want 1", n)
+	}
+
+	inside := b.encodeEvent(&ButtonPressEvent{Code: ButtonPressCode, Detail: 1, Event: p.Id, EventX: 10, EventY: 10})
+	outside := b.encodeEvent(&ButtonPressEvent{Code: ButtonPressCode, Detail: 1, Event: p.Id, EventX: -5, EventY: 10})
+	s.send(inside, outside)
+
+	ev, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ev.(ButtonPressEvent); !ok {
+		t.Fatalf("got %T for a press inside the popup, want ButtonPressEvent", ev)
+	}
+
+	ev, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dismiss, ok := ev.(PopupDismissEvent); !ok || dismiss.Popup != p {
+		t.Fatalf("got %#v for a press outside the popup, want PopupDismissEvent", ev)
+	}
+
+	b.atom("X_TEST_BARRIER")
+	if n := len(s.requestsWithOpcode(OpUngrabPointer)); n != 1 {
+		t.Errorf("%d pointer ungrabs, want 1", n)
+	}
+	if _, ok := b.popups[p.Id]; ok {
+		t.Error("dismissed popup still registered")
+	}
+}
+
+func TestSubmenuGivesGrabBack(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		switch req.Opcode {
+		case OpQueryExtension:
+			return extensionReply(req)
+		case OpTranslateCoordinates, OpGrabPointer, OpGrabKeyboard:
+			return make([]byte, 32)
+		}
+		return nil
+	})
+
+	parent, err := b.OpenWindow("parent", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	menu, err := parent.OpenPopup(PopupDropdownMenu, image.Rect(0, 0, 50, 20), 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submenu, err := menu.OpenPopup(PopupMenu, image.Rect(0, 0, 100, 20), 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submenu.Close()
+	b.atom("X_TEST_BARRIER")
+	grabs := s.requestsWithOpcode(OpGrabPointer)
+	if len(grabs) != 3 || WindowId(binary.BigEndian.Uint32(grabs[2].Body)) != menu.Id {
+		t.Errorf("pointer not grabbed back by the menu: %v", grabs)
+	}
+	if n := len(s.requestsWithOpcode(OpUngrabPointer)); n != 0 {
+		t.Errorf("%d pointer ungrabs with the menu open, want 0", n)
+	}
+
+	menu.Close()
+	b.atom("X_TEST_BARRIER_2")
+	if n := len(s.requestsWithOpcode(OpUngrabPointer)); n != 1 {
+		t.Errorf("%d pointer ungrabs, want 1", n)
+	}
+	if len(b.popupGrabs) != 0 {
+		t.Errorf("popups still grabbing: %v", b.popupGrabs)
+	}
+}
+
+func TestDestroyedSubmenu(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		switch req.Opcode {
+		case OpQueryExtension:
+			return extensionReply(req)
+		case OpTranslateCoordinates, OpGrabPointer, OpGrabKeyboard:
+			return make([]byte, 32)
+		}
+		return nil
+	})
+
+	parent, err := b.OpenWindow("parent", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	menu, err := parent.OpenPopup(PopupDropdownMenu, image.Rect(0, 0, 50, 20), 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submenu, err := menu.OpenPopup(PopupMenu, image.Rect(0, 0, 100, 20), 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.send(b.encodeEvent(&DestroyNotifyEvent{Code: DestroyNotifyCode, Event: submenu.Id, Window: submenu.Id}))
+	_, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.popups[submenu.Id]; ok {
+		t.Error("destroyed popup still registered")
+	}
+	if len(b.popupGrabs) != 1 || b.popupGrabs[0] != menu {
+		t.Errorf("popups grabbing %v, want the menu", b.popupGrabs)
+	}
+}
@@ -0,0 +1,283 @@
+package x
+
+import (
+	"fmt"
+	"image"
+)
+
+// Minor opcodes of the Present extension.
+const (
+	presentQueryVersion Card8 = 0
+	presentPixmap       Card8 = 1
+	presentNotifyMSC    Card8 = 2
+	presentSelectInput  Card8 = 3
+)
+
+const (
+	presentMajorVersion = 1
+	presentMinorVersion = 2
+)
+
+// Present generic event types.
+const (
+	PresentConfigureNotifyType Card16 = 0
+	PresentCompleteNotifyType  Card16 = 1
+	PresentIdleNotifyType      Card16 = 2
+)
+
+// Present event selection masks.
+const (
+	PresentConfigureNotifyMask Card32 = 1 << 0
+	PresentCompleteNotifyMask  Card32 = 1 << 1
+	PresentIdleNotifyMask      Card32 = 1 << 2
+)
+
+type PresentCompleteKind Card8
+
+const (
+	PresentCompleteKindPixmap PresentCompleteKind = iota
+	PresentCompleteKindNotifyMSC
+)
+
+type PresentCompleteMode Card8
+
+const (
+	PresentCompleteModeCopy PresentCompleteMode = iota
+	PresentCompleteModeFlip
+	PresentCompleteModeSkip
+	PresentCompleteModeSuboptimalCopy
+)
+
+type PresentCompleteNotifyEvent struct {
+	Code      Card8
+	Extension Card8
+	Sequence  Card16
+	Length    Card32
+	EventType Card16
+	Kind      PresentCompleteKind
+	Mode      PresentCompleteMode
+	EventId   Card32
+	Window    WindowId
+	Serial    Card32
+	UST       Card64
+	MSC       Card64
+}
+
+type PresentIdleNotifyEvent struct {
+	Code      Card8
+	Extension Card8
+	Sequence  Card16
+	Length    Card32
+	EventType Card16
+	Pad0      Card16
+	EventId   Card32
+	Window    WindowId
+	Serial    Card32
+	Pixmap    Pixmap
+	IdleFence Card32
+}
+
+// FrameEvent tells the application that a frame reached the screen of a
+// window, or that the vertical blank requested with RequestFrame happened,
+// and that drawing the next frame can start.
+type FrameEvent struct {
+	Window WindowId
+	// MSC is the media stream counter, the number of vertical blanks of
+	// the CRTC showing the window.
+	MSC uint64
+	// UST is the time of the vertical blank in microseconds.
+	UST uint64
+	// Skipped is set if the presented frame was replaced by a later one
+	// before it was shown.
+	Skipped bool
+}
+
+type presentQueryVersionReply struct {
+	Header       replyHeader
+	MajorVersion Card32
+	MinorVersion Card32
+}
+
+// presentExtension queries the Present extension, negotiating its version
+// the first time it is used.
+func (b *Backend) presentExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("Present")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("Present: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, presentQueryVersion, 12)
+	b.write(Card32(presentMajorVersion))
+	b.write(Card32(presentMinorVersion))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing Present extension: %w", err)
+	}
+
+	var reply presentQueryVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing Present extension: %w", err)
+	}
+
+	b.registerGenericEvent(ext.majorOpcode, PresentCompleteNotifyType, PresentCompleteNotifyEvent{})
+	b.registerGenericEvent(ext.majorOpcode, PresentIdleNotifyType, PresentIdleNotifyEvent{})
+
+	ext.initialized = true
+	b.extensions["Present"] = ext
+	return ext, nil
+}
+
+// windowPresent holds the pixmaps a window presents its frames from.
+// Pixmaps are reused once the server reports them idle.
+type windowPresent struct {
+	ext     extension
+	eventId Card32
+	serial  Card32
+	pixmaps []presentBuffer
+}
+
+type presentBuffer struct {
+	pixmap Pixmap
+	size   image.Point
+	idle   bool
+}
+
+// initPresent selects Present events for the window, so frames are
+// delivered through pixmaps and paced by the vertical blank.
+func (w *Window) initPresent() error {
+	ext, err := w.b.presentExtension()
+	if err != nil {
+		return err
+	}
+
+	eventId := w.b.allocId()
+	w.b.beginExtensionRequest(ext, presentSelectInput, 16)
+	w.b.write(eventId)
+	w.b.write(w.Id)
+	w.b.write(PresentCompleteNotifyMask | PresentIdleNotifyMask)
+
+	w.present = &windowPresent{
+		ext:     ext,
+		eventId: eventId,
+	}
+	return w.b.err
+}
+
+func (w *Window) closePresent() {
+	if w.present == nil {
+		return
+	}
+	for _, p := range w.present.pixmaps {
+		w.b.freePixmap(p.pixmap)
+	}
+	w.present = nil
+}
+
+// idlePixmap returns an idle pixmap of the given size, creating one if all
+// are still in use by the server.
+func (w *Window) idlePixmap(size image.Point) *presentBuffer {
+	p := w.present
+	for i := range p.pixmaps {
+		if p.pixmaps[i].idle && p.pixmaps[i].size == size {
+			p.pixmaps[i].idle = false
+			return &p.pixmaps[i]
+		}
+	}
+
+	p.pixmaps = append(p.pixmaps, presentBuffer{
+		pixmap: w.b.createPixmap(Drawable(w.Id), w.format.depth, size.X, size.Y),
+		size:   size,
+	})
+	return &p.pixmaps[len(p.pixmaps)-1]
+}
+
+// presentPixmap draws img to an idle pixmap and queues it for presentation
+// at the vertical blank where the MSC reaches targetMSC, or at the next one
+// if it already did.
+func (w *Window) presentPixmap(img *image.RGBA, targetMSC uint64) error {
+	size := img.Bounds().Size()
+	pixmap := w.idlePixmap(size).pixmap
+
+	err := w.b.putImage(Drawable(pixmap), w.gc, &w.format, img, img.Bounds(), image.Point{})
+	if err != nil {
+		return err
+	}
+
+	w.present.serial++
+	w.b.beginExtensionRequest(w.present.ext, presentPixmap, 72)
+	w.b.write(w.Id)
+	w.b.write(pixmap)
+	w.b.write(w.present.serial)
+	w.b.write(Card32(0)) // Valid region
+	w.b.write(Card32(0)) // Update region
+	w.b.write(Int16(0))  // X offset
+	w.b.write(Int16(0))  // Y offset
+	w.b.write(Card32(0)) // Target CRTC
+	w.b.write(Card32(0)) // Wait fence
+	w.b.write(Card32(0)) // Idle fence
+	w.b.write(Card32(0)) // Options
+	w.b.writeUnused(4)
+	w.b.write(Card64(targetMSC))
+	w.b.write(Card64(0)) // Divisor
+	w.b.write(Card64(0)) // Remainder
+	return w.b.err
+}
+
+// RequestFrame asks for a FrameEvent at the next vertical blank, for
+// applications that have nothing to present but need to keep animating.
+func (w *Window) RequestFrame() error {
+	if w.present == nil {
+		return fmt.Errorf("requesting frame: Present: %w", ErrMissingExtension)
+	}
+
+	w.present.serial++
+	w.b.beginExtensionRequest(w.present.ext, presentNotifyMSC, 40)
+	w.b.write(w.Id)
+	w.b.write(w.present.serial)
+	w.b.writeUnused(4)
+	w.b.write(Card64(0)) // Target MSC
+	w.b.write(Card64(1)) // Divisor
+	w.b.write(Card64(0)) // Remainder
+
+	w.b.flush()
+	return w.b.err
+}
+
+// presentIdle marks a pixmap as reusable. Pixmaps of an outdated size are
+// freed instead.
+func (w *Window) presentIdle(ev PresentIdleNotifyEvent) {
+	if w.present == nil {
+		return
+	}
+
+	size := image.Pt(w.Width, w.Height)
+	pixmaps := w.present.pixmaps[:0]
+	for _, p := range w.present.pixmaps {
+		if p.pixmap == ev.Pixmap {
+			if p.size != size {
+				w.b.freePixmap(p.pixmap)
+				continue
+			}
+			p.idle = true
+		}
+		pixmaps = append(pixmaps, p)
+	}
+	w.present.pixmaps = pixmaps
+}
+
+func (w *Window) presentComplete(ev PresentCompleteNotifyEvent) FrameEvent {
+	return FrameEvent{
+		Window:  w.Id,
+		MSC:     uint64(ev.MSC),
+		UST:     uint64(ev.UST),
+		Skipped: ev.Mode == PresentCompleteModeSkip,
+	}
+}
@@ -0,0 +1,125 @@
+package x
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestPresentRecyclesPixmaps(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode == 128 && req.Data == presentQueryVersion {
+			reply := make([]byte, 32)
+			binary.BigEndian.PutUint32(reply[8:], presentMajorVersion)
+			binary.BigEndian.PutUint32(reply[12:], presentMinorVersion)
+			return reply
+		}
+		return extensionReply(req, "Present")
+	})
+
+	w, err := b.OpenWindow("present", 64, 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.present == nil {
+		t.Fatal("Present not enabled")
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	err = w.Present(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := w.present.pixmaps[0].pixmap
+
+	// The pixmap is still in use, so the next frame needs another one.
+	err = w.Present(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.present.pixmaps) != 2 {
+		t.Fatalf("got %d pixmaps, want 2", len(w.present.pixmaps))
+	}
+
+	idle := b.encodeEvent(&PresentIdleNotifyEvent{
+		Code:      GenericEventCode,
+		Extension: 128,
+		EventType: PresentIdleNotifyType,
+		Window:    w.Id,
+		Serial:    1,
+		Pixmap:    first,
+	})
+	complete := b.encodeEvent(&PresentCompleteNotifyEvent{
+		Code:      GenericEventCode,
+		Extension: 128,
+		Length:    2,
+		EventType: PresentCompleteNotifyType,
+		Kind:      PresentCompleteKindPixmap,
+		Mode:      PresentCompleteModeFlip,
+		Window:    w.Id,
+		Serial:    2,
+		UST:       1000,
+		MSC:       7,
+	})
+	s.send(idle, complete)
+
+	_, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frameEv, ok := ev.(FrameEvent)
+	if !ok || frameEv.MSC != 7 || frameEv.UST != 1000 || frameEv.Window != w.Id {
+		t.Fatalf("got %#v, want frame event at MSC 7", ev)
+	}
+
+	err = w.Present(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.present.pixmaps) != 2 {
+		t.Fatalf("idle pixmap not reused, got %d pixmaps", len(w.present.pixmaps))
+	}
+}
+
+func TestPresentAt(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode == 128 && req.Data == presentQueryVersion {
+			reply := make([]byte, 32)
+			binary.BigEndian.PutUint32(reply[8:], presentMajorVersion)
+			binary.BigEndian.PutUint32(reply[12:], presentMinorVersion)
+			return reply
+		}
+		return extensionReply(req, "Present")
+	})
+
+	w, err := b.OpenWindow("present", 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.PresentAt(image.NewRGBA(image.Rect(0, 0, 16, 16)), 1<<40|9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Round trip so the server has seen every request.
+	_, err = b.atom("PRESENT_TEST_BARRIER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pixmaps []fakeRequest
+	for _, req := range s.requestsWithOpcode(128) {
+		if req.Data == presentPixmap {
+			pixmaps = append(pixmaps, req)
+		}
+	}
+	if len(pixmaps) != 1 {
+		t.Fatalf("got %d PresentPixmap requests, want 1", len(pixmaps))
+	}
+	if msc := binary.BigEndian.Uint64(pixmaps[0].Body[44:52]); msc != 1<<40|9 {
+		t.Errorf("target MSC %d, want %d", msc, uint64(1<<40|9))
+	}
+}
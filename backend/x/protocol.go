@@ -18,6 +18,7 @@ type Int32 int32
 type Card8 uint8
 type Card16 uint16
 type Card32 uint32
+type Card64 uint64
 type Timestamp uint32
 type String8 []byte
 
@@ -56,8 +57,8 @@ const (
 type Bool uint8
 
 const (
-	True Bool = iota
-	False
+	False Bool = iota
+	True
 )
 
 type Event uint8
@@ -90,6 +91,11 @@ const (
 	EVKeymapState
 )
 
+// Mask returns the event mask bit selecting e.
+func (e Event) Mask() Card32 {
+	return 1 << e
+}
+
 type ByteOrder Card8
 
 const (
@@ -180,3 +186,127 @@ const (
 	TrueColor
 	DirectColor
 )
+
+type Opcode Card8
+
+const (
+	OpCreateWindow           Opcode = 1
+	OpChangeWindowAttributes Opcode = 2
+	OpGetWindowAttributes    Opcode = 3
+	OpDestroyWindow          Opcode = 4
+	OpMapWindow              Opcode = 8
+	OpGetGeometry            Opcode = 14
+	OpQueryTree              Opcode = 15
+	OpInternAtom             Opcode = 16
+	OpChangeProperty         Opcode = 18
+	OpDeleteProperty         Opcode = 19
+	OpGetProperty            Opcode = 20
+	OpGetSelectionOwner      Opcode = 23
+	OpSendEvent              Opcode = 25
+	OpGrabPointer            Opcode = 26
+	OpUngrabPointer          Opcode = 27
+	OpGrabButton             Opcode = 28
+	OpUngrabButton           Opcode = 29
+	OpGrabKeyboard           Opcode = 31
+	OpUngrabKeyboard         Opcode = 32
+	OpGrabKey                Opcode = 33
+	OpUngrabKey              Opcode = 34
+	OpTranslateCoordinates   Opcode = 40
+	OpGetInputFocus          Opcode = 43
+	OpOpenFont               Opcode = 45
+	OpCloseFont              Opcode = 46
+	OpCreatePixmap           Opcode = 53
+	OpFreePixmap             Opcode = 54
+	OpCreateGC               Opcode = 55
+	OpFreeGC                 Opcode = 60
+	OpPutImage               Opcode = 72
+	OpGetImage               Opcode = 73
+	OpCreateGlyphCursor      Opcode = 94
+	OpFreeCursor             Opcode = 95
+	OpQueryExtension         Opcode = 98
+	OpGetKeyboardMapping     Opcode = 101
+	OpGetModifierMapping     Opcode = 119
+)
+
+const (
+	AtomNone            Atom = 0
+	AtomAtom            Atom = 4
+	AtomCardinal        Atom = 6
+	AtomResourceManager Atom = 23
+	AtomString          Atom = 31
+	AtomWindow          Atom = 33
+	AtomWMName          Atom = 39
+	AtomWMClass         Atom = 67
+	AtomWMTransientFor  Atom = 68
+	AnyPropertyType     Atom = 0
+)
+
+type WindowClass Card16
+
+const (
+	CopyFromParent WindowClass = iota
+	InputOutput
+	InputOnly
+)
+
+// Window attribute value mask bits, in the order their values are sent.
+const (
+	CWBackPixmap       Card32 = 1 << 0
+	CWBackPixel        Card32 = 1 << 1
+	CWBorderPixmap     Card32 = 1 << 2
+	CWBorderPixel      Card32 = 1 << 3
+	CWBitGravity       Card32 = 1 << 4
+	CWWinGravity       Card32 = 1 << 5
+	CWBackingStore     Card32 = 1 << 6
+	CWBackingPlanes    Card32 = 1 << 7
+	CWBackingPixel     Card32 = 1 << 8
+	CWOverrideRedirect Card32 = 1 << 9
+	CWSaveUnder        Card32 = 1 << 10
+	CWEventMask        Card32 = 1 << 11
+	CWDontPropagate    Card32 = 1 << 12
+	CWColormap         Card32 = 1 << 13
+	CWCursor           Card32 = 1 << 14
+)
+
+// Graphics context value mask bits used by the backend.
+const (
+	GCForeground        Card32 = 1 << 2
+	GCBackground        Card32 = 1 << 3
+	GCGraphicsExposures Card32 = 1 << 16
+)
+
+// Modifier masks of the state field of input events.
+const (
+	ShiftMask   Card16 = 1 << 0
+	LockMask    Card16 = 1 << 1
+	ControlMask Card16 = 1 << 2
+	Mod1Mask    Card16 = 1 << 3
+	Mod2Mask    Card16 = 1 << 4
+	Mod3Mask    Card16 = 1 << 5
+	Mod4Mask    Card16 = 1 << 6
+	Mod5Mask    Card16 = 1 << 7
+	AnyModifier Card16 = 1 << 15
+)
+
+type GrabMode Card8
+
+const (
+	GrabModeSync GrabMode = iota
+	GrabModeAsync
+)
+
+type PropMode Card8
+
+const (
+	PropModeReplace PropMode = iota
+	PropModePrepend
+	PropModeAppend
+)
+
+type ImageFormat Card8
+
+const (
+	XYBitmap ImageFormat = iota
+	XYPixmap
+	ZPixmap
+)
@@ -0,0 +1,149 @@
+package x
+
+import (
+	"fmt"
+)
+
+// Minor opcodes of the RENDER extension.
+const (
+	renderQueryVersion     Card8 = 0
+	renderQueryPictFormats Card8 = 1
+	renderCreatePicture    Card8 = 4
+	renderFreePicture      Card8 = 7
+	renderCreateCursor     Card8 = 27
+)
+
+const (
+	renderMajorVersion = 0
+	renderMinorVersion = 11
+)
+
+type Picture uint32
+type PictFormat uint32
+
+type PictType Card8
+
+const (
+	PictTypeIndexed PictType = iota
+	PictTypeDirect
+)
+
+type PictFormatInfo struct {
+	Id         PictFormat
+	Type       PictType
+	Depth      Card8
+	Pad0       Card16
+	RedShift   Card16
+	RedMask    Card16
+	GreenShift Card16
+	GreenMask  Card16
+	BlueShift  Card16
+	BlueMask   Card16
+	AlphaShift Card16
+	AlphaMask  Card16
+	Colormap   Colormap
+}
+
+type renderQueryVersionReply struct {
+	Header       replyHeader
+	MajorVersion Card32
+	MinorVersion Card32
+}
+
+type renderQueryPictFormatsReply struct {
+	Header          replyHeader
+	FormatsLength   Card32
+	ScreensLength   Card32
+	DepthsLength    Card32
+	VisualsLength   Card32
+	SubpixelsLength Card32
+	Pad0            Card32
+	Formats         []PictFormatInfo `lengthField:"FormatsLength"`
+}
+
+// renderExtension queries the RENDER extension, negotiating its version
+// and reading the picture formats the first time it is used.
+func (b *Backend) renderExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("RENDER")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("RENDER: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, renderQueryVersion, 12)
+	b.write(Card32(renderMajorVersion))
+	b.write(Card32(renderMinorVersion))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing RENDER extension: %w", err)
+	}
+
+	var reply renderQueryVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing RENDER extension: %w", err)
+	}
+
+	seq = b.beginExtensionRequest(ext, renderQueryPictFormats, 4)
+	buf, err = b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("querying picture formats: %w", err)
+	}
+
+	var formats renderQueryPictFormatsReply
+	err = b.decode(buf, &formats)
+	if err != nil {
+		return ext, fmt.Errorf("querying picture formats: %w", err)
+	}
+	b.pictFormats = formats.Formats
+
+	ext.initialized = true
+	b.extensions["RENDER"] = ext
+	return ext, nil
+}
+
+// argbPictFormat finds the standard 32 bit ARGB picture format.
+func (b *Backend) argbPictFormat() (format PictFormat, err error) {
+	_, err = b.renderExtension()
+	if err != nil {
+		return format, err
+	}
+
+	for _, f := range b.pictFormats {
+		if f.Type == PictTypeDirect && f.Depth == 32 &&
+			f.RedShift == 16 && f.RedMask == 0xff &&
+			f.GreenShift == 8 && f.GreenMask == 0xff &&
+			f.BlueShift == 0 && f.BlueMask == 0xff &&
+			f.AlphaShift == 24 && f.AlphaMask == 0xff {
+			return f.Id, nil
+		}
+	}
+	return format, fmt.Errorf("no ARGB picture format: %w", ErrNotImplemented)
+}
+
+func (b *Backend) createPicture(drawable Drawable, format PictFormat) (pic Picture, err error) {
+	ext, err := b.renderExtension()
+	if err != nil {
+		return pic, err
+	}
+
+	pic = Picture(b.allocId())
+	b.beginExtensionRequest(ext, renderCreatePicture, 20)
+	b.write(pic)
+	b.write(drawable)
+	b.write(format)
+	b.write(Card32(0)) // Value mask
+	return pic, b.err
+}
+
+func (b *Backend) freePicture(pic Picture) {
+	ext := b.extensions["RENDER"]
+	b.beginExtensionRequest(ext, renderFreePicture, 8)
+	b.write(pic)
+}
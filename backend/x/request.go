@@ -0,0 +1,439 @@
+package x
+
+import (
+	"fmt"
+	"io"
+)
+
+// ProtocolError is an error packet sent by the X server in response to a
+// request.
+type ProtocolError struct {
+	Code        Card8
+	Sequence    Card16
+	BadValue    Card32
+	MinorOpcode Card16
+	MajorOpcode Card8
+}
+
+// Core error codes handled by the backend.
+const (
+	errWindow   Card8 = 3
+	errDrawable Card8 = 9
+)
+
+var errorNames = [...]string{
+	1:  "Request",
+	2:  "Value",
+	3:  "Window",
+	4:  "Pixmap",
+	5:  "Atom",
+	6:  "Cursor",
+	7:  "Font",
+	8:  "Match",
+	9:  "Drawable",
+	10: "Access",
+	11: "Alloc",
+	12: "Colormap",
+	13: "GContext",
+	14: "IDChoice",
+	15: "Name",
+	16: "Length",
+	17: "Implementation",
+}
+
+func (e *ProtocolError) Error() string {
+	name := "extension error"
+	if int(e.Code) < len(errorNames) && errorNames[e.Code] != "" {
+		name = errorNames[e.Code]
+	}
+	return fmt.Sprintf("X protocol error %d (%s) in request %d.%d (sequence %d, value %d)",
+		e.Code, name, e.MajorOpcode, e.MinorOpcode, e.Sequence, e.BadValue)
+}
+
+// replyHeader is the common prefix of every reply packet. Data holds the
+// request specific byte that follows the packet type.
+type replyHeader struct {
+	Reply    Card8
+	Data     Card8
+	Sequence Card16
+	Length   Card32
+}
+
+// beginRequest writes a request header. The length is the full size of the
+// request in bytes, and is rounded up to the four byte unit used on the
+// wire.
+func (b *Backend) beginRequest(opcode Opcode, data Card8, length int) Card16 {
+	b.sequence++
+	b.bytesWritten = 0
+	b.write(opcode)
+	b.write(data)
+	b.write(Card16(pad(length) / 4))
+	return b.sequence
+}
+
+// beginExtensionRequest writes the header of an extension request, which
+// carries the minor opcode in place of the data byte.
+func (b *Backend) beginExtensionRequest(ext extension, minor Card8, length int) Card16 {
+	return b.beginRequest(Opcode(ext.majorOpcode), minor, length)
+}
+
+func (b *Backend) writeValues(values ...Card32) {
+	b.write(values)
+}
+
+// readPacket reads the next reply, event or error sent by the server.
+// maxPacketLength bounds the additional data of replies and generic events,
+// so that a broken server cannot make the client allocate unbounded memory.
+// It is far larger than the data requested by the backend.
+const maxPacketLength = 1 << 28
+
+func (b *Backend) readPacket() (buf []byte, err error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	buf = make([]byte, 32)
+	_, err = io.ReadFull(b.r, buf)
+	if err != nil {
+		return nil, b.disconnect(err)
+	}
+
+	// Replies and generic events carry additional data after the first 32
+	// bytes.
+	if buf[0] == 1 || Card8(buf[0])&eventCodeMask == GenericEventCode {
+		length := int(b.byteOrder.Uint32(buf[4:8])) * 4
+		if length > maxPacketLength {
+			return nil, b.disconnect(fmt.Errorf("packet of %d bytes: %w", length, ErrMalformed))
+		}
+		if length > 0 {
+			buf = append(buf, make([]byte, length)...)
+			_, err = io.ReadFull(b.r, buf[32:])
+			if err != nil {
+				return nil, b.disconnect(err)
+			}
+		}
+	}
+
+	return buf, nil
+}
+
+func (b *Backend) decodeError(buf []byte) *ProtocolError {
+	var perr ProtocolError
+	perr.Code = Card8(buf[1])
+	perr.Sequence = Card16(b.byteOrder.Uint16(buf[2:4]))
+	perr.BadValue = Card32(b.byteOrder.Uint32(buf[4:8]))
+	perr.MinorOpcode = Card16(b.byteOrder.Uint16(buf[8:10]))
+	perr.MajorOpcode = Card8(buf[10])
+	return &perr
+}
+
+// waitReply flushes pending requests and reads packets until the reply or
+// error for the request with the given sequence number arrives. Events
+// received in the meantime are queued.
+func (b *Backend) waitReply(sequence Card16) (reply []byte, err error) {
+	b.flush()
+	if b.err != nil {
+		return nil, fmt.Errorf("sending request: %w", b.err)
+	}
+
+	for {
+		buf, err := b.readPacket()
+		if err != nil {
+			return nil, fmt.Errorf("reading reply: %w", err)
+		}
+
+		switch buf[0] {
+		case 0:
+			perr := b.decodeError(buf)
+			if perr.Sequence == sequence {
+				return nil, perr
+			}
+			b.events = append(b.events, buf)
+		case 1:
+			if Card16(b.byteOrder.Uint16(buf[2:4])) == sequence {
+				return buf, nil
+			}
+		default:
+			b.events = append(b.events, buf)
+		}
+	}
+}
+
+// checkRequests waits until the server processed every request sent so far
+// and returns the first error caused by a request with a sequence number
+// from first to last. Other errors stay queued as events.
+func (b *Backend) checkRequests(first, last Card16) error {
+	seq := b.beginRequest(OpGetInputFocus, 0, 4)
+	_, err := b.waitReply(seq)
+	if err != nil {
+		return err
+	}
+
+	var found *ProtocolError
+	events := b.events[:0]
+	for _, buf := range b.events {
+		if buf[0] == 0 {
+			perr := b.decodeError(buf)
+			if perr.Sequence-first <= last-first {
+				if found == nil {
+					found = perr
+				}
+				continue
+			}
+		}
+		events = append(events, buf)
+	}
+	b.events = events
+
+	if found != nil {
+		return found
+	}
+	return nil
+}
+
+// Flush sends all buffered requests to the server.
+func (b *Backend) Flush() error {
+	b.flush()
+	return b.err
+}
+
+func (b *Backend) rootScreen() *Screen {
+	return &b.initResponse.Roots[b.screen]
+}
+
+// extension describes an extension supported by the server. Extensions that
+// negotiate a version before use set initialized once they have done so,
+// and keep the version when parts of them need a newer one.
+type extension struct {
+	present      bool
+	initialized  bool
+	majorOpcode  Card8
+	firstEvent   Card8
+	firstError   Card8
+	majorVersion Card16
+	minorVersion Card16
+}
+
+type queryExtensionReply struct {
+	Header      replyHeader
+	Present     Bool
+	MajorOpcode Card8
+	FirstEvent  Card8
+	FirstError  Card8
+}
+
+// queryExtension looks up the opcode and event base of an extension. The
+// result is cached, so callers do not need to keep it.
+func (b *Backend) queryExtension(name string) (ext extension, err error) {
+	ext, ok := b.extensions[name]
+	if ok {
+		return ext, nil
+	}
+
+	seq := b.beginRequest(OpQueryExtension, 0, 8+len(name))
+	b.write(Card16(len(name)))
+	b.writeUnused(2)
+	b.writeString(name)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("querying extension %s: %w", name, err)
+	}
+
+	var reply queryExtensionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("decoding extension %s: %w", name, err)
+	}
+
+	ext = extension{
+		present:     reply.Present == True,
+		majorOpcode: reply.MajorOpcode,
+		firstEvent:  reply.FirstEvent,
+		firstError:  reply.FirstError,
+	}
+	b.extensions[name] = ext
+	return ext, nil
+}
+
+type internAtomReply struct {
+	Header replyHeader
+	Atom   Atom
+}
+
+// atom interns the named atom, caching the result.
+func (b *Backend) atom(name string) (atom Atom, err error) {
+	atom, ok := b.atoms[name]
+	if ok {
+		return atom, nil
+	}
+
+	seq := b.beginRequest(OpInternAtom, 0, 8+len(name))
+	b.write(Card16(len(name)))
+	b.writeUnused(2)
+	b.writeString(name)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return atom, fmt.Errorf("interning atom %s: %w", name, err)
+	}
+
+	var reply internAtomReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return atom, fmt.Errorf("decoding atom %s: %w", name, err)
+	}
+
+	b.atoms[name] = reply.Atom
+	return reply.Atom, nil
+}
+
+// internAtoms interns several atoms at once.
+func (b *Backend) internAtoms(names ...string) (atoms []Atom, err error) {
+	atoms = make([]Atom, len(names))
+	for i, name := range names {
+		atoms[i], err = b.atom(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return atoms, nil
+}
+
+// changeProperty replaces, prepends or appends to a window property. The
+// data length must be a multiple of format/8. Data that does not fit in a
+// single request is split into several, appended to each other.
+func (b *Backend) changeProperty(mode PropMode, window WindowId, property, typ Atom, format Card8, data []byte) {
+	maxData := (int(b.initResponse.MaximumRequestLength)*4 - 24) &^ 3
+	if len(data) <= maxData {
+		b.changePropertyChunk(mode, window, property, typ, format, data)
+		return
+	}
+
+	var chunks [][]byte
+	for len(data) > maxData {
+		chunks = append(chunks, data[:maxData])
+		data = data[maxData:]
+	}
+	chunks = append(chunks, data)
+
+	if mode == PropModePrepend {
+		for i := len(chunks) - 1; i >= 0; i-- {
+			b.changePropertyChunk(PropModePrepend, window, property, typ, format, chunks[i])
+		}
+		return
+	}
+	for _, chunk := range chunks {
+		b.changePropertyChunk(mode, window, property, typ, format, chunk)
+		mode = PropModeAppend
+	}
+}
+
+func (b *Backend) changePropertyChunk(mode PropMode, window WindowId, property, typ Atom, format Card8, data []byte) {
+	b.beginRequest(OpChangeProperty, Card8(mode), 24+len(data))
+	b.write(window)
+	b.write(property)
+	b.write(typ)
+	b.write(format)
+	b.writeUnused(3)
+	b.write(Card32(len(data) / int(format/8)))
+	b.write(data)
+	b.writePadding()
+}
+
+// deleteProperty removes a window property.
+func (b *Backend) deleteProperty(window WindowId, property Atom) {
+	b.beginRequest(OpDeleteProperty, 0, 12)
+	b.write(window)
+	b.write(property)
+}
+
+func (b *Backend) changePropertyString(window WindowId, property, typ Atom, s string) {
+	b.changeProperty(PropModeReplace, window, property, typ, 8, []byte(s))
+}
+
+func (b *Backend) changeProperty32(window WindowId, property, typ Atom, values ...Card32) {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		b.byteOrder.PutUint32(data[4*i:], uint32(v))
+	}
+	b.changeProperty(PropModeReplace, window, property, typ, 32, data)
+}
+
+type getPropertyReply struct {
+	Header      replyHeader
+	Type        Atom
+	BytesAfter  Card32
+	ValueLength Card32
+}
+
+// Property is the value of a window property.
+type Property struct {
+	Type   Atom
+	Format Card8
+	Value  []byte
+}
+
+// getProperty reads up to maxLength bytes of a window property. A property
+// that does not exist is returned with Type AtomNone.
+func (b *Backend) getProperty(window WindowId, property, typ Atom, maxLength int) (prop Property, err error) {
+	seq := b.beginRequest(OpGetProperty, 0, 24)
+	b.write(window)
+	b.write(property)
+	b.write(typ)
+	b.write(Card32(0))
+	b.write(Card32(pad(maxLength) / 4))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return prop, fmt.Errorf("getting property %d: %w", property, err)
+	}
+
+	var reply getPropertyReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return prop, fmt.Errorf("decoding property %d: %w", property, err)
+	}
+
+	prop.Type = reply.Type
+	prop.Format = reply.Header.Data
+	n := int(reply.ValueLength) * int(prop.Format) / 8
+	if n > len(buf)-32 {
+		return prop, fmt.Errorf("property %d value of %d bytes overflows reply", property, n)
+	}
+	prop.Value = buf[32 : 32+n]
+	return prop, nil
+}
+
+// values32 interprets a format 32 property value as a list of cards.
+func (b *Backend) values32(prop Property) []Card32 {
+	values := make([]Card32, len(prop.Value)/4)
+	for i := range values {
+		values[i] = Card32(b.byteOrder.Uint32(prop.Value[4*i:]))
+	}
+	return values
+}
+
+// sendEvent sends a synthetic 32 byte event to a window.
+func (b *Backend) sendEvent(propagate bool, destination WindowId, mask Card32, event []byte) {
+	var p Card8
+	if propagate {
+		p = 1
+	}
+	b.beginRequest(OpSendEvent, p, 44)
+	b.write(destination)
+	b.write(mask)
+	b.write(event[:32])
+}
+
+// sendClientMessage sends a format 32 ClientMessage event.
+func (b *Backend) sendClientMessage(destination, window WindowId, mask Card32, typ Atom, data ...Card32) {
+	ev := ClientMessageEvent{
+		Code:   ClientMessageCode,
+		Format: 32,
+		Window: window,
+		Type:   typ,
+	}
+	copy(ev.Data[:], data)
+	b.sendEvent(false, destination, mask, b.encodeEvent(&ev))
+}
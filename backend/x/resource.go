@@ -0,0 +1,376 @@
+package x
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth limits nested #include directives, which could otherwise
+// include each other forever.
+const maxIncludeDepth = 16
+
+// ResourceDatabase is an X resource database, in the format read by xrdb
+// and stored in the RESOURCE_MANAGER property of the root window.
+type ResourceDatabase struct {
+	entries []resourceEntry
+}
+
+// resourceEntry is a resource specification such as "Xft*antialias" with
+// its value.
+type resourceEntry struct {
+	spec       string
+	components []resourceComponent
+	value      string
+}
+
+// resourceComponent is a component of a resource specification. Loose
+// components are preceded by "*" and may be separated from the previous
+// one by any number of levels; tight ones are preceded by ".".
+type resourceComponent struct {
+	name  string
+	loose bool
+}
+
+// ParseResources parses a resource database. #include directives are
+// ignored, as the database may come from the X server, which must not get
+// local files read: only LoadResources follows them.
+func ParseResources(s string) (*ResourceDatabase, error) {
+	db := &ResourceDatabase{}
+	err := db.parse(s, "", false, 0)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// LoadResources reads a resource database from a file, such as
+// ~/.Xresources.
+func LoadResources(path string) (*ResourceDatabase, error) {
+	db := &ResourceDatabase{}
+	err := db.load(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *ResourceDatabase) load(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("reading resources from %s: includes nested too deeply", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading resources: %w", err)
+	}
+	return db.parse(string(data), filepath.Dir(path), true, depth)
+}
+
+// parse adds the entries of a database. The files named by #include
+// directives are read relative to dir if includes is set.
+func (db *ResourceDatabase) parse(s, dir string, includes bool, depth int) error {
+	// Lines ending with a backslash continue on the next one.
+	s = strings.ReplaceAll(s, "\\\n", "")
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimLeft(line, " \t")
+		switch {
+		case line == "" || line[0] == '!':
+			continue
+		case line[0] == '#':
+			if !includes {
+				continue
+			}
+			err := db.directive(line, dir, depth)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Malformed lines are ignored, like Xlib does.
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		components := parseResourceSpec(strings.TrimRight(line[:colon], " \t"))
+		if len(components) == 0 {
+			continue
+		}
+		value := unescapeResourceValue(strings.TrimLeft(line[colon+1:], " \t"))
+		db.set(components, value)
+	}
+	return nil
+}
+
+// directive handles #include "file". Other directives are left by cpp
+// when xrdb is run with -nocpp, and are ignored.
+func (db *ResourceDatabase) directive(line, dir string, depth int) error {
+	fields := strings.Fields(line[1:])
+	if len(fields) < 2 || fields[0] != "include" {
+		return nil
+	}
+
+	name := strings.TrimSpace(line[strings.Index(line, "include")+len("include"):])
+	if len(name) < 2 || name[0] != '"' || name[len(name)-1] != '"' {
+		return fmt.Errorf("parsing resources: invalid include %q", line)
+	}
+	name = name[1 : len(name)-1]
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	return db.load(name, depth+1)
+}
+
+// set adds an entry, replacing any with the same specification.
+func (db *ResourceDatabase) set(components []resourceComponent, value string) {
+	spec := normalizeResourceSpec(components)
+	for i := range db.entries {
+		if db.entries[i].spec == spec {
+			db.entries[i].value = value
+			return
+		}
+	}
+	db.entries = append(db.entries, resourceEntry{spec: spec, components: components, value: value})
+}
+
+func parseResourceSpec(spec string) (components []resourceComponent) {
+	loose := false
+	start := 0
+	for i := 0; i <= len(spec); i++ {
+		if i < len(spec) && spec[i] != '.' && spec[i] != '*' {
+			continue
+		}
+		if i > start {
+			components = append(components, resourceComponent{name: spec[start:i], loose: loose})
+			loose = false
+		}
+		if i < len(spec) && spec[i] == '*' {
+			loose = true
+		}
+		start = i + 1
+	}
+	return components
+}
+
+func normalizeResourceSpec(components []resourceComponent) string {
+	var sb strings.Builder
+	for i, c := range components {
+		if c.loose {
+			sb.WriteByte('*')
+		} else if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(c.name)
+	}
+	return sb.String()
+}
+
+// unescapeResourceValue decodes the escapes of a value: "\n" for a newline,
+// "\\" for a backslash, "\ " for a space and "\ooo" for an octal byte.
+func unescapeResourceValue(v string) string {
+	if !strings.Contains(v, "\\") {
+		return v
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			sb.WriteByte(v[i])
+			continue
+		}
+
+		i++
+		switch c := v[i]; {
+		case c == 'n':
+			sb.WriteByte('\n')
+		case c >= '0' && c <= '7' && i+2 < len(v) && isOctal(v[i+1]) && isOctal(v[i+2]):
+			n, _ := strconv.ParseUint(v[i:i+3], 8, 8)
+			sb.WriteByte(byte(n))
+			i += 2
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// Get returns the value of the resource with the given fully qualified
+// name and class, such as "xterm.vt100.background" and
+// "XTerm.VT100.Background". An empty class is the same as the name.
+//
+// When several entries match, the most specific one is used, comparing
+// levels from left to right: a level matched by name beats one matched by
+// class, which beats "?", which beats a level skipped by "*"; on equal
+// terms, a tight binding beats a loose one.
+func (db *ResourceDatabase) Get(name, class string) (value string, ok bool) {
+	if class == "" {
+		class = name
+	}
+	names := strings.Split(name, ".")
+	classes := strings.Split(class, ".")
+	if len(names) != len(classes) {
+		return "", false
+	}
+
+	var best []int
+	for _, e := range db.entries {
+		score := matchResource(e.components, names, classes)
+		if score != nil && (best == nil || compareScores(score, best) > 0) {
+			best = score
+			value = e.value
+		}
+	}
+	return value, best != nil
+}
+
+// matchResource returns the best score of an entry matching a query, with
+// a precedence value for each level of the query, or nil if the entry does
+// not match.
+func matchResource(components []resourceComponent, names, classes []string) []int {
+	if len(components) == 0 {
+		if len(names) == 0 {
+			return []int{}
+		}
+		return nil
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var best []int
+
+	c := components[0]
+	kind := 0
+	switch c.name {
+	case names[0]:
+		kind = 3
+	case classes[0]:
+		kind = 2
+	case "?":
+		kind = 1
+	}
+	if kind != 0 {
+		rest := matchResource(components[1:], names[1:], classes[1:])
+		if rest != nil {
+			level := kind * 2
+			if !c.loose {
+				level++
+			}
+			best = append([]int{level}, rest...)
+		}
+	}
+
+	if c.loose {
+		rest := matchResource(components, names[1:], classes[1:])
+		if rest != nil {
+			skipped := append([]int{0}, rest...)
+			if best == nil || compareScores(skipped, best) > 0 {
+				best = skipped
+			}
+		}
+	}
+	return best
+}
+
+func compareScores(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// GetInt returns the value of a resource as an integer.
+func (db *ResourceDatabase) GetInt(name, class string) (int, bool) {
+	v, ok := db.Get(name, class)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	return n, err == nil
+}
+
+// GetFloat returns the value of a resource as a floating point number.
+func (db *ResourceDatabase) GetFloat(name, class string) (float64, bool) {
+	v, ok := db.Get(name, class)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	return f, err == nil
+}
+
+// GetBool returns the value of a resource as a boolean. The values
+// accepted are those of Xlib: true, on, yes and 1, and their opposites.
+func (db *ResourceDatabase) GetBool(name, class string) (value bool, ok bool) {
+	v, ok := db.Get(name, class)
+	if !ok {
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "on", "yes", "1":
+		return true, true
+	case "false", "off", "no", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// Resources reads the resource database of the screen from the
+// RESOURCE_MANAGER property of the root window, as loaded by xrdb. Without
+// it, the database is empty.
+func (b *Backend) Resources() (*ResourceDatabase, error) {
+	prop, err := b.getProperty(b.rootScreen().Root, AtomResourceManager, AtomString, 1<<24)
+	if err != nil {
+		return nil, fmt.Errorf("reading resources: %w", err)
+	}
+
+	db, err := ParseResources(string(prop.Value))
+	if err != nil {
+		return nil, fmt.Errorf("reading resources: %w", err)
+	}
+	return db, nil
+}
+
+// ScaleFactor returns the factor by which the user asked to scale the user
+// interface, from the Xft.dpi resource relative to the 96 DPI baseline.
+func (b *Backend) ScaleFactor() (float64, error) {
+	db, err := b.Resources()
+	if err != nil {
+		return 0, err
+	}
+	dpi, ok := db.GetFloat("Xft.dpi", "Xft.Dpi")
+	if !ok || dpi <= 0 {
+		return 1, nil
+	}
+	return dpi / 96, nil
+}
+
+// CursorTheme returns the cursor theme and size chosen by the user with
+// the Xcursor.theme and Xcursor.size resources, or the XCURSOR_THEME and
+// XCURSOR_SIZE environment variables. The size is zero if unset.
+func (b *Backend) CursorTheme() (theme string, size int, err error) {
+	db, err := b.Resources()
+	if err != nil {
+		return "", 0, err
+	}
+
+	theme, ok := db.Get("Xcursor.theme", "Xcursor.Theme")
+	if !ok {
+		theme = os.Getenv("XCURSOR_THEME")
+	}
+	size, ok = db.GetInt("Xcursor.size", "Xcursor.Size")
+	if !ok {
+		size, _ = strconv.Atoi(os.Getenv("XCURSOR_SIZE"))
+	}
+	return theme, size, nil
+}
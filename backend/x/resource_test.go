@@ -0,0 +1,137 @@
+package x
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResourceLookup(t *testing.T) {
+	db, err := ParseResources(`! Comment
+*background:	gray
+XTerm*background:	black
+xterm.vt100.background:	white
+XTerm.VT100.foreground: green
+*?.foreground: red
+*scrollBar: on
+Xft.dpi:  144
+Xft.hintstyle: hint\
+full
+*label: \ two\\words\n\101
+xterm*geometry: 80x24
+xterm*geometry: 100x30
+malformed line
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, class string
+		want        string
+		ok          bool
+	}{
+		{"xterm.vt100.background", "XTerm.VT100.Background", "white", true},
+		{"uxterm.vt100.background", "XTerm.VT100.Background", "black", true},
+		{"xclock.clock.background", "XClock.Clock.Background", "gray", true},
+		{"xterm.vt100.foreground", "XTerm.VT100.Foreground", "green", true},
+		{"xclock.clock.foreground", "XClock.Clock.Foreground", "red", true},
+		{"foreground", "Foreground", "", false},
+		{"Xft.dpi", "Xft.Dpi", "144", true},
+		{"Xft.hintstyle", "", "hintfull", true},
+		{"app.label", "App.Label", " two\\words\nA", true},
+		{"xterm.geometry", "XTerm.Geometry", "100x30", true},
+		{"xterm.vt100", "XTerm", "", false},
+	}
+	for _, test := range tests {
+		got, ok := db.Get(test.name, test.class)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Get(%q, %q) = %q, %v, want %q, %v", test.name, test.class, got, ok, test.want, test.ok)
+		}
+	}
+
+	if dpi, ok := db.GetFloat("Xft.dpi", "Xft.Dpi"); !ok || dpi != 144 {
+		t.Errorf("GetFloat(Xft.dpi) = %v, %v", dpi, ok)
+	}
+	if on, ok := db.GetBool("xterm.vt100.scrollBar", "XTerm.VT100.ScrollBar"); !ok || !on {
+		t.Errorf("GetBool(scrollBar) = %v, %v", on, ok)
+	}
+	if _, ok := db.GetInt("xterm.vt100.background", ""); ok {
+		t.Error("GetInt accepted a color")
+	}
+}
+
+func TestResourceInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, s string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(s), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Xresources", "Xcursor.theme: base\n#include \"colors\"\nXcursor.size: 24\n")
+	write("colors", "Xcursor.theme: Adwaita\n*color0: #000000\n")
+	write("loop", "#include \"loop\"\n")
+
+	db, err := LoadResources(filepath.Join(dir, "Xresources"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get("Xcursor.theme", ""); v != "Adwaita" {
+		t.Errorf("theme %q, want the included Adwaita", v)
+	}
+	if v, _ := db.Get("xterm.color0", "XTerm.Color0"); v != "#000000" {
+		t.Errorf("color0 %q", v)
+	}
+	if v, _ := db.GetInt("Xcursor.size", ""); v != 24 {
+		t.Errorf("size %d", v)
+	}
+
+	_, err = LoadResources(filepath.Join(dir, "loop"))
+	if err == nil {
+		t.Error("recursive include did not fail")
+	}
+
+	// Parsed databases, such as the one of the X server, include nothing.
+	db, err = ParseResources("#include \"" + filepath.Join(dir, "colors") + "\"\nXcursor.size: 32\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := db.Get("Xcursor.theme", ""); ok {
+		t.Errorf("included theme %q from a parsed database", v)
+	}
+	if v, _ := db.GetInt("Xcursor.size", ""); v != 32 {
+		t.Errorf("size %d", v)
+	}
+}
+
+func TestScaleFactor(t *testing.T) {
+	b, _ := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode != OpGetProperty || Atom(binary.BigEndian.Uint32(req.Body[4:])) != AtomResourceManager {
+			return nil
+		}
+		value := "Xft.dpi:\t192\nXcursor.theme:\tBreeze\n"
+		reply := make([]byte, 32)
+		reply[1] = 8
+		binary.BigEndian.PutUint32(reply[8:], uint32(AtomString))
+		binary.BigEndian.PutUint32(reply[16:], uint32(len(value)))
+		return append(reply, value...)
+	})
+
+	scale, err := b.ScaleFactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scale != 2 {
+		t.Errorf("scale factor %v, want 2", scale)
+	}
+
+	theme, _, err := b.CursorTheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme != "Breeze" {
+		t.Errorf("cursor theme %q, want Breeze", theme)
+	}
+}
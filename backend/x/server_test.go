@@ -0,0 +1,238 @@
+package x
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeRequest is a request received by fakeServer.
+type fakeRequest struct {
+	Opcode   Opcode
+	Data     Card8
+	Sequence Card16
+	Body     []byte
+}
+
+// fakeServer answers requests of a Backend connected to it through a pipe.
+// Requests are answered by reply, which returns the full reply packet, or
+// nil for requests without a reply. The packet type, sequence number and
+// length are filled in by the server.
+type fakeServer struct {
+	conn  net.Conn
+	reply func(req fakeRequest) []byte
+	// fail returns the code of the error to answer a request with instead
+	// of its reply, or 0. It is set with failRequests.
+	fail func(req fakeRequest) Card8
+
+	mu       sync.Mutex
+	requests []fakeRequest
+	atoms    map[string]Atom
+}
+
+// newFakeBackend returns a backend connected to a fake server with a single
+// 24 bit TrueColor screen.
+func newFakeBackend(t *testing.T, reply func(req fakeRequest) []byte) (*Backend, *fakeServer) {
+	client, server := net.Pipe()
+
+	b := &Backend{
+		conn:       client,
+		r:          bufio.NewReader(client),
+		w:          bufio.NewWriter(client),
+		byteOrder:  binary.BigEndian,
+		atoms:      make(map[string]Atom),
+		extensions: make(map[string]extension),
+		windows:    make(map[WindowId]*Window),
+		trayIcons:  make(map[WindowId]*TrayIcon),
+		popups:     make(map[WindowId]*Popup),
+	}
+	b.initResponse = InitResponse{
+		ResourceIdBase:       0x200000,
+		ResourceIdMask:       0x1fffff,
+		MaximumRequestLength: 65535,
+		ImageByteOrder:       LSBFirst,
+		PixmapFormats: []Format{
+			{Depth: 24, BitsPerPixel: 32, ScanlinePad: 32},
+		},
+		Roots: []Screen{{
+			Root:           0x100,
+			WidthInPixels:  1920,
+			HeightInPixels: 1080,
+			RootVisual:     0x21,
+			RootDepth:      24,
+			AllowedDepths: []Depth{{
+				Depth: 24,
+				Visuals: []VisualType{{
+					VisualId:  0x21,
+					Class:     TrueColor,
+					RedMask:   0xff0000,
+					GreenMask: 0x00ff00,
+					BlueMask:  0x0000ff,
+				}},
+			}},
+		}},
+	}
+
+	s := &fakeServer{
+		conn:  server,
+		reply: reply,
+		atoms: make(map[string]Atom),
+	}
+	go s.serve()
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return b, s
+}
+
+func (s *fakeServer) serve() {
+	r := bufio.NewReader(s.conn)
+	var seq Card16
+
+	for {
+		var header [4]byte
+		_, err := io.ReadFull(r, header[:])
+		if err != nil {
+			return
+		}
+
+		length := int(binary.BigEndian.Uint16(header[2:4])) * 4
+		body := make([]byte, length-4)
+		_, err = io.ReadFull(r, body)
+		if err != nil {
+			return
+		}
+
+		seq++
+		req := fakeRequest{
+			Opcode:   Opcode(header[0]),
+			Data:     Card8(header[1]),
+			Sequence: seq,
+			Body:     body,
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		fail := s.fail
+		s.mu.Unlock()
+
+		if fail != nil {
+			if code := fail(req); code != 0 {
+				s.writeError(req, code)
+				continue
+			}
+		}
+		reply := s.defaultReply(req)
+		if reply == nil && s.reply != nil {
+			reply = s.reply(req)
+		}
+		if reply != nil {
+			s.writeReply(req.Sequence, reply)
+		}
+	}
+}
+
+// defaultReply answers InternAtom with fresh atoms.
+func (s *fakeServer) defaultReply(req fakeRequest) []byte {
+	if req.Opcode != OpInternAtom {
+		return nil
+	}
+
+	n := binary.BigEndian.Uint16(req.Body[0:2])
+	name := string(req.Body[4 : 4+n])
+
+	s.mu.Lock()
+	atom, ok := s.atoms[name]
+	if !ok {
+		atom = Atom(100 + len(s.atoms))
+		s.atoms[name] = atom
+	}
+	s.mu.Unlock()
+
+	reply := make([]byte, 32)
+	binary.BigEndian.PutUint32(reply[8:], uint32(atom))
+	return reply
+}
+
+func (s *fakeServer) writeReply(seq Card16, buf []byte) {
+	for len(buf) < 32 || len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+
+	buf[0] = 1
+	binary.BigEndian.PutUint16(buf[2:4], uint16(seq))
+	binary.BigEndian.PutUint32(buf[4:8], uint32((len(buf)-32)/4))
+	s.conn.Write(buf)
+}
+
+func (s *fakeServer) writeError(req fakeRequest, code Card8) {
+	buf := make([]byte, 32)
+	buf[1] = byte(code)
+	binary.BigEndian.PutUint16(buf[2:4], uint16(req.Sequence))
+	buf[10] = byte(req.Opcode)
+	s.conn.Write(buf)
+}
+
+// failRequests makes the server answer requests with the error code
+// returned by fail, unless it is 0.
+func (s *fakeServer) failRequests(fail func(req fakeRequest) Card8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+// send writes raw event or error packets to the client in the background,
+// as the pipe blocks until the client reads them.
+func (s *fakeServer) send(packets ...[]byte) {
+	go func() {
+		for _, p := range packets {
+			s.conn.Write(p)
+		}
+	}()
+}
+
+func (s *fakeServer) atom(name string) Atom {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.atoms[name]
+}
+
+// requestsWithOpcode returns the requests received so far with the given
+// major opcode.
+func (s *fakeServer) requestsWithOpcode(op Opcode) (reqs []fakeRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.requests {
+		if req.Opcode == op {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// extensionReply answers QueryExtension for the named extensions, using
+// opcodes from 128 and event bases from 64.
+func extensionReply(req fakeRequest, names ...string) []byte {
+	if req.Opcode != OpQueryExtension {
+		return nil
+	}
+
+	n := binary.BigEndian.Uint16(req.Body[0:2])
+	name := string(req.Body[4 : 4+n])
+
+	reply := make([]byte, 32)
+	for i, ext := range names {
+		if ext == name {
+			reply[8] = 1
+			reply[9] = byte(128 + i)
+			reply[10] = byte(64 + 8*i)
+			reply[11] = byte(128 + i)
+		}
+	}
+	return reply
+}
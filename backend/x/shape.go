@@ -0,0 +1,188 @@
+package x
+
+import (
+	"fmt"
+	"image"
+)
+
+// Minor opcodes of the SHAPE extension.
+const (
+	shapeQueryVersion Card8 = 0
+	shapeRectangles   Card8 = 1
+	shapeMask         Card8 = 2
+	shapeSelectInput  Card8 = 6
+)
+
+// ShapeKind selects which region of a window a shape applies to.
+type ShapeKind Card8
+
+const (
+	// ShapeBounding is the region the window occupies on screen.
+	ShapeBounding ShapeKind = iota
+	// ShapeClip is the region the window's contents are drawn in.
+	ShapeClip
+	// ShapeInput is the region that receives pointer input. Outside of
+	// it clicks go through to the windows below.
+	ShapeInput
+)
+
+type shapeOperation Card8
+
+const (
+	shapeSet shapeOperation = iota
+	shapeUnion
+	shapeIntersect
+	shapeSubtract
+	shapeInvert
+)
+
+const shapeUnsorted Card8 = 0
+
+type ShapeNotifyEvent struct {
+	Code     Card8
+	Kind     ShapeKind
+	Sequence Card16
+	Window   WindowId
+	X        Int16
+	Y        Int16
+	Width    Card16
+	Height   Card16
+	Time     Timestamp
+	Shaped   Bool
+	Pad0     [11]Card8
+}
+
+type shapeQueryVersionReply struct {
+	Header       replyHeader
+	MajorVersion Card16
+	MinorVersion Card16
+}
+
+// shapeExtension queries the SHAPE extension, and its version the first
+// time it is used.
+func (b *Backend) shapeExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("SHAPE")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("SHAPE: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, shapeQueryVersion, 4)
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing SHAPE extension: %w", err)
+	}
+
+	var reply shapeQueryVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing SHAPE extension: %w", err)
+	}
+	if reply.MajorVersion < 1 {
+		return ext, fmt.Errorf("SHAPE %d.%d: %w", reply.MajorVersion, reply.MinorVersion, ErrMissingExtension)
+	}
+	ext.majorVersion = reply.MajorVersion
+	ext.minorVersion = reply.MinorVersion
+
+	b.registerEvent(ext.firstEvent, ShapeNotifyEvent{})
+
+	ext.initialized = true
+	b.extensions["SHAPE"] = ext
+	return ext, nil
+}
+
+// shapeInit selects ShapeNotify events for the window the first time its
+// shape is changed. Input shapes need SHAPE 1.1.
+func (w *Window) shapeInit(kind ShapeKind) (ext extension, err error) {
+	ext, err = w.b.shapeExtension()
+	if err != nil {
+		return ext, err
+	}
+	if kind == ShapeInput && ext.majorVersion == 1 && ext.minorVersion < 1 {
+		return ext, fmt.Errorf("input shape with SHAPE %d.%d: %w", ext.majorVersion, ext.minorVersion, ErrMissingExtension)
+	}
+
+	if !w.shaped {
+		w.b.beginExtensionRequest(ext, shapeSelectInput, 12)
+		w.b.write(w.Id)
+		w.b.write(True)
+		w.b.writeUnused(3)
+		w.shaped = true
+	}
+	return ext, nil
+}
+
+// SetShapeRectangles sets a region of the window to the union of rects,
+// given in window coordinates. An empty list makes the region empty, which
+// for ShapeInput makes the whole window transparent to clicks.
+func (w *Window) SetShapeRectangles(kind ShapeKind, rects []image.Rectangle) error {
+	ext, err := w.shapeInit(kind)
+	if err != nil {
+		return fmt.Errorf("setting window shape: %w", err)
+	}
+
+	w.b.beginExtensionRequest(ext, shapeRectangles, 16+8*len(rects))
+	w.b.write(shapeSet)
+	w.b.write(kind)
+	w.b.write(shapeUnsorted)
+	w.b.writeUnused(1)
+	w.b.write(w.Id)
+	w.b.write(Int16(0)) // X offset
+	w.b.write(Int16(0)) // Y offset
+	for _, r := range rects {
+		r = r.Canon()
+		w.b.write(Int16(r.Min.X))
+		w.b.write(Int16(r.Min.Y))
+		w.b.write(Card16(r.Dx()))
+		w.b.write(Card16(r.Dy()))
+	}
+
+	w.b.flush()
+	return w.b.err
+}
+
+// SetShapeMask sets a region of the window from a 1 bit mask. Pixels of
+// mask that are at least half opaque are part of the region, and the mask
+// origin is placed at the window origin. A nil mask restores the default
+// rectangular region.
+func (w *Window) SetShapeMask(kind ShapeKind, mask image.Image) error {
+	ext, err := w.shapeInit(kind)
+	if err != nil {
+		return fmt.Errorf("setting window shape: %w", err)
+	}
+
+	var pixmap Pixmap
+	if mask != nil {
+		size := mask.Bounds().Size()
+		pixmap = w.b.createPixmap(Drawable(w.Id), 1, size.X, size.Y)
+		gc := w.b.createGC(Drawable(pixmap))
+		err = w.b.putBitmap(Drawable(pixmap), gc, mask, image.Point{})
+		w.b.freeGC(gc)
+		if err != nil {
+			w.b.freePixmap(pixmap)
+			return fmt.Errorf("setting window shape: %w", err)
+		}
+	}
+
+	w.b.beginExtensionRequest(ext, shapeMask, 20)
+	w.b.write(shapeSet)
+	w.b.write(kind)
+	w.b.writeUnused(2)
+	w.b.write(w.Id)
+	w.b.write(Int16(0)) // X offset
+	w.b.write(Int16(0)) // Y offset
+	w.b.write(pixmap)
+
+	// The server keeps its own copy of the region.
+	if pixmap != 0 {
+		w.b.freePixmap(pixmap)
+	}
+
+	w.b.flush()
+	return w.b.err
+}
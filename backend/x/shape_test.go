@@ -0,0 +1,113 @@
+package x
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func shapeReply(req fakeRequest) []byte {
+	if req.Opcode == 128 && req.Data == shapeQueryVersion {
+		reply := make([]byte, 32)
+		binary.BigEndian.PutUint16(reply[8:], 1)
+		binary.BigEndian.PutUint16(reply[10:], 1)
+		return reply
+	}
+	return extensionReply(req, "SHAPE")
+}
+
+func TestShapeMask(t *testing.T) {
+	b, s := newFakeBackend(t, shapeReply)
+	b.initResponse.BitmapBitOrder = LSBFirst
+	b.initResponse.PixmapFormats = append(b.initResponse.PixmapFormats, Format{
+		Depth:        1,
+		BitsPerPixel: 1,
+		ScanlinePad:  32,
+	})
+
+	w, err := b.OpenWindow("shape", 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, 10, 2))
+	mask.SetAlpha(0, 0, color.Alpha{0xff})
+	mask.SetAlpha(9, 0, color.Alpha{0xff})
+	mask.SetAlpha(3, 1, color.Alpha{0x7f})
+	mask.SetAlpha(4, 1, color.Alpha{0x80})
+
+	err = w.SetShapeMask(ShapeInput, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.atom("SHAPE_TEST_BARRIER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	puts := s.requestsWithOpcode(OpPutImage)
+	if len(puts) != 1 {
+		t.Fatalf("got %d PutImage requests, want 1", len(puts))
+	}
+	put := puts[0]
+	if put.Body[17] != 1 {
+		t.Fatalf("mask uploaded with depth %d", put.Body[17])
+	}
+
+	want := []byte{
+		0x01, 0x02, 0, 0,
+		0x10, 0x00, 0, 0,
+	}
+	got := put.Body[20:]
+	if string(got) != string(want) {
+		t.Fatalf("mask bits %x, want %x", got, want)
+	}
+
+	var masks []fakeRequest
+	for _, req := range s.requestsWithOpcode(128) {
+		if req.Data == shapeMask {
+			masks = append(masks, req)
+		}
+	}
+	if len(masks) != 1 || ShapeKind(masks[0].Body[1]) != ShapeInput {
+		t.Fatalf("got ShapeMask requests %v", masks)
+	}
+}
+
+func TestShapeVersion(t *testing.T) {
+	// SHAPE 1.0 has bounding and clip shapes, but no input shapes.
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode == 128 && req.Data == shapeQueryVersion {
+			reply := make([]byte, 32)
+			binary.BigEndian.PutUint16(reply[8:], 1)
+			return reply
+		}
+		return extensionReply(req, "SHAPE")
+	})
+	w, err := b.OpenWindow("shaped", 64, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.SetShapeRectangles(ShapeBounding, []image.Rectangle{image.Rect(0, 0, 32, 32)})
+	if err != nil {
+		t.Fatalf("bounding shape with SHAPE 1.0: %v", err)
+	}
+	err = w.SetShapeRectangles(ShapeInput, nil)
+	if !errors.Is(err, ErrMissingExtension) {
+		t.Fatalf("input shape with SHAPE 1.0 returned %v", err)
+	}
+
+	b.atom("SHAPE_TEST_BARRIER")
+	n := 0
+	for _, req := range s.requestsWithOpcode(128) {
+		if req.Data == shapeRectangles {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d ShapeRectangles requests, want 1", n)
+	}
+}
@@ -0,0 +1,103 @@
+package x
+
+import (
+	"fmt"
+	"net"
+)
+
+// Minor opcodes of the MIT-SHM extension.
+const (
+	shmQueryVersion Card8 = 0
+	shmAttach       Card8 = 1
+	shmDetach       Card8 = 2
+	shmGetImage     Card8 = 4
+)
+
+type ShmSeg uint32
+
+// shmSegment is a shared memory segment attached by both the client and
+// the X server.
+type shmSegment struct {
+	id   int
+	addr uintptr
+	data []byte
+	seg  ShmSeg
+}
+
+type shmQueryVersionReply struct {
+	Header       replyHeader
+	MajorVersion Card16
+	MinorVersion Card16
+}
+
+// shmExtension queries the MIT-SHM extension. Shared memory only works with
+// a server on the same machine, so it is never used over TCP.
+func (b *Backend) shmExtension() (ext extension, err error) {
+	if _, ok := b.conn.(*net.UnixConn); !ok {
+		return ext, fmt.Errorf("MIT-SHM over a remote connection: %w", ErrMissingExtension)
+	}
+
+	ext, err = b.queryExtension("MIT-SHM")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("MIT-SHM: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, shmQueryVersion, 4)
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing MIT-SHM extension: %w", err)
+	}
+
+	var reply shmQueryVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing MIT-SHM extension: %w", err)
+	}
+
+	ext.initialized = true
+	b.extensions["MIT-SHM"] = ext
+	return ext, nil
+}
+
+// attachShm creates a shared memory segment of the given size and attaches
+// it to the server. It fails if the server cannot access the segment, for
+// example because it runs in another IPC namespace.
+func (b *Backend) attachShm(size int) (s *shmSegment, err error) {
+	ext, err := b.shmExtension()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err = newShmSegment(size)
+	if err != nil {
+		return nil, err
+	}
+
+	s.seg = ShmSeg(b.allocId())
+	seq := b.beginExtensionRequest(ext, shmAttach, 16)
+	b.write(s.seg)
+	b.write(Card32(s.id))
+	b.write(False) // Read only
+	b.writeUnused(3)
+
+	err = b.checkRequests(seq, seq)
+	if err != nil {
+		s.release()
+		return nil, fmt.Errorf("attaching shared memory segment: %w", err)
+	}
+	return s, nil
+}
+
+func (b *Backend) detachShm(s *shmSegment) {
+	ext := b.extensions["MIT-SHM"]
+	b.beginExtensionRequest(ext, shmDetach, 8)
+	b.write(s.seg)
+	b.flush()
+	s.release()
+}
@@ -0,0 +1,43 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package x
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ipcPrivate = 0
+	ipcCreat   = 01000
+	ipcRmid    = 0
+)
+
+// newShmSegment creates and attaches a System V shared memory segment.
+func newShmSegment(size int) (s *shmSegment, err error) {
+	id, _, errno := syscall.Syscall(syscall.SYS_SHMGET, ipcPrivate, uintptr(size), ipcCreat|0600)
+	if errno != 0 {
+		return nil, fmt.Errorf("creating shared memory segment: %w", errno)
+	}
+
+	addr, _, errno := syscall.Syscall(syscall.SYS_SHMAT, id, 0, 0)
+	if errno != 0 {
+		syscall.Syscall(syscall.SYS_SHMCTL, id, ipcRmid, 0)
+		return nil, fmt.Errorf("attaching shared memory segment: %w", errno)
+	}
+
+	s = &shmSegment{id: int(id), addr: addr}
+	s.data = unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size)
+	return s, nil
+}
+
+// release detaches the segment and marks it for removal, which happens once
+// the X server detached it too.
+func (s *shmSegment) release() {
+	syscall.Syscall(syscall.SYS_SHMDT, s.addr, 0, 0)
+	syscall.Syscall(syscall.SYS_SHMCTL, uintptr(s.id), ipcRmid, 0)
+	s.data = nil
+}
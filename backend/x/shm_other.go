@@ -0,0 +1,14 @@
+//go:build !linux || !(amd64 || arm64)
+// +build !linux !amd64,!arm64
+
+package x
+
+import (
+	"fmt"
+)
+
+func newShmSegment(size int) (s *shmSegment, err error) {
+	return nil, fmt.Errorf("shared memory segments: %w", ErrNotImplemented)
+}
+
+func (s *shmSegment) release() {}
@@ -0,0 +1,161 @@
+package x
+
+import (
+	"fmt"
+	"image"
+)
+
+// Minor opcodes of the SYNC extension.
+const (
+	syncInitialize     Card8 = 0
+	syncCreateCounter  Card8 = 2
+	syncSetCounter     Card8 = 3
+	syncDestroyCounter Card8 = 6
+)
+
+const (
+	syncMajorVersion = 3
+	syncMinorVersion = 1
+)
+
+type Counter uint32
+
+type syncInitializeReply struct {
+	Header       replyHeader
+	MajorVersion Card8
+	MinorVersion Card8
+}
+
+// syncExtension queries the SYNC extension, negotiating its version the
+// first time it is used.
+func (b *Backend) syncExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("SYNC")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("SYNC: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, syncInitialize, 8)
+	b.write(Card8(syncMajorVersion))
+	b.write(Card8(syncMinorVersion))
+	b.writeUnused(2)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing SYNC extension: %w", err)
+	}
+
+	var reply syncInitializeReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing SYNC extension: %w", err)
+	}
+
+	ext.initialized = true
+	b.extensions["SYNC"] = ext
+	return ext, nil
+}
+
+func (b *Backend) writeInt64(v int64) {
+	b.write(Int32(v >> 32))
+	b.write(Card32(v))
+}
+
+// createCounter creates a SYNC counter with the given initial value.
+func (b *Backend) createCounter(value int64) (c Counter, err error) {
+	ext, err := b.syncExtension()
+	if err != nil {
+		return c, err
+	}
+
+	c = Counter(b.allocId())
+	b.beginExtensionRequest(ext, syncCreateCounter, 16)
+	b.write(c)
+	b.writeInt64(value)
+	return c, b.err
+}
+
+func (b *Backend) setCounter(c Counter, value int64) {
+	ext := b.extensions["SYNC"]
+	b.beginExtensionRequest(ext, syncSetCounter, 16)
+	b.write(c)
+	b.writeInt64(value)
+}
+
+func (b *Backend) destroyCounter(c Counter) {
+	ext := b.extensions["SYNC"]
+	b.beginExtensionRequest(ext, syncDestroyCounter, 8)
+	b.write(c)
+}
+
+// windowSync tracks the _NET_WM_SYNC_REQUEST protocol for a window. The
+// window manager sends a sync request before resizing the window, and waits
+// for the counter to reach the requested value before drawing the window
+// at its new size.
+type windowSync struct {
+	counter    Counter
+	enabled    bool
+	pending    bool
+	configured bool
+	value      int64
+}
+
+// initSync creates the window's sync counter and advertises it in the
+// _NET_WM_SYNC_REQUEST_COUNTER property.
+func (w *Window) initSync() error {
+	counter, err := w.b.createCounter(0)
+	if err != nil {
+		return err
+	}
+
+	property, err := w.b.atom("_NET_WM_SYNC_REQUEST_COUNTER")
+	if err != nil {
+		w.b.destroyCounter(counter)
+		return err
+	}
+
+	w.b.changeProperty32(w.Id, property, AtomCardinal, Card32(counter))
+	w.sync.counter = counter
+	w.sync.enabled = true
+	return w.b.err
+}
+
+func (w *Window) closeSync() {
+	if w.sync.enabled {
+		w.b.destroyCounter(w.sync.counter)
+		w.sync.enabled = false
+	}
+}
+
+// syncRequest records the counter value requested by the window manager.
+// The counter is updated once a frame for the configuration that follows
+// has been presented.
+func (w *Window) syncRequest(ev ClientMessageEvent) {
+	if !w.sync.enabled {
+		return
+	}
+
+	// The high word of the INT64 value is signed.
+	w.sync.value = int64(int32(ev.Data[3]))<<32 | int64(ev.Data[2])
+	w.sync.pending = true
+	w.sync.configured = false
+}
+
+// presented updates the sync counter after a frame of the given size was
+// drawn, if it matches the configuration the window manager waits for.
+func (w *Window) presented(size image.Point) {
+	if !w.sync.pending || !w.sync.configured {
+		return
+	}
+	if size.X != w.Width || size.Y != w.Height {
+		return
+	}
+
+	w.b.setCounter(w.sync.counter, w.sync.value)
+	w.sync.pending = false
+}
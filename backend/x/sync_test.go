@@ -0,0 +1,93 @@
+package x
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestSyncRequest(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		if req.Opcode == 128 && req.Data == syncInitialize {
+			reply := make([]byte, 32)
+			reply[8] = syncMajorVersion
+			reply[9] = syncMinorVersion
+			return reply
+		}
+		return extensionReply(req, "SYNC")
+	})
+
+	w, err := b.OpenWindow("sync", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creates := s.requestsWithOpcode(128)
+	if len(creates) < 2 || creates[1].Data != syncCreateCounter {
+		t.Fatalf("counter not created: %v", creates)
+	}
+	counter := Counter(binary.BigEndian.Uint32(creates[1].Body))
+
+	request := b.encodeEvent(&ClientMessageEvent{
+		Code:   ClientMessageCode,
+		Format: 32,
+		Window: w.Id,
+		Type:   s.atom("WM_PROTOCOLS"),
+		Data:   [5]Card32{Card32(s.atom("_NET_WM_SYNC_REQUEST")), 0, 42, 1},
+	})
+	configure := b.encodeEvent(&ConfigureNotifyEvent{
+		Code:   ConfigureNotifyCode,
+		Event:  w.Id,
+		Window: w.Id,
+		Width:  200,
+		Height: 150,
+	})
+	s.send(request, configure)
+
+	for i := 0; i < 2; i++ {
+		_, err = b.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A stale frame must not release the window manager.
+	err = w.Present(image.NewRGBA(image.Rect(0, 0, 100, 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Present(image.NewRGBA(image.Rect(0, 0, 200, 150)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Round trip so the server has seen every request.
+	_, err = b.atom("SYNC_TEST_BARRIER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sets []fakeRequest
+	for _, req := range s.requestsWithOpcode(128) {
+		if req.Data == syncSetCounter {
+			sets = append(sets, req)
+		}
+	}
+	if len(sets) != 1 {
+		t.Fatalf("got %d SetCounter requests, want 1", len(sets))
+	}
+
+	gotCounter := Counter(binary.BigEndian.Uint32(sets[0].Body[0:4]))
+	hi := int64(int32(binary.BigEndian.Uint32(sets[0].Body[4:8])))
+	lo := int64(binary.BigEndian.Uint32(sets[0].Body[8:12]))
+	if gotCounter != counter || hi<<32|lo != 1<<32|42 {
+		t.Fatalf("SetCounter(%d, %d), want (%d, %d)", gotCounter, hi<<32|lo, counter, int64(1<<32|42))
+	}
+}
+
+func TestSyncRequestNegative(t *testing.T) {
+	w := &Window{sync: windowSync{enabled: true}}
+	w.syncRequest(ClientMessageEvent{Data: [5]Card32{0, 0, 0xfffffffe, 0xffffffff}})
+	if w.sync.value != -2 {
+		t.Errorf("sync request value %d, want -2", w.sync.value)
+	}
+}
@@ -0,0 +1,212 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var ErrNoTrayManager = errors.New("no system tray manager running")
+
+// systemTrayRequestDock is the opcode of _NET_SYSTEM_TRAY_OPCODE messages
+// asking the tray manager to embed an icon.
+const systemTrayRequestDock Card32 = 0
+
+// xembedEmbeddedNotify is the _XEMBED message sent by the embedder once it
+// reparented the icon.
+const xembedEmbeddedNotify Card32 = 0
+
+const (
+	xembedVersion = 0
+	xembedMapped  = 1 << 0
+)
+
+// TrayIcon is an icon docked in the freedesktop system tray. It is drawn
+// like any other window, at the size chosen by the tray.
+type TrayIcon struct {
+	*Window
+
+	// Embedder is the tray window the icon is embedded in, or zero while
+	// it is not docked.
+	Embedder WindowId
+
+	selection Atom
+}
+
+// TrayClickEvent reports a button press on a tray icon.
+type TrayClickEvent struct {
+	Icon   *TrayIcon
+	Button Card8
+	X      Int16
+	Y      Int16
+	RootX  Int16
+	RootY  Int16
+	Time   Timestamp
+}
+
+type getSelectionOwnerReply struct {
+	Header replyHeader
+	Owner  WindowId
+}
+
+func (b *Backend) selectionOwner(selection Atom) (owner WindowId, err error) {
+	seq := b.beginRequest(OpGetSelectionOwner, 0, 8)
+	b.write(selection)
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return owner, fmt.Errorf("getting selection owner: %w", err)
+	}
+
+	var reply getSelectionOwnerReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return owner, fmt.Errorf("getting selection owner: %w", err)
+	}
+	return reply.Owner, nil
+}
+
+// OpenTrayIcon docks a new icon of the given size in the system tray of the
+// screen. The tray may resize the icon, which is reported with
+// ConfigureNotifyEvent. If the tray manager restarts, the icon is docked
+// again automatically.
+func (b *Backend) OpenTrayIcon(size int) (t *TrayIcon, err error) {
+	selection, err := b.atom(fmt.Sprintf("_NET_SYSTEM_TRAY_S%d", b.screen))
+	if err != nil {
+		return nil, fmt.Errorf("opening tray icon: %w", err)
+	}
+
+	manager, err := b.selectionOwner(selection)
+	if err != nil {
+		return nil, fmt.Errorf("opening tray icon: %w", err)
+	}
+	if manager == 0 {
+		return nil, fmt.Errorf("opening tray icon: %w", ErrNoTrayManager)
+	}
+
+	screen := b.rootScreen()
+	w, err := b.createWindow(screen.Root, image.Rect(0, 0, size, size),
+		CWBackPixel|CWEventMask, screen.BlackPixel, windowEventMask)
+	if err != nil {
+		return nil, fmt.Errorf("opening tray icon: %w", err)
+	}
+
+	t = &TrayIcon{Window: w, selection: selection}
+
+	// The atoms of the messages handled for the icon are interned here, so
+	// handling them does not need a round trip.
+	atoms, err := b.internAtoms("_XEMBED_INFO", "_XEMBED", "MANAGER")
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("opening tray icon: %w", err)
+	}
+	xembedInfo := atoms[0]
+	b.changeProperty32(w.Id, xembedInfo, xembedInfo, xembedVersion, xembedMapped)
+
+	// Watch the root window for MANAGER messages announcing a new tray.
+	b.selectRootInput(EVStructureNotify.Mask())
+
+	err = b.dockTrayIcon(t, manager)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("opening tray icon: %w", err)
+	}
+
+	b.trayIcons[w.Id] = t
+	return t, nil
+}
+
+// dockTrayIcon asks the tray manager to embed the icon.
+func (b *Backend) dockTrayIcon(t *TrayIcon, manager WindowId) error {
+	opcode, err := b.atom("_NET_SYSTEM_TRAY_OPCODE")
+	if err != nil {
+		return err
+	}
+
+	b.sendClientMessage(manager, manager, 0, opcode,
+		0, // Current time
+		systemTrayRequestDock,
+		Card32(t.Id),
+	)
+	b.flush()
+	return b.err
+}
+
+// Close removes the icon from the tray and destroys it.
+func (t *TrayIcon) Close() {
+	delete(t.b.trayIcons, t.Id)
+	t.Window.Close()
+}
+
+// selectRootInput adds events to those selected on the root window.
+func (b *Backend) selectRootInput(mask Card32) {
+	b.rootEventMask |= mask
+	b.beginRequest(OpChangeWindowAttributes, 0, 16)
+	b.write(b.rootScreen().Root)
+	b.write(CWEventMask)
+	b.writeValues(b.rootEventMask)
+}
+
+// trayClientMessage handles XEMBED messages sent to tray icons and MANAGER
+// messages sent to the root window when a tray manager starts.
+func (b *Backend) trayClientMessage(ev ClientMessageEvent) error {
+	if len(b.trayIcons) == 0 {
+		return nil
+	}
+
+	atoms, err := b.internAtoms("_XEMBED", "MANAGER")
+	if err != nil {
+		return err
+	}
+	xembed, manager := atoms[0], atoms[1]
+
+	switch ev.Type {
+	case xembed:
+		t, ok := b.trayIcons[ev.Window]
+		if !ok {
+			return nil
+		}
+		if ev.Data[1] == xembedEmbeddedNotify {
+			t.Embedder = WindowId(ev.Data[3])
+		}
+	case manager:
+		if ev.Window != b.rootScreen().Root {
+			return nil
+		}
+		for _, t := range b.trayIcons {
+			if Atom(ev.Data[1]) == t.selection {
+				t.Embedder = 0
+				err = b.dockTrayIcon(t, WindowId(ev.Data[2]))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// trayReparented undocks icons whose tray went away. The icon is reparented
+// back to the root window when the embedder is destroyed.
+func (b *Backend) trayReparented(ev ReparentNotifyEvent) {
+	t, ok := b.trayIcons[ev.Window]
+	if ok && ev.Parent == b.rootScreen().Root {
+		t.Embedder = 0
+	}
+}
+
+func (b *Backend) trayClick(ev ButtonPressEvent) (TrayClickEvent, bool) {
+	t, ok := b.trayIcons[ev.Event]
+	if !ok {
+		return TrayClickEvent{}, false
+	}
+	return TrayClickEvent{
+		Icon:   t,
+		Button: ev.Detail,
+		X:      ev.EventX,
+		Y:      ev.EventY,
+		RootX:  ev.RootX,
+		RootY:  ev.RootY,
+		Time:   ev.Time,
+	}, true
+}
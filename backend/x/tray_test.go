@@ -0,0 +1,127 @@
+package x
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func trayReply(owner WindowId) func(req fakeRequest) []byte {
+	return func(req fakeRequest) []byte {
+		if req.Opcode == OpGetSelectionOwner {
+			reply := make([]byte, 32)
+			binary.BigEndian.PutUint32(reply[8:], uint32(owner))
+			return reply
+		}
+		return extensionReply(req)
+	}
+}
+
+func TestTrayIconWithoutManager(t *testing.T) {
+	b, _ := newFakeBackend(t, trayReply(0))
+
+	_, err := b.OpenTrayIcon(22)
+	if !errors.Is(err, ErrNoTrayManager) {
+		t.Fatalf("got error %v, want ErrNoTrayManager", err)
+	}
+}
+
+func TestTrayIconDock(t *testing.T) {
+	const manager WindowId = 0x400001
+	b, s := newFakeBackend(t, trayReply(manager))
+
+	icon, err := b.OpenTrayIcon(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.atom("TRAY_TEST_BARRIER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sends := s.requestsWithOpcode(OpSendEvent)
+	if len(sends) != 1 {
+		t.Fatalf("got %d SendEvent requests, want 1", len(sends))
+	}
+	body := sends[0].Body
+	if WindowId(binary.BigEndian.Uint32(body[0:4])) != manager {
+		t.Fatal("dock request not sent to the tray manager")
+	}
+	ev := body[8:]
+	if Atom(binary.BigEndian.Uint32(ev[8:12])) != s.atom("_NET_SYSTEM_TRAY_OPCODE") ||
+		binary.BigEndian.Uint32(ev[16:20]) != uint32(systemTrayRequestDock) ||
+		WindowId(binary.BigEndian.Uint32(ev[20:24])) != icon.Id {
+		t.Fatalf("wrong dock message %x", ev)
+	}
+
+	embedded := b.encodeEvent(&ClientMessageEvent{
+		Code:   ClientMessageCode,
+		Format: 32,
+		Window: icon.Id,
+		Type:   s.atom("_XEMBED"),
+		Data:   [5]Card32{0, xembedEmbeddedNotify, 0, Card32(manager), xembedVersion},
+	})
+	click := b.encodeEvent(&ButtonPressEvent{
+		Code:   ButtonPressCode,
+		Detail: 3,
+		Event:  icon.Id,
+		EventX: 4,
+		EventY: 5,
+	})
+	s.send(embedded, click)
+
+	_, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icon.Embedder != manager {
+		t.Fatalf("icon embedded in %d, want %d", icon.Embedder, manager)
+	}
+
+	got, err := b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clickEv, ok := got.(TrayClickEvent)
+	if !ok || clickEv.Icon != icon || clickEv.Button != 3 || clickEv.X != 4 || clickEv.Y != 5 {
+		t.Fatalf("got %#v, want click on icon", got)
+	}
+}
+
+func TestTrayIconDestroyed(t *testing.T) {
+	b, s := newFakeBackend(t, trayReply(0x400001))
+
+	icon, err := b.OpenTrayIcon(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.send(b.encodeEvent(&DestroyNotifyEvent{Code: DestroyNotifyCode, Event: icon.Id, Window: icon.Id}))
+	_, err = b.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.trayIcons[icon.Id]; ok {
+		t.Error("destroyed tray icon still registered")
+	}
+}
+
+func TestSelectRootInputKeepsMasks(t *testing.T) {
+	b, s := newFakeBackend(t, nil)
+
+	b.selectRootInput(EVPropertyChange.Mask())
+	b.selectRootInput(EVStructureNotify.Mask())
+	// Round trip so the server has seen every request.
+	_, err := b.atom("ROOT_INPUT_TEST_BARRIER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := s.requestsWithOpcode(OpChangeWindowAttributes)
+	if len(reqs) != 2 {
+		t.Fatalf("got %d ChangeWindowAttributes requests, want 2", len(reqs))
+	}
+	want := EVPropertyChange.Mask() | EVStructureNotify.Mask()
+	if mask := Card32(binary.BigEndian.Uint32(reqs[1].Body[8:12])); mask != want {
+		t.Errorf("selected %#x on the root, want %#x", mask, want)
+	}
+}
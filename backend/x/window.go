@@ -0,0 +1,266 @@
+package x
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// windowEventMask selects the events delivered for windows opened by the
+// backend.
+var windowEventMask = EVKeyPress.Mask() |
+	EVKeyRelease.Mask() |
+	EVButtonPress.Mask() |
+	EVButtonRelease.Mask() |
+	EVEnterWindow.Mask() |
+	EVLeaveWindow.Mask() |
+	EVPointerMotion.Mask() |
+	EVExposure.Mask() |
+	EVStructureNotify.Mask() |
+	EVFocusChange.Mask() |
+	EVPropertyChange.Mask()
+
+type Window struct {
+	b      *Backend
+	Id     WindowId
+	Width  int
+	Height int
+
+	format  pixelFormat
+	gc      GContext
+	sync    windowSync
+	present *windowPresent
+	shaped  bool
+}
+
+func (b *Backend) OpenWindow(title string, width, height int) (w *Window, err error) {
+	screen := b.rootScreen()
+
+	w, err = b.createWindow(screen.Root, image.Rect(0, 0, width, height),
+		CWBackPixel|CWEventMask, screen.BlackPixel, windowEventMask)
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+
+	err = w.SetTitle(title)
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+
+	protocols := []string{"WM_DELETE_WINDOW"}
+	// Without SYNC, the window manager does not wait for frames.
+	err = w.initSync()
+	switch {
+	case err == nil:
+		protocols = append(protocols, "_NET_WM_SYNC_REQUEST")
+	case !errors.Is(err, ErrMissingExtension):
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+	err = w.setProtocols(protocols...)
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+
+	w.mapWindow()
+	b.flush()
+	if b.err != nil {
+		return nil, fmt.Errorf("opening window: %w", b.err)
+	}
+
+	return w, nil
+}
+
+// createWindow creates an unmapped window with the depth and visual of the
+// root window. The attribute values must follow the order of their bits in
+// mask.
+func (b *Backend) createWindow(parent WindowId, r image.Rectangle, mask Card32, values ...Card32) (w *Window, err error) {
+	screen := b.rootScreen()
+
+	w = &Window{
+		b:      b,
+		Id:     WindowId(b.allocId()),
+		Width:  r.Dx(),
+		Height: r.Dy(),
+	}
+
+	w.format, err = b.pixelFormat(screen.RootDepth, screen.RootVisual)
+	if err != nil {
+		return nil, err
+	}
+
+	b.beginRequest(OpCreateWindow, screen.RootDepth, 32+4*len(values))
+	b.write(w.Id)
+	b.write(parent)
+	b.write(Int16(r.Min.X))
+	b.write(Int16(r.Min.Y))
+	b.write(Card16(r.Dx()))
+	b.write(Card16(r.Dy()))
+	b.write(Card16(0)) // Border width
+	b.write(InputOutput)
+	b.write(screen.RootVisual)
+	b.write(mask)
+	b.writeValues(values...)
+
+	b.windows[w.Id] = w
+	w.gc = b.createGC(Drawable(w.Id))
+
+	// Without Present, frames are drawn directly with PutImage.
+	err = w.initPresent()
+	if err != nil && !errors.Is(err, ErrMissingExtension) {
+		return nil, err
+	}
+
+	return w, b.err
+}
+
+// mapWindow shows the window.
+func (w *Window) mapWindow() {
+	w.b.beginRequest(OpMapWindow, 0, 8)
+	w.b.write(w.Id)
+}
+
+// SetTitle sets the window title shown by the window manager.
+func (w *Window) SetTitle(title string) error {
+	utf8String, err := w.b.atom("UTF8_STRING")
+	if err != nil {
+		return err
+	}
+	netWMName, err := w.b.atom("_NET_WM_NAME")
+	if err != nil {
+		return err
+	}
+
+	w.b.changePropertyString(w.Id, AtomWMName, AtomString, title)
+	w.b.changePropertyString(w.Id, netWMName, utf8String, title)
+	return w.b.err
+}
+
+// SetIcon sets the icon shown for the window in taskbars and window
+// switchers. Several sizes of the same icon can be given, and the window
+// manager picks the most suitable. Without images, the icon is removed.
+func (w *Window) SetIcon(images ...image.Image) error {
+	netWMIcon, err := w.b.atom("_NET_WM_ICON")
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		w.b.deleteProperty(w.Id, netWMIcon)
+		return w.b.err
+	}
+
+	var values []Card32
+	for _, img := range images {
+		r := img.Bounds()
+		values = append(values, Card32(r.Dx()), Card32(r.Dy()))
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				values = append(values, Card32(c.A)<<24|Card32(c.R)<<16|Card32(c.G)<<8|Card32(c.B))
+			}
+		}
+	}
+
+	w.b.changeProperty32(w.Id, netWMIcon, AtomCardinal, values...)
+	w.b.flush()
+	return w.b.err
+}
+
+// setProtocols sets the WM_PROTOCOLS the window takes part in.
+func (w *Window) setProtocols(names ...string) error {
+	atoms, err := w.b.internAtoms(append(names, "WM_PROTOCOLS")...)
+	if err != nil {
+		return err
+	}
+
+	values := make([]Card32, len(names))
+	for i := range names {
+		values[i] = Card32(atoms[i])
+	}
+	w.b.changeProperty32(w.Id, atoms[len(names)], AtomAtom, values...)
+	return w.b.err
+}
+
+// Present draws img on the window, with its origin at the top left corner.
+// When the server supports the Present extension the frame is shown at the
+// next vertical blank, and a FrameEvent is delivered once it is on screen.
+func (w *Window) Present(img *image.RGBA) error {
+	return w.PresentAt(img, 0)
+}
+
+// PresentAt is like Present, but shows the frame at the vertical blank
+// where the MSC of FrameEvent reaches targetMSC, which paces frames to the
+// refresh rate. Frames whose target has passed are shown at the next one.
+// Without the Present extension, the frame is drawn right away.
+func (w *Window) PresentAt(img *image.RGBA, targetMSC uint64) (err error) {
+	if w.present != nil {
+		err = w.presentPixmap(img, targetMSC)
+	} else {
+		err = w.b.putImage(Drawable(w.Id), w.gc, &w.format, img, img.Bounds(), image.Point{})
+	}
+	if err != nil {
+		return fmt.Errorf("presenting frame: %w", err)
+	}
+
+	w.presented(img.Bounds().Size())
+
+	w.b.flush()
+	return w.b.err
+}
+
+func (w *Window) changeAttributes(mask Card32, values ...Card32) {
+	w.b.beginRequest(OpChangeWindowAttributes, 0, 12+4*len(values))
+	w.b.write(w.Id)
+	w.b.write(mask)
+	w.b.writeValues(values...)
+}
+
+// Close destroys the window.
+func (w *Window) Close() {
+	w.closeSync()
+	w.closePresent()
+	w.b.freeGC(w.gc)
+	w.b.beginRequest(OpDestroyWindow, 0, 8)
+	w.b.write(w.Id)
+	w.b.flush()
+	delete(w.b.windows, w.Id)
+}
+
+// windowDestroyed forgets a window destroyed by the server or another
+// client, such as a tray manager, along with the popup or tray icon it
+// belongs to.
+func (b *Backend) windowDestroyed(id WindowId) {
+	if p, ok := b.popups[id]; ok {
+		if p.grabbed {
+			b.releasePopupGrab(p)
+		}
+		delete(b.popups, id)
+	}
+	delete(b.trayIcons, id)
+	delete(b.windows, id)
+}
+
+func (w *Window) configure(ev ConfigureNotifyEvent) {
+	w.Width = int(ev.Width)
+	w.Height = int(ev.Height)
+	w.sync.configured = true
+}
+
+func (w *Window) clientMessage(ev ClientMessageEvent) error {
+	protocols, err := w.b.atom("WM_PROTOCOLS")
+	if err != nil {
+		return err
+	}
+	if ev.Type != protocols {
+		return nil
+	}
+
+	syncRequest, err := w.b.atom("_NET_WM_SYNC_REQUEST")
+	if err != nil {
+		return err
+	}
+	if Atom(ev.Data[0]) == syncRequest {
+		w.syncRequest(ev)
+	}
+	return nil
+}
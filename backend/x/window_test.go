@@ -0,0 +1,70 @@
+package x
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSetIconChunked(t *testing.T) {
+	b, s := newFakeBackend(t, func(req fakeRequest) []byte {
+		return extensionReply(req)
+	})
+	// Requests of at most 64 bytes leave 40 bytes of property data each.
+	b.initResponse.MaximumRequestLength = 16
+
+	w, err := b.OpenWindow("icon", 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	small.SetNRGBA(1, 0, color.NRGBA{0x11, 0x22, 0x33, 0x80})
+	large := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	err = w.SetIcon(small, large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.atom("X_TEST_BARRIER")
+
+	icon := s.atom("_NET_WM_ICON")
+	var modes []PropMode
+	var data []byte
+	for _, req := range s.requestsWithOpcode(OpChangeProperty) {
+		if Atom(binary.BigEndian.Uint32(req.Body[4:])) != icon {
+			continue
+		}
+		if len(req.Body)+4 > 64 {
+			t.Errorf("request of %d bytes exceeds the maximum length", len(req.Body)+4)
+		}
+		n := binary.BigEndian.Uint32(req.Body[16:])
+		modes = append(modes, PropMode(req.Data))
+		data = append(data, req.Body[20:20+4*n]...)
+	}
+
+	// Two sizes and 20 pixels are split into 3 requests of up to 10 values.
+	want := []PropMode{PropModeReplace, PropModeAppend, PropModeAppend}
+	if len(modes) != len(want) {
+		t.Fatalf("icon set with modes %v, want %v", modes, want)
+	}
+	for i := range want {
+		if modes[i] != want[i] {
+			t.Fatalf("icon set with modes %v, want %v", modes, want)
+		}
+	}
+
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint32(data[4*i:])
+	}
+	if len(values) != 2+4+2+16 {
+		t.Fatalf("icon has %d values", len(values))
+	}
+	if values[0] != 2 || values[1] != 2 || values[6] != 4 || values[7] != 4 {
+		t.Errorf("icon sizes %v", values)
+	}
+	if values[3] != 0x80112233 {
+		t.Errorf("pixel %#x, want 0x80112233", values[3])
+	}
+}
@@ -0,0 +1,144 @@
+package x
+
+import (
+	"fmt"
+)
+
+// Minor opcodes of the XTEST extension.
+const (
+	xtestGetVersion Card8 = 0
+	xtestFakeInput  Card8 = 2
+)
+
+const (
+	xtestMajorVersion = 2
+	xtestMinorVersion = 2
+)
+
+type xtestGetVersionReply struct {
+	Header       replyHeader
+	MinorVersion Card16
+}
+
+// xtestExtension queries the XTEST extension, negotiating its version the
+// first time it is used.
+func (b *Backend) xtestExtension() (ext extension, err error) {
+	ext, err = b.queryExtension("XTEST")
+	if err != nil {
+		return ext, err
+	}
+	if !ext.present {
+		return ext, fmt.Errorf("XTEST: %w", ErrMissingExtension)
+	}
+	if ext.initialized {
+		return ext, nil
+	}
+
+	seq := b.beginExtensionRequest(ext, xtestGetVersion, 8)
+	b.write(Card8(xtestMajorVersion))
+	b.writeUnused(1)
+	b.write(Card16(xtestMinorVersion))
+
+	buf, err := b.waitReply(seq)
+	if err != nil {
+		return ext, fmt.Errorf("initializing XTEST extension: %w", err)
+	}
+
+	var reply xtestGetVersionReply
+	err = b.decode(buf, &reply)
+	if err != nil {
+		return ext, fmt.Errorf("initializing XTEST extension: %w", err)
+	}
+
+	ext.initialized = true
+	b.extensions["XTEST"] = ext
+	return ext, nil
+}
+
+// fakeInput makes the server process an input event as if it came from a
+// real device.
+func (b *Backend) fakeInput(code Card8, detail Card8, x, y int) error {
+	ext, err := b.xtestExtension()
+	if err != nil {
+		return fmt.Errorf("faking input: %w", err)
+	}
+
+	b.beginExtensionRequest(ext, xtestFakeInput, 36)
+	b.write(code)
+	b.write(detail)
+	b.writeUnused(2)
+	b.write(Timestamp(0)) // Current time
+	b.write(b.rootScreen().Root)
+	b.writeUnused(8)
+	b.write(Int16(x))
+	b.write(Int16(y))
+	b.writeUnused(7)
+	b.write(Card8(0)) // Core device
+
+	b.flush()
+	return b.err
+}
+
+// FakeKey presses or releases a key.
+func (b *Backend) FakeKey(keycode KeyCode, press bool) error {
+	code := KeyReleaseCode
+	if press {
+		code = KeyPressCode
+	}
+	return b.fakeInput(code, Card8(keycode), 0, 0)
+}
+
+// FakeButton presses or releases a pointer button.
+func (b *Backend) FakeButton(button Card8, press bool) error {
+	code := ButtonReleaseCode
+	if press {
+		code = ButtonPressCode
+	}
+	return b.fakeInput(code, button, 0, 0)
+}
+
+// FakeMotion moves the pointer to a position on the root window.
+func (b *Backend) FakeMotion(x, y int) error {
+	return b.fakeInput(MotionNotifyCode, 0, x, y)
+}
+
+// TypeString types s by pressing and releasing the key of each rune,
+// holding Shift for runes on the shifted level of their key.
+func (b *Backend) TypeString(s string) error {
+	km, err := b.keyboardMapping()
+	if err != nil {
+		return fmt.Errorf("typing string: %w", err)
+	}
+
+	shift, _ := km.keycodes(KeysymShiftL)
+	if len(shift) == 0 {
+		shift, _ = km.keycodes(KeysymShiftR)
+	}
+
+	for _, r := range s {
+		kcs, column := km.keycodes(RuneKeysym(r))
+		if len(kcs) == 0 {
+			return fmt.Errorf("typing %q: no key for %q: %w", s, r, ErrNotImplemented)
+		}
+		if column == 1 && len(shift) == 0 {
+			return fmt.Errorf("typing %q: no Shift key: %w", s, ErrNotImplemented)
+		}
+
+		if column == 1 {
+			err = b.FakeKey(shift[0], true)
+		}
+		if err == nil {
+			err = b.FakeKey(kcs[0], true)
+		}
+		if err == nil {
+			err = b.FakeKey(kcs[0], false)
+		}
+		if err == nil && column == 1 {
+			err = b.FakeKey(shift[0], false)
+		}
+		if err != nil {
+			return fmt.Errorf("typing %q: %w", s, err)
+		}
+	}
+	return nil
+}
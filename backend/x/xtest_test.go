@@ -0,0 +1,101 @@
+package x
+
+import (
+	"image"
+	"os"
+	"testing"
+)
+
+// TestXTestInput needs a running X server with the XTEST extension, such as
+// the one started by `xvfb-run go test ./backend/x`.
+func TestXTestInput(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY not set")
+	}
+
+	var b Backend
+	err := b.Init()
+	if err != nil {
+		t.Skipf("no X server: %v", err)
+	}
+	defer b.Close()
+
+	w, err := b.OpenWindow("xtest", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the window to be mapped, so that input reaches it.
+	for {
+		ev, err := b.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev, ok := ev.(ExposeEvent); ok && ev.Window == w.Id {
+			break
+		}
+	}
+	err = w.Present(image.NewRGBA(image.Rect(0, 0, 100, 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.FakeMotion(50, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.FakeButton(1, true)
+	if err == nil {
+		err = b.FakeButton(1, false)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const text = "Hi!"
+	err = b.TypeString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	km, err := b.keyboardMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clicked := false
+	var typed []rune
+	for len(typed) < len(text) {
+		ev, err := b.NextEvent()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch ev := ev.(type) {
+		case ButtonPressEvent:
+			if ev.Event == w.Id && ev.Detail == 1 && ev.EventX == 50 && ev.EventY == 50 {
+				clicked = true
+			}
+		case KeyPressEvent:
+			if ev.Event != w.Id {
+				continue
+			}
+			column := 0
+			if ev.State&ShiftMask != 0 {
+				column = 1
+			}
+			sym := km.keysym(ev.Detail, column)
+			if sym >= KeysymShiftL && sym <= KeysymSuperR {
+				continue
+			}
+			typed = append(typed, rune(sym))
+		}
+	}
+
+	if !clicked {
+		t.Fatal("button press not received")
+	}
+	if string(typed) != text {
+		t.Fatalf("typed %q, want %q", string(typed), text)
+	}
+}